@@ -4,4 +4,24 @@
 // Record messages through Recorder inside the same transaction as the business
 // write. Publish them through Relay after commit. Delivery is at-least-once, so
 // consumers must deduplicate by the message ID.
+//
+// Failed records are retried according to the relay RetryPolicy. The default
+// NoRetryPolicy fails them for good; FixedBackoffPolicy and
+// ExponentialBackoffPolicy schedule another attempt, and ClassifyingPolicy
+// picks a policy per error class and never retries records that cannot be
+// decoded.
+//
+// WithConcurrency lets RunOnce publish a claimed batch in parallel. Records
+// that share a Key are published one at a time in claim order. When one of
+// them fails and waits for a retry, the later records of that Key in the batch
+// are deferred. Stores that implement Releaser take deferred records back
+// without counting the claim as an attempt; with other stores they stay
+// claimed until their lease expires. Keeping a Key in order across
+// batches and retries is up to the Store, which must not claim a record while
+// an earlier record with the same Key waits for a retry or is held by another
+// claim; sqlstore.Store does this.
+//
+// Published records are kept until removed. Stores that implement Purger can
+// be cleaned by a Janitor, which purges published records older than the
+// retention window in batches and keeps cumulative JanitorMetrics.
 package outbox
@@ -10,4 +10,6 @@ var (
 	ErrInvalidRunOptions   = errors.New("outbox: invalid run options")
 	ErrUnknownKind         = errors.New("outbox: unknown message kind")
 	ErrNilFactory          = errors.New("outbox: nil protobuf factory")
+	ErrDecodeFailed        = errors.New("outbox: decode record")
+	ErrPurgeUnsupported    = errors.New("outbox: store does not support purging")
 )
@@ -0,0 +1,183 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRetention      = 7 * 24 * time.Hour
+	defaultPurgeBatchSize = 1000
+)
+
+// Purger is an optional Store capability that removes published records.
+//
+// Purge deletes, or archives, at most limit records in StatusPublished whose
+// UpdatedAt is before olderThan and returns how many it removed. Records in
+// any other status are never purged.
+type Purger interface {
+	Purge(ctx context.Context, olderThan time.Time, limit int) (int, error)
+}
+
+// JanitorOption configures a Janitor.
+type JanitorOption func(*Janitor)
+
+// WithRetention sets how long published records are kept. The default is
+// seven days.
+func WithRetention(retention time.Duration) JanitorOption {
+	return func(j *Janitor) {
+		if retention > 0 {
+			j.retention = retention
+		}
+	}
+}
+
+// WithPurgeBatchSize sets how many records one Purge call removes. The default
+// is 1000.
+func WithPurgeBatchSize(size int) JanitorOption {
+	return func(j *Janitor) {
+		if size > 0 {
+			j.batchSize = size
+		}
+	}
+}
+
+// WithJanitorClock sets the time source for the retention cutoff.
+func WithJanitorClock(now func() time.Time) JanitorOption {
+	return func(j *Janitor) {
+		if now != nil {
+			j.now = now
+		}
+	}
+}
+
+// Janitor purges published records older than the retention window in
+// batches, so outbox tables stay small.
+type Janitor struct {
+	purger    Purger
+	retention time.Duration
+	batchSize int
+	now       func() time.Time
+
+	mu      sync.Mutex
+	metrics JanitorMetrics
+}
+
+// PurgeResult reports one PurgeOnce pass.
+type PurgeResult struct {
+	Cutoff   time.Time
+	Purged   int
+	Batches  int
+	Duration time.Duration
+	Err      error
+}
+
+// JanitorMetrics are cumulative counters over every PurgeOnce pass.
+type JanitorMetrics struct {
+	Runs       int64
+	Purged     int64
+	Batches    int64
+	Errors     int64
+	LastRunAt  time.Time
+	LastPurged int
+	LastError  error
+}
+
+// NewJanitor constructs a Janitor for store, which must implement Purger.
+func NewJanitor(store Store, opts ...JanitorOption) (*Janitor, error) {
+	if store == nil {
+		return nil, ErrNilStore
+	}
+	purger, ok := store.(Purger)
+	if !ok {
+		return nil, ErrPurgeUnsupported
+	}
+	j := &Janitor{
+		purger:    purger,
+		retention: defaultRetention,
+		batchSize: defaultPurgeBatchSize,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(j)
+		}
+	}
+	return j, nil
+}
+
+// PurgeOnce purges batches of published records older than the retention
+// window until a batch comes back short, ctx is done, or Purge fails.
+func (j *Janitor) PurgeOnce(ctx context.Context) PurgeResult {
+	started := j.now()
+	result := PurgeResult{Cutoff: started.Add(-j.retention)}
+	for {
+		if err := ctx.Err(); err != nil {
+			result.Err = err
+			break
+		}
+		n, err := j.purger.Purge(ctx, result.Cutoff, j.batchSize)
+		result.Purged += n
+		result.Batches++
+		if err != nil {
+			result.Err = fmt.Errorf("purge outbox records: %w", err)
+			break
+		}
+		if n < j.batchSize {
+			break
+		}
+	}
+	result.Duration = j.now().Sub(started)
+
+	j.mu.Lock()
+	j.metrics.Runs++
+	j.metrics.Purged += int64(result.Purged)
+	j.metrics.Batches += int64(result.Batches)
+	if result.Err != nil {
+		j.metrics.Errors++
+	}
+	j.metrics.LastRunAt = started
+	j.metrics.LastPurged = result.Purged
+	j.metrics.LastError = result.Err
+	j.mu.Unlock()
+	return result
+}
+
+// Metrics returns a snapshot of the cumulative purge counters.
+func (j *Janitor) Metrics() JanitorMetrics {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.metrics
+}
+
+// JanitorRunOptions configures Janitor.Run.
+type JanitorRunOptions struct {
+	Interval time.Duration
+	OnResult func(PurgeResult)
+}
+
+// Run calls PurgeOnce every Interval until ctx is done, reporting each pass to
+// OnResult.
+func (j *Janitor) Run(ctx context.Context, opts JanitorRunOptions) error {
+	if opts.Interval <= 0 {
+		return ErrInvalidRunOptions
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		result := j.PurgeOnce(ctx)
+		if opts.OnResult != nil {
+			opts.OnResult(result)
+		}
+		timer := time.NewTimer(opts.Interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
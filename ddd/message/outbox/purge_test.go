@@ -0,0 +1,102 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/ddd/message/outbox"
+	"github.com/stretchr/testify/require"
+)
+
+type purgingStore struct {
+	relayStore
+	remaining int
+	err       error
+	cutoffs   []time.Time
+	limits    []int
+}
+
+func (s *purgingStore) Purge(_ context.Context, olderThan time.Time, limit int) (int, error) {
+	s.cutoffs = append(s.cutoffs, olderThan)
+	s.limits = append(s.limits, limit)
+	if s.err != nil {
+		return 0, s.err
+	}
+	n := min(limit, s.remaining)
+	s.remaining -= n
+	return n, nil
+}
+
+// PurgeOnce must purge in batches up to the retention cutoff until a batch
+// comes back short, and accumulate the outcome in the janitor metrics.
+func TestJanitorPurgeOnceDrainsInBatches(t *testing.T) {
+	store := &purgingStore{remaining: 25}
+	janitor, err := outbox.NewJanitor(store, outbox.WithRetention(time.Hour), outbox.WithPurgeBatchSize(10), outbox.WithJanitorClock(fixedClock))
+	require.NoError(t, err)
+
+	result := janitor.PurgeOnce(context.Background())
+
+	require.NoError(t, result.Err)
+	require.Equal(t, 25, result.Purged)
+	require.Equal(t, 3, result.Batches)
+	require.Equal(t, fixedClock().Add(-time.Hour), result.Cutoff)
+	require.Equal(t, []int{10, 10, 10}, store.limits)
+	require.Equal(t, fixedClock().Add(-time.Hour), store.cutoffs[0])
+
+	janitor.PurgeOnce(context.Background())
+	metrics := janitor.Metrics()
+	require.Equal(t, int64(2), metrics.Runs)
+	require.Equal(t, int64(25), metrics.Purged)
+	require.Equal(t, int64(4), metrics.Batches)
+	require.Zero(t, metrics.LastPurged)
+	require.Equal(t, fixedClock(), metrics.LastRunAt)
+}
+
+// Purge failures must stop the pass and be reported in the result and the
+// error counter.
+func TestJanitorPurgeOnceReportsFailure(t *testing.T) {
+	store := &purgingStore{err: errors.New("lock timeout")}
+	janitor, err := outbox.NewJanitor(store)
+	require.NoError(t, err)
+
+	result := janitor.PurgeOnce(context.Background())
+
+	require.ErrorIs(t, result.Err, store.err)
+	require.Contains(t, result.Err.Error(), "purge outbox records")
+	require.Equal(t, 1, result.Batches)
+	require.Equal(t, int64(1), janitor.Metrics().Errors)
+	require.ErrorIs(t, janitor.Metrics().LastError, store.err)
+}
+
+// NewJanitor must reject stores that cannot purge so the cleanup job does not
+// silently do nothing.
+func TestNewJanitorRejectsStoresWithoutPurger(t *testing.T) {
+	_, err := outbox.NewJanitor(&relayStore{})
+	require.ErrorIs(t, err, outbox.ErrPurgeUnsupported)
+
+	_, err = outbox.NewJanitor(nil)
+	require.ErrorIs(t, err, outbox.ErrNilStore)
+}
+
+// Run must reject non-positive intervals and stop on context cancellation.
+func TestJanitorRunStopsOnContextCancellation(t *testing.T) {
+	janitor, err := outbox.NewJanitor(&purgingStore{remaining: 3})
+	require.NoError(t, err)
+	require.ErrorIs(t, janitor.Run(context.Background(), outbox.JanitorRunOptions{}), outbox.ErrInvalidRunOptions)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var results []outbox.PurgeResult
+	err = janitor.Run(ctx, outbox.JanitorRunOptions{
+		Interval: time.Millisecond,
+		OnResult: func(result outbox.PurgeResult) {
+			results = append(results, result)
+			cancel()
+		},
+	})
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.Len(t, results, 1)
+	require.Equal(t, 3, results[0].Purged)
+}
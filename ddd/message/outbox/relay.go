@@ -4,22 +4,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/go-jimu/components/ddd/message"
 )
 
 type Relay struct {
-	store     Store
-	codec     Codec
-	publisher message.Publisher
-	retry     RetryPolicy
-	now       func() time.Time
+	store       Store
+	codec       Codec
+	publisher   message.Publisher
+	retry       RetryPolicy
+	now         func() time.Time
+	concurrency int
 }
 
 type relayConfig struct {
-	retry RetryPolicy
-	now   func() time.Time
+	retry       RetryPolicy
+	now         func() time.Time
+	concurrency int
 }
 
 type RelayOption func(*relayConfig)
@@ -40,6 +43,24 @@ func WithClock(now func() time.Time) RelayOption {
 	}
 }
 
+// WithConcurrency sets how many records RunOnce publishes in parallel. Records
+// that share a non-empty Key are still published one at a time in claim
+// order, so per-aggregate ordering is kept. The default is 1, which publishes
+// every record in claim order.
+//
+// With any concurrency, once a record fails and is scheduled for a retry, the
+// later records of its Key in the batch are deferred so they are not published
+// ahead of it. Stores that implement Releaser get deferred records back through
+// Release; with other stores they stay claimed until their lease expires.
+// Records that failed for good do not hold their Key back.
+func WithConcurrency(n int) RelayOption {
+	return func(cfg *relayConfig) {
+		if n > 0 {
+			cfg.concurrency = n
+		}
+	}
+}
+
 func NewRelay(store Store, codec Codec, publisher message.Publisher, opts ...RelayOption) (*Relay, error) {
 	if store == nil {
 		return nil, ErrNilStore
@@ -50,13 +71,20 @@ func NewRelay(store Store, codec Codec, publisher message.Publisher, opts ...Rel
 	if publisher == nil {
 		return nil, ErrNilPublisher
 	}
-	cfg := relayConfig{retry: NoRetryPolicy{}, now: time.Now}
+	cfg := relayConfig{retry: NoRetryPolicy{}, now: time.Now, concurrency: 1}
 	for _, opt := range opts {
 		if opt != nil {
 			opt(&cfg)
 		}
 	}
-	return &Relay{store: store, codec: codec, publisher: publisher, retry: cfg.retry, now: cfg.now}, nil
+	return &Relay{
+		store:       store,
+		codec:       codec,
+		publisher:   publisher,
+		retry:       cfg.retry,
+		now:         cfg.now,
+		concurrency: cfg.concurrency,
+	}, nil
 }
 
 type RunResult struct {
@@ -65,7 +93,10 @@ type RunResult struct {
 	// Failed counts decode or publish failures that were successfully persisted
 	// through Store.MarkFailed.
 	Failed int
-	Errors []error
+	// Deferred counts records that were not processed because an earlier
+	// record with the same Key failed in the batch and waits for a retry.
+	Deferred int
+	Errors   []error
 }
 
 type RunOptions struct {
@@ -111,26 +142,115 @@ func (r *Relay) RunOnce(ctx context.Context, opts ClaimOptions) RunResult {
 		return RunResult{Errors: []error{fmt.Errorf("claim outbox records: %w", err)}}
 	}
 	result := RunResult{Claimed: len(records)}
-	for _, record := range records {
-		msg, err := r.codec.Decode(record)
-		if err != nil {
-			r.markFailed(ctx, &result, record, err)
-			continue
+	var deferred []Record
+	if r.concurrency <= 1 {
+		failedKeys := make(map[string]bool)
+		for _, record := range records {
+			if record.Key != "" && failedKeys[record.Key] {
+				deferred = append(deferred, record)
+				continue
+			}
+			if !r.process(ctx, &result, record) && record.Key != "" {
+				failedKeys[record.Key] = true
+			}
 		}
-		if err := r.publisher.Publish(ctx, msg); err != nil {
-			r.markFailed(ctx, &result, record, err)
+		r.release(ctx, &result, deferred)
+		return result
+	}
+
+	groups := groupByKey(records)
+	jobs := make(chan []Record)
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for range min(r.concurrency, len(groups)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range jobs {
+				var partial RunResult
+				var rest []Record
+				for i, record := range group {
+					if !r.process(ctx, &partial, record) {
+						rest = group[i+1:]
+						break
+					}
+				}
+				mu.Lock()
+				result.Published += partial.Published
+				result.Failed += partial.Failed
+				result.Errors = append(result.Errors, partial.Errors...)
+				deferred = append(deferred, rest...)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, group := range groups {
+		jobs <- group
+	}
+	close(jobs)
+	wg.Wait()
+	r.release(ctx, &result, deferred)
+	return result
+}
+
+// process publishes record and reports whether later records with the same key
+// may follow: it was published, or it failed for good.
+func (r *Relay) process(ctx context.Context, result *RunResult, record Record) bool {
+	msg, err := r.codec.Decode(record)
+	if err != nil {
+		return r.markFailed(ctx, result, record, fmt.Errorf("%w: %w", ErrDecodeFailed, err))
+	}
+	if err := r.publisher.Publish(ctx, msg); err != nil {
+		return r.markFailed(ctx, result, record, err)
+	}
+	if err := r.store.MarkPublished(ctx, record); err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("mark published record %s: %w", record.ID, err))
+		return true
+	}
+	result.Published++
+	return true
+}
+
+// groupByKey splits records into groups that can be published independently,
+// keeping claim order within each group. Records without a key form their own
+// groups.
+func groupByKey(records []Record) [][]Record {
+	groups := make([][]Record, 0, len(records))
+	index := make(map[string]int)
+	for _, record := range records {
+		if record.Key == "" {
+			groups = append(groups, []Record{record})
 			continue
 		}
-		if err := r.store.MarkPublished(ctx, record); err != nil {
-			result.Errors = append(result.Errors, fmt.Errorf("mark published record %s: %w", record.ID, err))
-			continue
+		i, ok := index[record.Key]
+		if !ok {
+			i = len(groups)
+			index[record.Key] = i
+			groups = append(groups, nil)
 		}
-		result.Published++
+		groups[i] = append(groups[i], record)
+	}
+	return groups
+}
+
+// release counts deferred records and hands them back to the store when it
+// implements Releaser.
+func (r *Relay) release(ctx context.Context, result *RunResult, deferred []Record) {
+	result.Deferred += len(deferred)
+	releaser, ok := r.store.(Releaser)
+	if !ok || len(deferred) == 0 {
+		return
+	}
+	if err := releaser.Release(ctx, deferred...); err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("release deferred records: %w", err))
 	}
-	return result
 }
 
-func (r *Relay) markFailed(ctx context.Context, result *RunResult, record Record, cause error) {
+// markFailed persists a failed attempt of record and reports whether it failed
+// for good, which no longer holds its key back.
+func (r *Relay) markFailed(ctx context.Context, result *RunResult, record Record, cause error) bool {
 	decision := r.retry.NextAttempt(record, cause, r.now())
 	nextAttemptAt := time.Time{}
 	if decision.Retry {
@@ -143,7 +263,8 @@ func (r *Relay) markFailed(ctx context.Context, result *RunResult, record Record
 			cause.Error(),
 			errors.Join(cause, err),
 		))
-		return
+		return false
 	}
 	result.Failed++
+	return nextAttemptAt.IsZero()
 }
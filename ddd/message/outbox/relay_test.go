@@ -3,6 +3,8 @@ package outbox_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -47,6 +49,28 @@ func TestRelayRunOnceMarksDecodeFailure(t *testing.T) {
 	require.Contains(t, store.failed[0].reason, "unknown message kind")
 }
 
+// Decode failures must reach the retry policy wrapped in ErrDecodeFailed so a
+// ClassifyingPolicy never retries them, while publish failures still retry.
+func TestRelayRunOnceClassifiesDecodeFailures(t *testing.T) {
+	record := validRecord(t)
+	undecodable := validRecord(t)
+	undecodable.ID = "record-2"
+	undecodable.Kind = "missing.kind"
+	undecodable.Key = "order-8"
+	store := &relayStore{claimed: []outbox.Record{record, undecodable}}
+	policy := outbox.ClassifyingPolicy{Default: outbox.FixedBackoffPolicy{Backoff: time.Minute}}
+	relay, err := outbox.NewRelay(store, registeredCodec(t), &relayPublisher{err: errors.New("broker unavailable")},
+		outbox.WithClock(fixedClock), outbox.WithRetryPolicy(policy))
+	require.NoError(t, err)
+
+	result := relay.RunOnce(context.Background(), validClaimOptions())
+
+	require.Equal(t, 2, result.Failed)
+	require.Equal(t, fixedClock().Add(time.Minute), store.failed[0].nextAttemptAt)
+	require.True(t, store.failed[1].nextAttemptAt.IsZero())
+	require.Contains(t, store.failed[1].reason, "outbox: decode record")
+}
+
 // Publish failures must use the configured retry policy and persist the next
 // attempt time.
 func TestRelayRunOnceRetriesPublishFailure(t *testing.T) {
@@ -267,7 +291,218 @@ func TestNewRelayNilClockKeepsDefaultClock(t *testing.T) {
 	require.Empty(t, result.Errors)
 }
 
+// With concurrency, records of different keys must be published in parallel
+// while records that share a key keep their claim order and never overlap.
+func TestRelayRunOncePublishesKeysInParallel(t *testing.T) {
+	var claimed []outbox.Record
+	for i, key := range []string{"order-1", "order-2", "order-1", "", "order-2", "order-1", ""} {
+		record := validRecord(t)
+		record.ID = fmt.Sprintf("record-%d", i)
+		record.MessageID = fmt.Sprintf("message-%d", i)
+		record.Key = key
+		claimed = append(claimed, record)
+	}
+	store := &relayStore{claimed: claimed}
+	publisher := &orderingPublisher{parallel: make(chan struct{}), inFlight: make(map[string]int), order: make(map[string][]string)}
+	relay, err := outbox.NewRelay(store, registeredCodec(t), publisher, outbox.WithClock(fixedClock), outbox.WithConcurrency(3))
+	require.NoError(t, err)
+
+	result := relay.RunOnce(context.Background(), validClaimOptions())
+
+	require.Equal(t, 7, result.Claimed)
+	require.Equal(t, 7, result.Published)
+	require.Empty(t, result.Errors)
+	require.Empty(t, publisher.overlaps)
+	require.True(t, publisher.sawParallel)
+	require.Equal(t, []string{"message-0", "message-2", "message-5"}, publisher.order["order-1"])
+	require.Equal(t, []string{"message-1", "message-4"}, publisher.order["order-2"])
+	require.Len(t, store.published, 7)
+}
+
+// Once a record fails, later records with its key must be deferred so they
+// are not published ahead of its retry, while other keys carry on.
+func TestRelayRunOnceDefersKeyAfterFailure(t *testing.T) {
+	for _, concurrency := range []int{1, 3} {
+		var claimed []outbox.Record
+		for i, key := range []string{"order-1", "order-2", "order-1", "order-1", "order-2"} {
+			record := validRecord(t)
+			record.ID = fmt.Sprintf("record-%d", i)
+			record.MessageID = fmt.Sprintf("message-%d", i)
+			record.Key = key
+			claimed = append(claimed, record)
+		}
+		store := &relayStore{claimed: claimed}
+		publisher := &failingPublisher{failID: "message-0"}
+		relay, err := outbox.NewRelay(store, registeredCodec(t), publisher, outbox.WithClock(fixedClock),
+			outbox.WithRetryPolicy(outbox.FixedBackoffPolicy{Backoff: time.Minute}), outbox.WithConcurrency(concurrency))
+		require.NoError(t, err)
+
+		result := relay.RunOnce(context.Background(), validClaimOptions())
+
+		require.Equal(t, 2, result.Published, "concurrency %d", concurrency)
+		require.Equal(t, 1, result.Failed, "concurrency %d", concurrency)
+		require.Equal(t, 2, result.Deferred, "concurrency %d", concurrency)
+		require.ElementsMatch(t, []string{"message-0", "message-1", "message-4"}, publisher.ids, "concurrency %d", concurrency)
+		require.ElementsMatch(t, []string{"record-1", "record-4"}, store.published, "concurrency %d", concurrency)
+		require.Equal(t, []outbox.Record{claimed[2], claimed[3]}, store.released, "concurrency %d", concurrency)
+	}
+}
+
+// A record that failed for good must not hold its key back, so the default
+// NoRetryPolicy publishes the rest of the key in the same batch.
+func TestRelayRunOnceDoesNotDeferAfterTerminalFailure(t *testing.T) {
+	for _, concurrency := range []int{1, 3} {
+		var claimed []outbox.Record
+		for i := range 3 {
+			record := validRecord(t)
+			record.ID = fmt.Sprintf("record-%d", i)
+			record.MessageID = fmt.Sprintf("message-%d", i)
+			claimed = append(claimed, record)
+		}
+		store := &relayStore{claimed: claimed}
+		publisher := &failingPublisher{failID: "message-0"}
+		relay, err := outbox.NewRelay(store, registeredCodec(t), publisher, outbox.WithClock(fixedClock),
+			outbox.WithConcurrency(concurrency))
+		require.NoError(t, err)
+
+		result := relay.RunOnce(context.Background(), validClaimOptions())
+
+		require.Equal(t, 2, result.Published, "concurrency %d", concurrency)
+		require.Equal(t, 1, result.Failed, "concurrency %d", concurrency)
+		require.Zero(t, result.Deferred, "concurrency %d", concurrency)
+		require.Equal(t, []string{"message-0", "message-1", "message-2"}, publisher.ids, "concurrency %d", concurrency)
+		require.Empty(t, store.released, "concurrency %d", concurrency)
+	}
+}
+
+// Deferred records must be handed back without counting their claim as an
+// attempt, so the next claim sees the attempts they had before.
+func TestRelayRunOnceReleasedRecordsKeepAttempts(t *testing.T) {
+	first := validRecord(t)
+	second := validRecord(t)
+	second.ID = "record-2"
+	second.MessageID = "message-2"
+	store := &countingStore{attempts: map[string]int{}, pending: []outbox.Record{first, second}}
+	publisher := &failingPublisher{failID: "message-1"}
+	relay, err := outbox.NewRelay(store, registeredCodec(t), publisher, outbox.WithClock(fixedClock),
+		outbox.WithRetryPolicy(outbox.FixedBackoffPolicy{Backoff: time.Minute}))
+	require.NoError(t, err)
+
+	for range 3 {
+		result := relay.RunOnce(context.Background(), validClaimOptions())
+		require.Empty(t, result.Errors)
+		require.Equal(t, 1, result.Deferred)
+	}
+
+	require.Equal(t, 3, store.attempts["record-1"])
+	require.Equal(t, 0, store.attempts["record-2"])
+}
+
+// Stores without Releaser keep deferred records claimed until their lease
+// expires; they are still reported as deferred.
+func TestRelayRunOnceDefersWithoutReleaser(t *testing.T) {
+	first := validRecord(t)
+	second := validRecord(t)
+	second.ID = "record-2"
+	second.MessageID = "message-2"
+	store := &relayStore{claimed: []outbox.Record{first, second}}
+	relay, err := outbox.NewRelay(struct{ outbox.Store }{store}, registeredCodec(t), &failingPublisher{failID: "message-1"},
+		outbox.WithClock(fixedClock), outbox.WithRetryPolicy(outbox.FixedBackoffPolicy{Backoff: time.Minute}))
+	require.NoError(t, err)
+
+	result := relay.RunOnce(context.Background(), validClaimOptions())
+
+	require.Equal(t, 1, result.Failed)
+	require.Equal(t, 1, result.Deferred)
+	require.Empty(t, result.Errors)
+	require.Empty(t, store.released)
+}
+
+// countingStore claims every pending record, counting the claim as an attempt
+// the way a real store does, and takes the attempt back on Release.
+type countingStore struct {
+	relayStore
+	attempts map[string]int
+	pending  []outbox.Record
+}
+
+func (s *countingStore) Claim(context.Context, outbox.ClaimOptions) ([]outbox.Record, error) {
+	claimed := make([]outbox.Record, 0, len(s.pending))
+	for _, record := range s.pending {
+		s.attempts[record.ID]++
+		record.Attempts = s.attempts[record.ID]
+		claimed = append(claimed, record)
+	}
+	return claimed, nil
+}
+
+func (s *countingStore) Release(_ context.Context, records ...outbox.Record) error {
+	for _, record := range records {
+		s.attempts[record.ID]--
+	}
+	return nil
+}
+
+type failingPublisher struct {
+	mu     sync.Mutex
+	failID string
+	ids    []string
+}
+
+func (p *failingPublisher) Publish(_ context.Context, msg message.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ids = append(p.ids, msg.ID())
+	if msg.ID() == p.failID {
+		return errors.New("broker unavailable")
+	}
+	return nil
+}
+
+// orderingPublisher records per-key publish order and holds each publish until
+// two are in flight, proving the relay publishes in parallel.
+type orderingPublisher struct {
+	mu          sync.Mutex
+	parallel    chan struct{}
+	total       int
+	sawParallel bool
+	inFlight    map[string]int
+	order       map[string][]string
+	overlaps    []string
+}
+
+func (p *orderingPublisher) Publish(_ context.Context, msg message.Message) error {
+	p.mu.Lock()
+	p.total++
+	if p.total == 2 && !p.sawParallel {
+		p.sawParallel = true
+		close(p.parallel)
+	}
+	if msg.Key() != "" {
+		p.inFlight[msg.Key()]++
+		if p.inFlight[msg.Key()] > 1 {
+			p.overlaps = append(p.overlaps, msg.ID())
+		}
+		p.order[msg.Key()] = append(p.order[msg.Key()], msg.ID())
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.parallel:
+	case <-time.After(time.Second):
+	}
+
+	p.mu.Lock()
+	p.total--
+	if msg.Key() != "" {
+		p.inFlight[msg.Key()]--
+	}
+	p.mu.Unlock()
+	return nil
+}
+
 type relayStore struct {
+	mu               sync.Mutex
 	claimed          []outbox.Record
 	claimCalls       int
 	claimOptions     []outbox.ClaimOptions
@@ -276,6 +511,7 @@ type relayStore struct {
 	markFailedErr    error
 	published        []string
 	failed           []failedRecord
+	released         []outbox.Record
 }
 
 type failedRecord struct {
@@ -294,6 +530,8 @@ func (s *relayStore) Claim(_ context.Context, opts outbox.ClaimOptions) ([]outbo
 	return s.claimed, nil
 }
 func (s *relayStore) MarkPublished(_ context.Context, records ...outbox.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.markPublishedErr != nil {
 		return s.markPublishedErr
 	}
@@ -303,6 +541,8 @@ func (s *relayStore) MarkPublished(_ context.Context, records ...outbox.Record)
 	return nil
 }
 func (s *relayStore) MarkFailed(_ context.Context, record outbox.Record, reason string, nextAttemptAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.markFailedErr != nil {
 		return s.markFailedErr
 	}
@@ -310,6 +550,13 @@ func (s *relayStore) MarkFailed(_ context.Context, record outbox.Record, reason
 	return nil
 }
 
+func (s *relayStore) Release(_ context.Context, records ...outbox.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.released = append(s.released, records...)
+	return nil
+}
+
 type relayPublisher struct {
 	messages []message.Message
 	err      error
@@ -1,6 +1,12 @@
 package outbox
 
-import "time"
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
 
 type RetryPolicy interface {
 	NextAttempt(record Record, err error, now time.Time) RetryDecision
@@ -37,6 +43,140 @@ func (p FixedBackoffPolicy) NextAttempt(record Record, err error, now time.Time)
 	return decision
 }
 
+// Jitter selects how ExponentialBackoffPolicy randomizes its delays.
+type Jitter int
+
+const (
+	// NoJitter uses the computed delay as is.
+	NoJitter Jitter = iota
+	// FullJitter picks a delay in [0, d).
+	FullJitter
+	// EqualJitter picks a delay in [d/2, d).
+	EqualJitter
+)
+
+// ExponentialBackoffPolicy retries with a delay of Base * Multiplier^(n-1)
+// after the nth attempt, capped at Cap and randomized by Jitter.
+//
+// MaxAttempts counts attempts like FixedBackoffPolicy: zero or less is
+// unlimited. A Multiplier below 1 uses 2, and a Cap of zero or less leaves the
+// delay uncapped. MaxAge stops retrying once the record is older than MaxAge,
+// measured from Record.CreatedAt; zero or less disables the cutoff. Rand
+// returns a number in [0, 1) for jitter and defaults to math/rand/v2.
+type ExponentialBackoffPolicy struct {
+	MaxAttempts int
+	Base        time.Duration
+	Multiplier  float64
+	Cap         time.Duration
+	Jitter      Jitter
+	Rand        func() float64
+	MaxAge      time.Duration
+}
+
+func (p ExponentialBackoffPolicy) NextAttempt(record Record, err error, now time.Time) RetryDecision {
+	decision := RetryDecision{Reason: errorReason(err)}
+	if p.MaxAttempts > 0 && record.Attempts >= p.MaxAttempts {
+		return decision
+	}
+	if p.MaxAge > 0 && !record.CreatedAt.IsZero() && now.Sub(record.CreatedAt) >= p.MaxAge {
+		return decision
+	}
+	decision.Retry = true
+	decision.NextAttemptAt = now.Add(p.delay(record.Attempts))
+	return decision
+}
+
+func (p ExponentialBackoffPolicy) delay(attempts int) time.Duration {
+	if p.Base <= 0 {
+		return 0
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	delay := float64(p.Base) * math.Pow(multiplier, float64(max(attempts, 1)-1))
+	if p.Cap > 0 {
+		delay = min(delay, float64(p.Cap))
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, which would wrap negative
+	// when converted, so the largest delay is kept exactly at the edge.
+	if delay >= float64(math.MaxInt64) {
+		delay = float64(math.MaxInt64)
+	}
+
+	random := p.Rand
+	if random == nil {
+		random = rand.Float64
+	}
+	switch p.Jitter {
+	case FullJitter:
+		delay *= random()
+	case EqualJitter:
+		delay = delay/2 + delay/2*random()
+	}
+	if delay >= float64(math.MaxInt64) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+// ErrorMatcher reports whether a relay error belongs to a class.
+type ErrorMatcher func(error) bool
+
+// MatchErrors matches errors that wrap any of targets.
+func MatchErrors(targets ...error) ErrorMatcher {
+	return func(err error) bool {
+		for _, target := range targets {
+			if errors.Is(err, target) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchTimeout matches context deadline errors and errors that report
+// Timeout() true, such as net.Error timeouts.
+func MatchTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var timeout interface{ Timeout() bool }
+	return errors.As(err, &timeout) && timeout.Timeout()
+}
+
+// RetryRule applies Policy to errors matched by Match.
+type RetryRule struct {
+	Match  ErrorMatcher
+	Policy RetryPolicy
+}
+
+// ClassifyingPolicy picks the policy of the first rule that matches the
+// error, falling back to Default. Errors wrapping ErrDecodeFailed are never
+// retried, since decoding the same record again fails the same way. A nil
+// Default or rule policy is NoRetryPolicy.
+type ClassifyingPolicy struct {
+	Rules   []RetryRule
+	Default RetryPolicy
+}
+
+func (p ClassifyingPolicy) NextAttempt(record Record, err error, now time.Time) RetryDecision {
+	if errors.Is(err, ErrDecodeFailed) {
+		return NoRetryPolicy{}.NextAttempt(record, err, now)
+	}
+	policy := p.Default
+	for _, rule := range p.Rules {
+		if rule.Match != nil && rule.Match(err) {
+			policy = rule.Policy
+			break
+		}
+	}
+	if policy == nil {
+		policy = NoRetryPolicy{}
+	}
+	return policy.NextAttempt(record, err, now)
+}
+
 func errorReason(err error) string {
 	if err == nil {
 		return ""
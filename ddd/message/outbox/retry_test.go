@@ -1,7 +1,10 @@
 package outbox_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math"
 	"testing"
 	"time"
 
@@ -96,3 +99,93 @@ func TestFixedBackoffPolicyWithNegativeBackoffSchedulesAtNow(t *testing.T) {
 	require.Equal(t, now, decision.NextAttemptAt)
 	require.Equal(t, "temporary", decision.Reason)
 }
+
+// ExponentialBackoffPolicy must grow the delay by the multiplier per attempt
+// and stop growing at the cap.
+func TestExponentialBackoffPolicyGrowsUpToCap(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	policy := outbox.ExponentialBackoffPolicy{Base: time.Second, Multiplier: 3, Cap: 20 * time.Second}
+
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 3 * time.Second, 3: 9 * time.Second, 4: 20 * time.Second, 60: 20 * time.Second} {
+		decision := policy.NextAttempt(outbox.Record{Attempts: attempts}, errors.New("temporary"), now)
+
+		require.True(t, decision.Retry)
+		require.Equal(t, now.Add(want), decision.NextAttemptAt, "attempt %d", attempts)
+	}
+}
+
+// An uncapped policy must not overflow into a past retry time once the
+// attempt count grows large, with or without jitter.
+func TestExponentialBackoffPolicyWithoutCapDoesNotOverflow(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	almostOne := func() float64 { return 0.999999 }
+
+	for _, jitter := range []outbox.Jitter{outbox.NoJitter, outbox.FullJitter, outbox.EqualJitter} {
+		policy := outbox.ExponentialBackoffPolicy{Base: time.Second, Jitter: jitter, Rand: almostOne}
+		for _, attempts := range []int{40, 64, 1000, math.MaxInt32} {
+			decision := policy.NextAttempt(outbox.Record{Attempts: attempts}, errors.New("temporary"), now)
+
+			require.True(t, decision.Retry)
+			require.True(t, decision.NextAttemptAt.After(now.Add(24*time.Hour)), "jitter %d attempt %d: %s", jitter, attempts, decision.NextAttemptAt)
+		}
+	}
+}
+
+// Jitter must scale the computed delay with the injected random source so
+// retries of many records spread out.
+func TestExponentialBackoffPolicyAppliesJitter(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	half := func() float64 { return 0.5 }
+	record := outbox.Record{Attempts: 3}
+
+	full := outbox.ExponentialBackoffPolicy{Base: time.Second, Jitter: outbox.FullJitter, Rand: half}.NextAttempt(record, errors.New("temporary"), now)
+	equal := outbox.ExponentialBackoffPolicy{Base: time.Second, Jitter: outbox.EqualJitter, Rand: half}.NextAttempt(record, errors.New("temporary"), now)
+
+	require.Equal(t, now.Add(2*time.Second), full.NextAttemptAt)
+	require.Equal(t, now.Add(3*time.Second), equal.NextAttemptAt)
+}
+
+// ExponentialBackoffPolicy must stop at the max attempt count and once the
+// record is older than the max age.
+func TestExponentialBackoffPolicyStopsAtMaxAttemptsAndMaxAge(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	policy := outbox.ExponentialBackoffPolicy{MaxAttempts: 5, Base: time.Second, MaxAge: time.Hour}
+
+	require.False(t, policy.NextAttempt(outbox.Record{Attempts: 5, CreatedAt: now}, errors.New("temporary"), now).Retry)
+	require.False(t, policy.NextAttempt(outbox.Record{Attempts: 1, CreatedAt: now.Add(-time.Hour)}, errors.New("temporary"), now).Retry)
+
+	decision := policy.NextAttempt(outbox.Record{Attempts: 1, CreatedAt: now.Add(-59 * time.Minute)}, errors.New("temporary"), now)
+	require.True(t, decision.Retry)
+	require.Equal(t, "temporary", decision.Reason)
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "i/o timeout" }
+func (timeoutError) Timeout() bool { return true }
+
+// ClassifyingPolicy must never retry decode failures, use the matching rule
+// for classified errors, and fall back to the default policy otherwise.
+func TestClassifyingPolicyRoutesErrorsByClass(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	brokerDown := errors.New("broker down")
+	policy := outbox.ClassifyingPolicy{
+		Rules: []outbox.RetryRule{
+			{Match: outbox.MatchTimeout, Policy: outbox.FixedBackoffPolicy{Backoff: time.Second}},
+			{Match: outbox.MatchErrors(brokerDown), Policy: outbox.FixedBackoffPolicy{Backoff: time.Minute}},
+		},
+	}
+	record := outbox.Record{Attempts: 1}
+
+	decode := policy.NextAttempt(record, fmt.Errorf("%w: %w", outbox.ErrDecodeFailed, outbox.ErrUnknownKind), now)
+	require.False(t, decode.Retry)
+	require.Contains(t, decode.Reason, "unknown message kind")
+
+	require.Equal(t, now.Add(time.Second), policy.NextAttempt(record, fmt.Errorf("publish: %w", context.DeadlineExceeded), now).NextAttemptAt)
+	require.Equal(t, now.Add(time.Second), policy.NextAttempt(record, timeoutError{}, now).NextAttemptAt)
+	require.Equal(t, now.Add(time.Minute), policy.NextAttempt(record, fmt.Errorf("publish: %w", brokerDown), now).NextAttemptAt)
+	require.False(t, policy.NextAttempt(record, errors.New("rejected"), now).Retry)
+
+	policy.Default = outbox.FixedBackoffPolicy{}
+	require.True(t, policy.NextAttempt(record, errors.New("rejected"), now).Retry)
+}
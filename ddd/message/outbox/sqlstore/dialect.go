@@ -0,0 +1,132 @@
+package sqlstore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-jimu/components/internal/sqlutil"
+)
+
+// Dialect selects placeholder syntax, column types, and locking support for a
+// database.
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectMySQL    Dialect = "mysql"
+	DialectSQLite   Dialect = "sqlite"
+)
+
+// DefaultTable is the outbox table name used when WithTable is not supplied.
+const DefaultTable = "message_outbox"
+
+// Schema returns the DDL statements that create the outbox table, with an
+// index on (status, created_at) for Claim and one on (status, updated_at) for
+// Purge.
+func Schema(dialect Dialect, table string) ([]string, error) {
+	if err := validateTable(table); err != nil {
+		return nil, err
+	}
+	index := strings.ReplaceAll(table, ".", "_") + "_due_idx"
+	purgeIndex := strings.ReplaceAll(table, ".", "_") + "_purge_idx"
+	switch dialect {
+	case DialectPostgres:
+		return []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(64) PRIMARY KEY,
+	message_id VARCHAR(255) NOT NULL,
+	kind VARCHAR(255) NOT NULL,
+	message_key VARCHAR(255) NOT NULL,
+	occurred_at BIGINT NOT NULL,
+	payload BYTEA,
+	headers TEXT NOT NULL,
+	status VARCHAR(32) NOT NULL,
+	attempts INTEGER NOT NULL,
+	next_attempt_at BIGINT NOT NULL,
+	locked_until BIGINT NOT NULL,
+	claimed_by VARCHAR(255) NOT NULL,
+	last_error TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL
+)`, table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (status, created_at)`, index, table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (status, updated_at)`, purgeIndex, table),
+		}, nil
+	case DialectMySQL:
+		return []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(64) PRIMARY KEY,
+	message_id VARCHAR(255) NOT NULL,
+	kind VARCHAR(255) NOT NULL,
+	message_key VARCHAR(255) NOT NULL,
+	occurred_at BIGINT NOT NULL,
+	payload LONGBLOB,
+	headers TEXT NOT NULL,
+	status VARCHAR(32) NOT NULL,
+	attempts INT NOT NULL,
+	next_attempt_at BIGINT NOT NULL,
+	locked_until BIGINT NOT NULL,
+	claimed_by VARCHAR(255) NOT NULL,
+	last_error TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL,
+	INDEX %s (status, created_at),
+	INDEX %s (status, updated_at)
+)`, table, index, purgeIndex),
+		}, nil
+	case DialectSQLite:
+		return []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id TEXT PRIMARY KEY,
+	message_id TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	message_key TEXT NOT NULL,
+	occurred_at INTEGER NOT NULL,
+	payload BLOB,
+	headers TEXT NOT NULL,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL,
+	next_attempt_at INTEGER NOT NULL,
+	locked_until INTEGER NOT NULL,
+	claimed_by TEXT NOT NULL,
+	last_error TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL
+)`, table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (status, created_at)`, index, table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (status, updated_at)`, purgeIndex, table),
+		}, nil
+	default:
+		return nil, ErrUnknownDialect
+	}
+}
+
+func validateTable(table string) error {
+	if !sqlutil.ValidTable(table) {
+		return ErrInvalidTable
+	}
+	return nil
+}
+
+// rebind rewrites ? placeholders into the dialect's placeholder syntax.
+func (d Dialect) rebind(query string) string {
+	if d != DialectPostgres {
+		return query
+	}
+	return sqlutil.Rebind(query)
+}
+
+func (d Dialect) valid() bool {
+	switch d {
+	case DialectPostgres, DialectMySQL, DialectSQLite:
+		return true
+	default:
+		return false
+	}
+}
+
+// supportsSkipLocked reports whether the dialect accepts FOR UPDATE SKIP
+// LOCKED.
+func (d Dialect) supportsSkipLocked() bool {
+	return d == DialectPostgres || d == DialectMySQL
+}
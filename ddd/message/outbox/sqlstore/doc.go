@@ -0,0 +1,39 @@
+// Package sqlstore provides an outbox.Store backed by database/sql.
+//
+// Store keeps outbox records in one table on PostgreSQL, MySQL, or SQLite.
+// Append writes through the DBTX the store is bound to, so binding the store
+// to the business transaction with WithTx appends records atomically with the
+// aggregate write. Migrate runs the DDL returned by Schema through the same
+// DBTX; note that MySQL commits implicitly around DDL statements.
+//
+// TxRecorder is the outbox.Recorder for application services. It appends
+// through the *sql.Tx carried by the context, set with ContextWithTx, or passed
+// to TxRecorder.Tx, and returns ErrNoTransaction when there is none, so records
+// can never be written outside the business transaction by accident.
+//
+// Claim selects due records and moves each one to outbox.StatusProcessing with
+// a conditional update that only succeeds while the record is still
+// claimable. On PostgreSQL and MySQL the selection runs in a transaction with
+// FOR UPDATE SKIP LOCKED, so concurrent relays skip rows another relay is
+// claiming instead of waiting for it. SQLite, and databases configured with
+// WithSkipLocked(false), rely on the conditional update and the record lease
+// alone. Records are claimed oldest first. A record with a message key is only
+// claimed together with every earlier record of the key that is neither
+// published nor failed for good, so a record is held back while an earlier
+// one waits for a retry or is claimed by another relay, even by a claim that
+// has not committed yet. Each key is therefore published in order across
+// concurrent relays.
+//
+// MarkPublished and MarkFailed only update records that are still processing
+// under the same claim, identified by ClaimedBy and Attempts. A relay whose
+// lease expired and whose record was reclaimed gets ErrStaleClaim.
+//
+// Release hands deferred records back to pending without counting their claim
+// as an attempt, so a relay that defers records does not burn their retries.
+//
+// Store also implements outbox.Purger. Purge deletes old published records in
+// batches, or moves them to the table set with WithArchiveTable.
+//
+// Timestamps are stored as Unix nanoseconds in integer columns to keep
+// comparisons portable across databases.
+package sqlstore
@@ -0,0 +1,12 @@
+package sqlstore
+
+import "errors"
+
+var (
+	ErrNilDB          = errors.New("outbox sql store database is nil")
+	ErrUnknownDialect = errors.New("outbox sql store dialect is unknown")
+	ErrInvalidTable   = errors.New("outbox sql store table name is invalid")
+	ErrInvalidRecord  = errors.New("outbox record is missing its id, message id, or kind")
+	ErrStaleClaim     = errors.New("outbox record is no longer claimed by this relay")
+	ErrNoTransaction  = errors.New("outbox recorder is called outside a transaction")
+)
@@ -0,0 +1,111 @@
+package sqlstore_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/ddd/message/outbox"
+	"github.com/go-jimu/components/ddd/message/outbox/sqlstore"
+	"github.com/stretchr/testify/require"
+)
+
+// seedPublished appends count records and publishes all but the last one at
+// start.
+func seedPublished(t *testing.T, store *sqlstore.Store, count int) {
+	t.Helper()
+	ctx := context.Background()
+	codec := newCodec(t)
+	for i := range count {
+		record, err := codec.Encode(newMessage(t, fmt.Sprintf("m%d", i), "c1"))
+		require.NoError(t, err)
+		require.NoError(t, store.Append(ctx, record))
+	}
+	claimed, err := store.Claim(ctx, claimOptions(start, "relay-1", count))
+	require.NoError(t, err)
+	require.Len(t, claimed, count)
+	require.NoError(t, store.MarkPublished(ctx, claimed[:count-1]...))
+}
+
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	t.Helper()
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&n))
+	return n
+}
+
+// Purge should delete only published records older than the cutoff, in
+// batches of at most limit.
+func TestStore_PurgeDeletesOldPublishedRecords(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	now := start
+	store := newStore(t, db, &now)
+	seedPublished(t, store, 4)
+
+	n, err := store.Purge(ctx, start, 10)
+	require.NoError(t, err)
+	require.Zero(t, n, "records published at the cutoff were purged")
+
+	n, err = store.Purge(ctx, start.Add(time.Second), 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	n, err = store.Purge(ctx, start.Add(time.Second), 2)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, 1, countRows(t, db, sqlstore.DefaultTable), "the unpublished record must be kept")
+}
+
+// With an archive table, purged records should be moved rather than lost,
+// and a janitor should drive the purge.
+func TestStore_PurgeArchivesRecords(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	now := start
+	store, err := sqlstore.New(db, sqlstore.DialectSQLite,
+		sqlstore.WithArchiveTable("message_outbox_archive"),
+		sqlstore.WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(ctx))
+	seedPublished(t, store, 3)
+
+	now = start.Add(48 * time.Hour)
+	janitor, err := outbox.NewJanitor(store, outbox.WithRetention(24*time.Hour), outbox.WithJanitorClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	result := janitor.PurgeOnce(ctx)
+
+	require.NoError(t, result.Err)
+	require.Equal(t, 2, result.Purged)
+	require.Equal(t, 1, countRows(t, db, sqlstore.DefaultTable))
+	require.Equal(t, 2, countRows(t, db, "message_outbox_archive"))
+	var status string
+	require.NoError(t, db.QueryRow("SELECT status FROM message_outbox_archive WHERE message_id = 'm0'").Scan(&status))
+	require.Equal(t, string(outbox.StatusPublished), status)
+
+	_, err = sqlstore.New(db, sqlstore.DialectSQLite, sqlstore.WithArchiveTable("archive; DROP TABLE x"))
+	require.ErrorIs(t, err, sqlstore.ErrInvalidTable)
+}
+
+// Purge filters and sorts published records by updated_at, so its query
+// should be served by an index instead of scanning every published row.
+func TestStore_PurgeUsesIndex(t *testing.T) {
+	db := openDB(t)
+	now := start
+	newStore(t, db, &now)
+
+	rows, err := db.Query(`EXPLAIN QUERY PLAN SELECT id FROM message_outbox
+		WHERE status = 'published' AND updated_at < 1 ORDER BY updated_at, id LIMIT 10`)
+	require.NoError(t, err)
+	defer rows.Close()
+	var plan []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		require.NoError(t, rows.Scan(&id, &parent, &unused, &detail))
+		plan = append(plan, detail)
+	}
+	require.NoError(t, rows.Err())
+	require.Contains(t, fmt.Sprint(plan), "message_outbox_purge_idx")
+}
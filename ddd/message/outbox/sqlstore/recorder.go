@@ -0,0 +1,88 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/go-jimu/components/ddd/message"
+	"github.com/go-jimu/components/ddd/message/outbox"
+)
+
+type txContextKey struct{}
+
+// ContextWithTx returns a copy of ctx carrying tx for TxRecorder.
+func ContextWithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// TxFromContext returns the transaction carried by ctx.
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(*sql.Tx)
+	return tx, ok && tx != nil
+}
+
+// TxRecorder records messages in the caller's business transaction.
+//
+// Record appends through the transaction carried by its context, set with
+// ContextWithTx, so the records commit or roll back with the aggregate write.
+// It returns ErrNoTransaction instead of writing outside a transaction.
+type TxRecorder struct {
+	store *Store
+	codec outbox.Codec
+}
+
+var _ outbox.Recorder = (*TxRecorder)(nil)
+
+// NewTxRecorder constructs a TxRecorder that encodes messages with codec and
+// appends them to store.
+func NewTxRecorder(store *Store, codec outbox.Codec) (*TxRecorder, error) {
+	if store == nil {
+		return nil, outbox.ErrNilStore
+	}
+	if codec == nil {
+		return nil, outbox.ErrNilCodec
+	}
+	return &TxRecorder{store: store, codec: codec}, nil
+}
+
+// Record appends messages in the transaction carried by ctx.
+func (r *TxRecorder) Record(ctx context.Context, messages ...message.Message) error {
+	tx, ok := TxFromContext(ctx)
+	if !ok {
+		return ErrNoTransaction
+	}
+	return r.record(ctx, tx, messages)
+}
+
+// Tx returns a Recorder bound to tx, for callers that pass the transaction
+// explicitly rather than through the context.
+func (r *TxRecorder) Tx(tx *sql.Tx) outbox.Recorder {
+	return boundRecorder{recorder: r, tx: tx}
+}
+
+func (r *TxRecorder) record(ctx context.Context, tx *sql.Tx, messages []message.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	records := make([]outbox.Record, 0, len(messages))
+	for _, msg := range messages {
+		record, err := r.codec.Encode(msg)
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+	}
+	return r.store.WithTx(tx).Append(ctx, records...)
+}
+
+type boundRecorder struct {
+	recorder *TxRecorder
+	tx       *sql.Tx
+}
+
+func (b boundRecorder) Record(ctx context.Context, messages ...message.Message) error {
+	if b.tx == nil {
+		return ErrNoTransaction
+	}
+	return b.recorder.record(ctx, b.tx, messages)
+}
@@ -0,0 +1,69 @@
+package sqlstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/ddd/message/outbox/sqlstore"
+	"github.com/stretchr/testify/require"
+)
+
+// Records written through the context transaction should commit and roll
+// back together with the business write.
+func TestTxRecorder_FollowsContextTransaction(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	now := start
+	store := newStore(t, db, &now)
+	_, err := db.ExecContext(ctx, "CREATE TABLE customers (id TEXT PRIMARY KEY)")
+	require.NoError(t, err)
+	recorder, err := sqlstore.NewTxRecorder(store, newCodec(t))
+	require.NoError(t, err)
+
+	save := func(id string, commit bool) {
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		txCtx := sqlstore.ContextWithTx(ctx, tx)
+		_, err = tx.ExecContext(txCtx, "INSERT INTO customers (id) VALUES (?)", id)
+		require.NoError(t, err)
+		require.NoError(t, recorder.Record(txCtx, newMessage(t, id, id)))
+		if commit {
+			require.NoError(t, tx.Commit())
+		} else {
+			require.NoError(t, tx.Rollback())
+		}
+	}
+	save("committed", true)
+	save("rolled-back", false)
+
+	claimed, err := store.Claim(ctx, claimOptions(now, "relay-1", 10))
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, "committed", claimed[0].MessageID)
+}
+
+// Recording outside a transaction must fail instead of silently writing
+// records that do not follow the business write.
+func TestTxRecorder_RejectsCallsOutsideTransaction(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	now := start
+	store := newStore(t, db, &now)
+	recorder, err := sqlstore.NewTxRecorder(store, newCodec(t))
+	require.NoError(t, err)
+
+	require.ErrorIs(t, recorder.Record(ctx, newMessage(t, "m1", "c1")), sqlstore.ErrNoTransaction)
+	require.ErrorIs(t, recorder.Record(ctx), sqlstore.ErrNoTransaction)
+	require.ErrorIs(t, recorder.Tx(nil).Record(ctx, newMessage(t, "m1", "c1")), sqlstore.ErrNoTransaction)
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, recorder.Tx(tx).Record(ctx, newMessage(t, "m2", "c1")))
+	require.NoError(t, tx.Commit())
+
+	claimed, err := store.Claim(ctx, claimOptions(now.Add(time.Second), "relay-1", 10))
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, "m2", claimed[0].MessageID)
+}
@@ -0,0 +1,504 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-jimu/components/ddd/message"
+	"github.com/go-jimu/components/ddd/message/outbox"
+)
+
+const recordColumns = "id, message_id, kind, message_key, occurred_at, payload, headers, status, attempts, " +
+	"next_attempt_at, locked_until, claimed_by, last_error, created_at, updated_at"
+
+// claimable matches pending records that are due, failed records with a due
+// retry, and processing records whose lease expired.
+const claimable = "((status = ? AND next_attempt_at <= ?) OR (status = ? AND next_attempt_at <> 0 AND next_attempt_at <= ?)" +
+	" OR (status = ? AND locked_until <= ?))"
+
+// keyBlocked matches candidate records, aliased r, whose message key has an
+// earlier record, aliased e, that is neither published, failed for good, nor
+// claimable now: it waits for a retry or is held by another claim.
+const keyBlocked = "r.message_key <> '' AND EXISTS (SELECT 1 FROM %s e WHERE e.message_key = r.message_key" +
+	" AND (e.created_at < r.created_at OR (e.created_at = r.created_at AND e.id < r.id))" +
+	" AND e.status <> ? AND NOT (e.status = ? AND e.next_attempt_at = 0)" +
+	" AND NOT ((e.status = ? AND e.next_attempt_at <= ?) OR (e.status = ? AND e.next_attempt_at <> 0 AND e.next_attempt_at <= ?)" +
+	" OR (e.status = ? AND e.locked_until <= ?)))"
+
+// predecessors counts the records, aliased e, that come before the candidate
+// record r under the same message key and are neither published nor failed
+// for good. A keyed record may only be claimed together with all of them.
+const predecessors = "(SELECT COUNT(*) FROM %s e WHERE r.message_key <> '' AND e.message_key = r.message_key" +
+	" AND (e.created_at < r.created_at OR (e.created_at = r.created_at AND e.id < r.id))" +
+	" AND e.status <> ? AND NOT (e.status = ? AND e.next_attempt_at = 0))"
+
+// DBTX is the subset of *sql.DB, *sql.Conn, and *sql.Tx the store uses.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithTable sets the outbox table name.
+func WithTable(table string) Option {
+	return func(s *Store) {
+		if table != "" {
+			s.table = table
+		}
+	}
+}
+
+// WithArchiveTable makes Purge move published records into table instead of
+// deleting them. The archive table has the same layout as the outbox table
+// and is created by Migrate.
+func WithArchiveTable(table string) Option {
+	return func(s *Store) {
+		s.archiveTable = table
+	}
+}
+
+// WithClock sets the time source for record timestamps.
+func WithClock(now func() time.Time) Option {
+	return func(s *Store) {
+		if now != nil {
+			s.now = now
+		}
+	}
+}
+
+// WithSkipLocked enables or disables FOR UPDATE SKIP LOCKED when claiming. It
+// defaults to true for PostgreSQL and MySQL; disable it for databases that do
+// not support it, such as MySQL before 8.0. It cannot be enabled for SQLite.
+func WithSkipLocked(enabled bool) Option {
+	return func(s *Store) {
+		s.skipLocked = enabled && s.dialect.supportsSkipLocked()
+	}
+}
+
+// Store is an outbox.Store backed by database/sql.
+type Store struct {
+	db           DBTX
+	dialect      Dialect
+	table        string
+	archiveTable string
+	skipLocked   bool
+	now          func() time.Time
+}
+
+var (
+	_ outbox.Store    = (*Store)(nil)
+	_ outbox.Purger   = (*Store)(nil)
+	_ outbox.Releaser = (*Store)(nil)
+)
+
+// New constructs a Store that runs statements through db with the placeholder
+// syntax and column types of dialect.
+func New(db DBTX, dialect Dialect, opts ...Option) (*Store, error) {
+	if db == nil {
+		return nil, ErrNilDB
+	}
+	if !dialect.valid() {
+		return nil, ErrUnknownDialect
+	}
+	s := &Store{
+		db:         db,
+		dialect:    dialect,
+		table:      DefaultTable,
+		skipLocked: dialect.supportsSkipLocked(),
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+	if err := validateTable(s.table); err != nil {
+		return nil, err
+	}
+	if s.archiveTable != "" {
+		if err := validateTable(s.archiveTable); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// WithTx returns a copy of s that runs every statement in tx.
+func (s *Store) WithTx(tx *sql.Tx) *Store {
+	bound := *s
+	bound.db = tx
+	return &bound
+}
+
+// Migrate creates the outbox table, and the archive table when one is
+// configured, when they do not exist.
+func (s *Store) Migrate(ctx context.Context) error {
+	for _, table := range []string{s.table, s.archiveTable} {
+		if table == "" {
+			continue
+		}
+		statements, err := Schema(s.dialect, table)
+		if err != nil {
+			return err
+		}
+		for _, statement := range statements {
+			if _, err := s.db.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("migrate outbox table %s: %w", table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Append inserts records. Records without a status are stored as pending, and
+// zero creation times are set to the current time. Claim returns records
+// oldest first, so creation times within one call are made strictly
+// increasing to keep records appended together in order.
+func (s *Store) Append(ctx context.Context, records ...outbox.Record) error {
+	now := s.now()
+	var previous time.Time
+	for _, record := range records {
+		if record.ID == "" || record.MessageID == "" || record.Kind == "" {
+			return ErrInvalidRecord
+		}
+		if record.Status == "" {
+			record.Status = outbox.StatusPending
+		}
+		if record.CreatedAt.IsZero() {
+			record.CreatedAt = now
+		}
+		if !record.CreatedAt.After(previous) {
+			record.CreatedAt = previous.Add(time.Nanosecond)
+		}
+		previous = record.CreatedAt
+		if record.UpdatedAt.IsZero() {
+			record.UpdatedAt = record.CreatedAt
+		}
+		headers, err := encodeHeaders(record.Headers)
+		if err != nil {
+			return fmt.Errorf("encode outbox record %s headers: %w", record.ID, err)
+		}
+		_, err = s.exec(ctx, "INSERT INTO "+s.table+" ("+recordColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			record.ID, record.MessageID, string(record.Kind), record.Key, unixNano(record.OccurredAt), record.Payload, headers,
+			string(record.Status), record.Attempts, unixNano(record.NextAttemptAt), unixNano(record.LockedUntil),
+			record.ClaimedBy, record.LastError, unixNano(record.CreatedAt), unixNano(record.UpdatedAt),
+		)
+		if err != nil {
+			return fmt.Errorf("append outbox record %s: %w", record.ID, err)
+		}
+	}
+	return nil
+}
+
+// Claim locks up to opts.Limit claimable records for opts.ClaimedBy until
+// opts.LockedUntil and increments their attempts. A record with a message key
+// is only claimed when this claim also holds every earlier unfinished record
+// of the key, so concurrent relays never publish one key out of order.
+func (s *Store) Claim(ctx context.Context, opts outbox.ClaimOptions) ([]outbox.Record, error) {
+	opts, err := outbox.NormalizeClaimOptions(opts, s.now)
+	if err != nil {
+		return nil, err
+	}
+	if !s.skipLocked {
+		return s.claim(ctx, s.db, opts, "")
+	}
+	beginner, ok := s.db.(txBeginner)
+	if !ok {
+		return s.claim(ctx, s.db, opts, " FOR UPDATE OF r SKIP LOCKED")
+	}
+	tx, err := beginner.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin outbox claim: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	records, err := s.claim(ctx, tx, opts, " FOR UPDATE OF r SKIP LOCKED")
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit outbox claim: %w", err)
+	}
+	return records, nil
+}
+
+func (s *Store) claim(ctx context.Context, db DBTX, opts outbox.ClaimOptions, lock string) ([]outbox.Record, error) {
+	now := opts.Now.UnixNano()
+	predicateArgs := []any{
+		string(outbox.StatusPending), now,
+		string(outbox.StatusFailed), now,
+		string(outbox.StatusProcessing), now,
+	}
+	args := append([]any{}, predicateArgs...)
+	args = append(args, string(outbox.StatusPublished), string(outbox.StatusFailed))
+	args = append(args, predicateArgs...)
+	args = append([]any{string(outbox.StatusPublished), string(outbox.StatusFailed)}, args...)
+	candidates, err := s.selectCandidates(ctx, db,
+		"SELECT "+recordColumns+", "+fmt.Sprintf(predecessors, s.table)+" FROM "+s.table+" r"+
+			" WHERE "+claimable+" AND NOT ("+fmt.Sprintf(keyBlocked, s.table)+")"+
+			" ORDER BY created_at, id LIMIT ?"+lock,
+		append(args, opts.Limit)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	claimed := make([]outbox.Record, 0, len(candidates))
+	held := make(map[string]int)
+	for _, candidate := range candidates {
+		record := candidate.record
+		// An earlier record of the key that this claim does not hold is
+		// pending, waiting, or held by another relay, possibly one whose
+		// claim is still uncommitted; claiming past it would break order.
+		if record.Key != "" && held[record.Key] != candidate.predecessors {
+			continue
+		}
+		args := append([]any{
+			string(outbox.StatusProcessing), opts.LockedUntil.UnixNano(), opts.ClaimedBy, now,
+			record.ID, record.Attempts,
+		}, predicateArgs...)
+		result, err := db.ExecContext(ctx, s.dialect.rebind("UPDATE "+s.table+
+			" SET status = ?, attempts = attempts + 1, locked_until = ?, claimed_by = ?, updated_at = ?"+
+			" WHERE id = ? AND attempts = ? AND "+claimable), args...)
+		if err != nil {
+			return nil, fmt.Errorf("claim outbox record %s: %w", record.ID, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("claim outbox record %s: %w", record.ID, err)
+		}
+		if affected == 0 {
+			continue
+		}
+		record.Status = outbox.StatusProcessing
+		record.Attempts++
+		record.LockedUntil = opts.LockedUntil
+		record.ClaimedBy = opts.ClaimedBy
+		record.UpdatedAt = opts.Now
+		claimed = append(claimed, record)
+		if record.Key != "" {
+			held[record.Key]++
+		}
+	}
+	return claimed, nil
+}
+
+// MarkPublished marks records published. Records no longer held by the same
+// claim are left unchanged and reported with ErrStaleClaim.
+func (s *Store) MarkPublished(ctx context.Context, records ...outbox.Record) error {
+	var errs []error
+	for _, record := range records {
+		errs = append(errs, s.transition(ctx, record,
+			"status = ?, locked_until = 0, updated_at = ?",
+			string(outbox.StatusPublished), s.now().UnixNano(),
+		))
+	}
+	return errors.Join(errs...)
+}
+
+// MarkFailed records reason and schedules the next attempt of record. A zero
+// nextAttemptAt fails the record for good.
+func (s *Store) MarkFailed(ctx context.Context, record outbox.Record, reason string, nextAttemptAt time.Time) error {
+	return s.transition(ctx, record,
+		"status = ?, last_error = ?, next_attempt_at = ?, locked_until = 0, updated_at = ?",
+		string(outbox.StatusFailed), reason, unixNano(nextAttemptAt), s.now().UnixNano(),
+	)
+}
+
+// Release returns records to pending and takes back the attempt their claim
+// counted, leaving their next attempt time and last error as they were.
+// Records no longer held by the same claim are left unchanged and reported
+// with ErrStaleClaim.
+func (s *Store) Release(ctx context.Context, records ...outbox.Record) error {
+	var errs []error
+	for _, record := range records {
+		errs = append(errs, s.transition(ctx, record,
+			"status = ?, attempts = attempts - 1, locked_until = 0, updated_at = ?",
+			string(outbox.StatusPending), s.now().UnixNano(),
+		))
+	}
+	return errors.Join(errs...)
+}
+
+// Purge deletes up to limit published records last updated before olderThan,
+// oldest first, copying them into the archive table first when one is
+// configured. Each call runs in its own transaction unless the store is bound
+// to one.
+func (s *Store) Purge(ctx context.Context, olderThan time.Time, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+	beginner, ok := s.db.(txBeginner)
+	if !ok {
+		return s.purge(ctx, s.db, olderThan, limit)
+	}
+	tx, err := beginner.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin outbox purge: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	n, err := s.purge(ctx, tx, olderThan, limit)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit outbox purge: %w", err)
+	}
+	return n, nil
+}
+
+func (s *Store) purge(ctx context.Context, db DBTX, olderThan time.Time, limit int) (int, error) {
+	rows, err := db.QueryContext(ctx, s.dialect.rebind("SELECT id FROM "+s.table+
+		" WHERE status = ? AND updated_at < ? ORDER BY updated_at, id LIMIT ?"),
+		string(outbox.StatusPublished), olderThan.UnixNano(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("select published outbox records: %w", err)
+	}
+	var ids []any
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("scan outbox record id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, fmt.Errorf("select published outbox records: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("select published outbox records: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	in := " WHERE id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+	if s.archiveTable != "" {
+		if _, err := db.ExecContext(ctx, s.dialect.rebind("INSERT INTO "+s.archiveTable+" ("+recordColumns+
+			") SELECT "+recordColumns+" FROM "+s.table+in), ids...); err != nil {
+			return 0, fmt.Errorf("archive outbox records: %w", err)
+		}
+	}
+	result, err := db.ExecContext(ctx, s.dialect.rebind("DELETE FROM "+s.table+in), ids...)
+	if err != nil {
+		return 0, fmt.Errorf("delete outbox records: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("delete outbox records: %w", err)
+	}
+	return int(affected), nil
+}
+
+// transition applies set to record while it is still held by the claim it was
+// returned with.
+func (s *Store) transition(ctx context.Context, record outbox.Record, set string, args ...any) error {
+	args = append(args, record.ID, string(outbox.StatusProcessing), record.ClaimedBy, record.Attempts)
+	result, err := s.exec(ctx, "UPDATE "+s.table+" SET "+set+
+		" WHERE id = ? AND status = ? AND claimed_by = ? AND attempts = ?", args...)
+	if err != nil {
+		return fmt.Errorf("update outbox record %s: %w", record.ID, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update outbox record %s: %w", record.ID, err)
+	}
+	if affected != 1 {
+		return fmt.Errorf("%w: %s", ErrStaleClaim, record.ID)
+	}
+	return nil
+}
+
+type candidate struct {
+	record       outbox.Record
+	predecessors int
+}
+
+func (s *Store) selectCandidates(ctx context.Context, db DBTX, query string, args ...any) ([]candidate, error) {
+	rows, err := db.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("select outbox records: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []candidate
+	for rows.Next() {
+		var (
+			c                                      candidate
+			record                                 = &c.record
+			kind, headers, status                  string
+			occurredAt, nextAttemptAt, lockedUntil int64
+			createdAt, updatedAt                   int64
+		)
+		if err := rows.Scan(&record.ID, &record.MessageID, &kind, &record.Key, &occurredAt, &record.Payload, &headers,
+			&status, &record.Attempts, &nextAttemptAt, &lockedUntil, &record.ClaimedBy, &record.LastError,
+			&createdAt, &updatedAt, &c.predecessors); err != nil {
+			return nil, fmt.Errorf("scan outbox record: %w", err)
+		}
+		if record.Headers, err = decodeHeaders(headers); err != nil {
+			return nil, fmt.Errorf("decode outbox record %s headers: %w", record.ID, err)
+		}
+		record.Kind = message.Kind(kind)
+		record.Status = outbox.Status(status)
+		record.OccurredAt = fromUnixNano(occurredAt)
+		record.NextAttemptAt = fromUnixNano(nextAttemptAt)
+		record.LockedUntil = fromUnixNano(lockedUntil)
+		record.CreatedAt = fromUnixNano(createdAt)
+		record.UpdatedAt = fromUnixNano(updatedAt)
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select outbox records: %w", err)
+	}
+	return candidates, nil
+}
+
+func (s *Store) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.db.ExecContext(ctx, s.dialect.rebind(query), args...)
+}
+
+func encodeHeaders(headers map[string]string) (string, error) {
+	if len(headers) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(headers)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func decodeHeaders(data string) (map[string]string, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(data), &headers); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
@@ -0,0 +1,490 @@
+package sqlstore_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/ddd/message"
+	"github.com/go-jimu/components/ddd/message/outbox"
+	"github.com/go-jimu/components/ddd/message/outbox/sqlstore"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var start = time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "outbox.db")+"?_busy_timeout=5000&_txlock=immediate")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func newStore(t *testing.T, db *sql.DB, now *time.Time) *sqlstore.Store {
+	t.Helper()
+	store, err := sqlstore.New(db, sqlstore.DialectSQLite, sqlstore.WithClock(func() time.Time { return *now }))
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.Background()))
+	return store
+}
+
+func newCodec(t *testing.T) *outbox.ProtoCodec {
+	t.Helper()
+	codec := outbox.NewProtoCodec()
+	require.NoError(t, codec.Register("customer.created", func() proto.Message { return &wrapperspb.StringValue{} }))
+	return codec
+}
+
+func newMessage(t *testing.T, id, key string) message.Message {
+	t.Helper()
+	msg, err := message.New("customer.created", wrapperspb.String(id),
+		message.WithID(id),
+		message.WithKey(key),
+		message.WithOccurredAt(start),
+		message.WithHeader("trace", id),
+	)
+	require.NoError(t, err)
+	return msg
+}
+
+func claimOptions(now time.Time, worker string, limit int) outbox.ClaimOptions {
+	return outbox.ClaimOptions{Limit: limit, Now: now, LockedUntil: now.Add(time.Minute), ClaimedBy: worker}
+}
+
+type publisher struct {
+	mu   sync.Mutex
+	ids  []string
+	fail string
+}
+
+func (p *publisher) Publish(_ context.Context, msg message.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if msg.ID() == p.fail {
+		return errors.New("broker unavailable")
+	}
+	p.ids = append(p.ids, msg.ID())
+	return nil
+}
+
+// Records recorded in the business transaction should be relayed in order
+// and not claimed again once published.
+func TestStore_RecordsAndRelaysInOrder(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	now := start
+	store := newStore(t, db, &now)
+	codec := newCodec(t)
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	recorder, err := outbox.NewRecorder(store.WithTx(tx), codec)
+	require.NoError(t, err)
+	require.NoError(t, recorder.Record(ctx, newMessage(t, "m1", "c1"), newMessage(t, "m2", "c1"), newMessage(t, "m3", "c2")))
+	require.NoError(t, tx.Commit())
+
+	pub := &publisher{}
+	relay, err := outbox.NewRelay(store, codec, pub, outbox.WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	result := relay.RunOnce(ctx, claimOptions(now, "relay-1", 10))
+	require.Empty(t, result.Errors)
+	require.Equal(t, 3, result.Published)
+	require.Equal(t, []string{"m1", "m2", "m3"}, pub.ids)
+
+	now = now.Add(time.Hour)
+	claimed, err := store.Claim(ctx, claimOptions(now, "relay-1", 10))
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+}
+
+// A rolled back business transaction must not leave outbox records behind.
+func TestStore_RolledBackAppendIsDiscarded(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	now := start
+	store := newStore(t, db, &now)
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	recorder, err := outbox.NewRecorder(store.WithTx(tx), newCodec(t))
+	require.NoError(t, err)
+	require.NoError(t, recorder.Record(ctx, newMessage(t, "m1", "c1")))
+	require.NoError(t, tx.Rollback())
+
+	claimed, err := store.Claim(ctx, claimOptions(now, "relay-1", 10))
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+}
+
+// Claim should return records with their metadata, lock them for the
+// claimant, and count the attempt.
+func TestStore_ClaimLocksRecords(t *testing.T) {
+	ctx := context.Background()
+	now := start
+	store := newStore(t, openDB(t), &now)
+	record, err := newCodec(t).Encode(newMessage(t, "m1", "c1"))
+	require.NoError(t, err)
+	require.NoError(t, store.Append(ctx, record))
+
+	claimed, err := store.Claim(ctx, claimOptions(now, "relay-1", 10))
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	got := claimed[0]
+	require.Equal(t, record.ID, got.ID)
+	require.Equal(t, "m1", got.MessageID)
+	require.Equal(t, message.Kind("customer.created"), got.Kind)
+	require.Equal(t, "c1", got.Key)
+	require.True(t, start.Equal(got.OccurredAt))
+	require.Equal(t, record.Payload, got.Payload)
+	require.Equal(t, map[string]string{"trace": "m1"}, got.Headers)
+	require.Equal(t, outbox.StatusProcessing, got.Status)
+	require.Equal(t, 1, got.Attempts)
+	require.Equal(t, "relay-1", got.ClaimedBy)
+	require.True(t, now.Add(time.Minute).Equal(got.LockedUntil))
+
+	claimed, err = store.Claim(ctx, claimOptions(now, "relay-2", 10))
+	require.NoError(t, err)
+	require.Empty(t, claimed, "a locked record was claimed twice")
+}
+
+// Failed records should be claimable again only when a retry is scheduled
+// and due.
+func TestStore_MarkFailedSchedulesRetries(t *testing.T) {
+	ctx := context.Background()
+	now := start
+	store := newStore(t, openDB(t), &now)
+	codec := newCodec(t)
+	for _, id := range []string{"retry", "terminal"} {
+		record, err := codec.Encode(newMessage(t, id, id))
+		require.NoError(t, err)
+		require.NoError(t, store.Append(ctx, record))
+	}
+
+	claimed, err := store.Claim(ctx, claimOptions(now, "relay-1", 10))
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	require.NoError(t, store.MarkFailed(ctx, claimed[0], "broker unavailable", now.Add(10*time.Second)))
+	require.NoError(t, store.MarkFailed(ctx, claimed[1], "unknown kind", time.Time{}))
+
+	claimed, err = store.Claim(ctx, claimOptions(now.Add(5*time.Second), "relay-1", 10))
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+
+	now = now.Add(10 * time.Second)
+	claimed, err = store.Claim(ctx, claimOptions(now, "relay-1", 10))
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, "retry", claimed[0].MessageID)
+	require.Equal(t, 2, claimed[0].Attempts)
+	require.Equal(t, "broker unavailable", claimed[0].LastError)
+}
+
+// Records a relay defers behind a failed record of their key should be
+// released without losing an attempt and follow it once it is retried.
+func TestStore_ReleasesDeferredRecords(t *testing.T) {
+	ctx := context.Background()
+	now := start
+	store := newStore(t, openDB(t), &now)
+	codec := newCodec(t)
+	for _, id := range []string{"c1-1", "c1-2", "c1-3"} {
+		record, err := codec.Encode(newMessage(t, id, "c1"))
+		require.NoError(t, err)
+		require.NoError(t, store.Append(ctx, record))
+	}
+	pub := &publisher{fail: "c1-1"}
+	relay, err := outbox.NewRelay(store, codec, pub, outbox.WithClock(func() time.Time { return now }),
+		outbox.WithRetryPolicy(outbox.FixedBackoffPolicy{Backoff: 10 * time.Second}))
+	require.NoError(t, err)
+
+	result := relay.RunOnce(ctx, claimOptions(now, "relay-1", 10))
+	require.Empty(t, result.Errors)
+	require.Equal(t, 1, result.Failed)
+	require.Equal(t, 2, result.Deferred)
+
+	claimed, err := store.Claim(ctx, claimOptions(now, "relay-2", 10))
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+
+	now = now.Add(10 * time.Second)
+	claimed, err = store.Claim(ctx, claimOptions(now, "relay-2", 10))
+	require.NoError(t, err)
+	require.Len(t, claimed, 3)
+	for i, attempts := range []int{2, 1, 1} {
+		require.Equal(t, attempts, claimed[i].Attempts, claimed[i].MessageID)
+	}
+	stale := claimed[1]
+	stale.ClaimedBy = "relay-1"
+	require.ErrorIs(t, store.Release(ctx, stale), sqlstore.ErrStaleClaim)
+}
+
+// Later records of a key should not be claimed while an earlier one waits
+// for a retry or is held by another claim, and should follow it once it is
+// published. A record that failed for good does not block its key.
+func TestStore_ClaimKeepsKeyOrder(t *testing.T) {
+	ctx := context.Background()
+	now := start
+	store := newStore(t, openDB(t), &now)
+	codec := newCodec(t)
+	for _, msg := range []message.Message{
+		newMessage(t, "c1-1", "c1"), newMessage(t, "c1-2", "c1"),
+		newMessage(t, "c2-1", "c2"), newMessage(t, "c2-2", "c2"),
+	} {
+		record, err := codec.Encode(msg)
+		require.NoError(t, err)
+		require.NoError(t, store.Append(ctx, record))
+	}
+
+	first, err := store.Claim(ctx, claimOptions(now, "relay-1", 1))
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	claimed, err := store.Claim(ctx, claimOptions(now, "relay-2", 10))
+	require.NoError(t, err)
+	require.Equal(t, []string{"c2-1", "c2-2"}, messageIDs(claimed), "c1-2 was claimed while c1-1 is held")
+
+	require.NoError(t, store.MarkFailed(ctx, first[0], "broker unavailable", now.Add(10*time.Second)))
+	require.NoError(t, store.MarkFailed(ctx, claimed[0], "unknown kind", time.Time{}))
+	require.NoError(t, store.MarkFailed(ctx, claimed[1], "broker unavailable", now.Add(time.Second)))
+	now = now.Add(time.Second)
+	claimed, err = store.Claim(ctx, claimOptions(now, "relay-2", 10))
+	require.NoError(t, err)
+	require.Equal(t, []string{"c2-2"}, messageIDs(claimed), "c1-2 was claimed while c1-1 waits for a retry")
+
+	now = now.Add(10 * time.Second)
+	claimed, err = store.Claim(ctx, claimOptions(now, "relay-1", 10))
+	require.NoError(t, err)
+	require.Equal(t, []string{"c1-1", "c1-2"}, messageIDs(claimed))
+}
+
+// beforeFirstExec runs hook once, right before the first statement executed
+// through it, to interleave another relay between a claim's select and its
+// conditional updates.
+type beforeFirstExec struct {
+	*sql.DB
+	once sync.Once
+	hook func()
+}
+
+func (db *beforeFirstExec) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	db.once.Do(db.hook)
+	return db.DB.ExecContext(ctx, query, args...)
+}
+
+// When another relay claims the first record of a key after this relay
+// selected the key's records, this relay must not claim the later ones.
+func TestStore_ClaimDoesNotPassAnotherRelaysRecord(t *testing.T) {
+	ctx := context.Background()
+	now := start
+	db := openDB(t)
+	store := newStore(t, db, &now)
+	codec := newCodec(t)
+	for _, id := range []string{"c1-1", "c1-2"} {
+		record, err := codec.Encode(newMessage(t, id, "c1"))
+		require.NoError(t, err)
+		require.NoError(t, store.Append(ctx, record))
+	}
+
+	var first []outbox.Record
+	racing := &beforeFirstExec{DB: db, hook: func() {
+		var err error
+		first, err = store.Claim(ctx, claimOptions(now, "relay-1", 1))
+		require.NoError(t, err)
+	}}
+	raced, err := sqlstore.New(racing, sqlstore.DialectSQLite, sqlstore.WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	claimed, err := raced.Claim(ctx, claimOptions(now, "relay-2", 10))
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"c1-1"}, messageIDs(first))
+	require.Empty(t, claimed, "relay-2 claimed c1-2 while relay-1 holds c1-1")
+}
+
+// Concurrent relays must never hold records of the same key at once, and
+// each key must be published in append order.
+func TestStore_ConcurrentClaimsKeepKeyOrder(t *testing.T) {
+	ctx := context.Background()
+	now := start
+	store := newStore(t, openDB(t), &now)
+	codec := newCodec(t)
+	const keys, perKey = 3, 10
+	for i := range perKey {
+		for k := range keys {
+			record, err := codec.Encode(newMessage(t, fmt.Sprintf("c%d-%02d", k, i), fmt.Sprintf("c%d", k)))
+			require.NoError(t, err)
+			require.NoError(t, store.Append(ctx, record))
+		}
+	}
+
+	var (
+		mu        sync.Mutex
+		holders   = make(map[string]string)
+		published = make(map[string][]string)
+		wg        sync.WaitGroup
+		errs      = make(chan error, 64)
+	)
+	for w := range 4 {
+		worker := fmt.Sprintf("relay-%d", w)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idle := 0; idle < 20; {
+				records, err := store.Claim(ctx, claimOptions(now, worker, 4))
+				if err != nil {
+					errs <- err
+					return
+				}
+				if len(records) == 0 {
+					idle++
+					time.Sleep(time.Millisecond)
+					continue
+				}
+				idle = 0
+				mu.Lock()
+				for _, record := range records {
+					if holder, ok := holders[record.Key]; ok && holder != worker {
+						errs <- fmt.Errorf("%s claimed %s while %s holds key %s", worker, record.MessageID, holder, record.Key)
+					}
+					holders[record.Key] = worker
+					published[record.Key] = append(published[record.Key], record.MessageID)
+				}
+				for _, record := range records {
+					delete(holders, record.Key)
+				}
+				mu.Unlock()
+				if err := store.MarkPublished(ctx, records...); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	for k := range keys {
+		key := fmt.Sprintf("c%d", k)
+		require.Len(t, published[key], perKey, key)
+		require.IsIncreasing(t, published[key], key)
+	}
+}
+
+// An expired lease should let another relay reclaim the record, and the
+// first relay must not be able to change it afterwards.
+func TestStore_ReclaimsExpiredLeases(t *testing.T) {
+	ctx := context.Background()
+	now := start
+	store := newStore(t, openDB(t), &now)
+	record, err := newCodec(t).Encode(newMessage(t, "m1", "c1"))
+	require.NoError(t, err)
+	require.NoError(t, store.Append(ctx, record))
+
+	first, err := store.Claim(ctx, claimOptions(now, "relay-1", 1))
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+
+	now = now.Add(2 * time.Minute)
+	second, err := store.Claim(ctx, claimOptions(now, "relay-2", 1))
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	require.Equal(t, 2, second[0].Attempts)
+
+	require.ErrorIs(t, store.MarkPublished(ctx, first[0]), sqlstore.ErrStaleClaim)
+	require.ErrorIs(t, store.MarkFailed(ctx, first[0], "late", time.Time{}), sqlstore.ErrStaleClaim)
+	require.NoError(t, store.MarkPublished(ctx, second[0]))
+}
+
+// Concurrent relays must never claim the same record.
+func TestStore_ConcurrentClaimsAreExclusive(t *testing.T) {
+	ctx := context.Background()
+	now := start
+	store := newStore(t, openDB(t), &now)
+	codec := newCodec(t)
+	const total = 30
+	for i := range total {
+		// Distinct keys: a shared key would hold later records back while an
+		// earlier one is claimed by another relay.
+		record, err := codec.Encode(newMessage(t, fmt.Sprintf("m%d", i), fmt.Sprintf("c%d", i)))
+		require.NoError(t, err)
+		require.NoError(t, store.Append(ctx, record))
+	}
+
+	var (
+		mu      sync.Mutex
+		claimed = make(map[string]string)
+		wg      sync.WaitGroup
+		errs    = make(chan error, 4)
+	)
+	for w := range 4 {
+		worker := fmt.Sprintf("relay-%d", w)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				records, err := store.Claim(ctx, claimOptions(now, worker, 3))
+				if err != nil {
+					errs <- err
+					return
+				}
+				if len(records) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, record := range records {
+					if owner, ok := claimed[record.ID]; ok {
+						errs <- fmt.Errorf("record %s claimed by %s and %s", record.ID, owner, worker)
+					}
+					claimed[record.ID] = worker
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Len(t, claimed, total)
+}
+
+// Invalid configuration and records should be rejected before touching the
+// database.
+func TestStore_ValidationErrors(t *testing.T) {
+	db := openDB(t)
+	_, err := sqlstore.New(nil, sqlstore.DialectSQLite)
+	require.ErrorIs(t, err, sqlstore.ErrNilDB)
+	_, err = sqlstore.New(db, "oracle")
+	require.ErrorIs(t, err, sqlstore.ErrUnknownDialect)
+	_, err = sqlstore.New(db, sqlstore.DialectSQLite, sqlstore.WithTable("outbox; DROP TABLE users"))
+	require.ErrorIs(t, err, sqlstore.ErrInvalidTable)
+
+	for _, dialect := range []sqlstore.Dialect{sqlstore.DialectPostgres, sqlstore.DialectMySQL, sqlstore.DialectSQLite} {
+		statements, err := sqlstore.Schema(dialect, "app.outbox")
+		require.NoError(t, err)
+		require.NotEmpty(t, statements)
+	}
+
+	now := start
+	store := newStore(t, db, &now)
+	require.ErrorIs(t, store.Append(context.Background(), outbox.Record{ID: "r1", Kind: "customer.created"}), sqlstore.ErrInvalidRecord)
+	_, err = store.Claim(context.Background(), outbox.ClaimOptions{Limit: 1, Now: now, LockedUntil: now})
+	require.ErrorIs(t, err, outbox.ErrInvalidClaimOptions)
+}
+
+func messageIDs(records []outbox.Record) []string {
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i] = record.MessageID
+	}
+	return ids
+}
@@ -12,6 +12,16 @@ type Store interface {
 	MarkFailed(ctx context.Context, record Record, reason string, nextAttemptAt time.Time) error
 }
 
+// Releaser is an optional Store capability that hands claimed records back.
+//
+// Release returns records still held by the claim they were returned with to
+// StatusPending without counting that claim as an attempt, so they can be
+// claimed again as soon as the records before them allow. Relay releases the
+// records it defers.
+type Releaser interface {
+	Release(ctx context.Context, records ...Record) error
+}
+
 type ClaimOptions struct {
 	Limit       int
 	Now         time.Time
@@ -0,0 +1,34 @@
+// Package sqlutil holds the database/sql helpers shared by the SQL-backed
+// packages of this module.
+package sqlutil
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// ValidTable reports whether table is a plain or schema-qualified identifier
+// that is safe to interpolate into SQL statements.
+func ValidTable(table string) bool {
+	return tableNamePattern.MatchString(table)
+}
+
+// Rebind rewrites ? placeholders into PostgreSQL's numbered $n placeholders.
+func Rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,28 @@
+package sqlutil
+
+import "testing"
+
+// Intent: Table names should be plain or schema-qualified identifiers only,
+// so they can be interpolated into SQL safely.
+func TestValidTable(t *testing.T) {
+	for table, want := range map[string]bool{
+		"tasks":         true,
+		"jobs.tasks_v2": true,
+		"":              false,
+		"1tasks":        false,
+		"tasks;drop":    false,
+		"a.b.c":         false,
+	} {
+		if got := ValidTable(table); got != want {
+			t.Fatalf("ValidTable(%q) = %v, want %v", table, got, want)
+		}
+	}
+}
+
+// Intent: Rebind should number placeholders in order for PostgreSQL.
+func TestRebind(t *testing.T) {
+	got := Rebind("UPDATE t SET a = ? WHERE id = ? AND b = ?")
+	if want := "UPDATE t SET a = $1 WHERE id = $2 AND b = $3"; got != want {
+		t.Fatalf("Rebind = %q, want %q", got, want)
+	}
+}
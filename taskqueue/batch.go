@@ -0,0 +1,315 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxBatchSize = 100
+	defaultMaxBatchWait = 100 * time.Millisecond
+)
+
+// BatchProcessor processes a group of tasks of one type in a single call.
+//
+// ProcessBatch returns nil when every task succeeded, BatchErrors to report
+// per-task outcomes, or any other error to fail every task in the batch.
+type BatchProcessor interface {
+	TaskType() TaskType
+	ProcessBatch(context.Context, []Task) error
+}
+
+// BatchProcessorFunc processes a group of tasks.
+type BatchProcessorFunc func(context.Context, []Task) error
+
+type functionBatchProcessor struct {
+	taskType TaskType
+	fn       BatchProcessorFunc
+}
+
+// NewBatchProcessor constructs a BatchProcessor for one task type.
+func NewBatchProcessor(taskType TaskType, fn BatchProcessorFunc) BatchProcessor {
+	return functionBatchProcessor{taskType: taskType, fn: fn}
+}
+
+func (p functionBatchProcessor) TaskType() TaskType {
+	return p.taskType
+}
+
+func (p functionBatchProcessor) ProcessBatch(ctx context.Context, tasks []Task) error {
+	if p.fn == nil {
+		return ErrNilProcessor
+	}
+	return p.fn(ctx, tasks)
+}
+
+// BatchErrors reports per-task outcomes of a batch. Entry i belongs to the
+// i-th task passed to ProcessBatch; nil entries succeeded.
+type BatchErrors []error
+
+func (e BatchErrors) Error() string {
+	var failures []string
+	for i, err := range e {
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("task %d: %v", i, err))
+		}
+	}
+	return fmt.Sprintf("%d of %d batched tasks failed: %s", len(failures), len(e), strings.Join(failures, "; "))
+}
+
+// Unwrap returns the non-nil member errors.
+func (e BatchErrors) Unwrap() []error {
+	var errs []error
+	for _, err := range e {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// BatchOption configures a Batcher.
+type BatchOption func(*Batcher)
+
+// WithMaxBatchSize flushes a batch once it holds size tasks.
+func WithMaxBatchSize(size int) BatchOption {
+	return func(b *Batcher) {
+		if size > 0 {
+			b.maxSize = size
+		}
+	}
+}
+
+// WithMaxBatchWait flushes a batch wait after its first task arrived, even
+// when it is not full.
+func WithMaxBatchWait(wait time.Duration) BatchOption {
+	return func(b *Batcher) {
+		if wait > 0 {
+			b.maxWait = wait
+		}
+	}
+}
+
+// WithBatchByKey groups tasks by Task.Key in addition to task type, so every
+// batch contains tasks of a single key.
+func WithBatchByKey() BatchOption {
+	return func(b *Batcher) {
+		b.byKey = true
+	}
+}
+
+// Batcher adapts a BatchProcessor to Processor. Each Process call joins the
+// pending batch for its group and blocks until that batch has been processed,
+// then returns the task's own outcome, so provider retries and dead letters
+// apply only to failed members.
+//
+// Batches fill only while the provider runs tasks of the type concurrently;
+// with less concurrency than the max batch size, batches flush on max wait.
+type Batcher struct {
+	processor BatchProcessor
+	maxSize   int
+	maxWait   time.Duration
+	byKey     bool
+
+	mu      sync.Mutex
+	pending map[string]*pendingBatch
+}
+
+var _ Processor = (*Batcher)(nil)
+
+type pendingBatch struct {
+	key     string
+	members []*batchMember
+	timer   *time.Timer
+}
+
+type batchMember struct {
+	ctx  context.Context
+	task Task
+	done chan error
+}
+
+// NewBatcher constructs a Batcher for processor.
+func NewBatcher(processor BatchProcessor, opts ...BatchOption) (*Batcher, error) {
+	if processor == nil {
+		return nil, ErrNilProcessor
+	}
+	if processor.TaskType() == "" {
+		return nil, ErrEmptyType
+	}
+	b := &Batcher{
+		processor: processor,
+		maxSize:   defaultMaxBatchSize,
+		maxWait:   defaultMaxBatchWait,
+		pending:   make(map[string]*pendingBatch),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(b)
+		}
+	}
+	return b, nil
+}
+
+// TaskType returns the task type of the wrapped BatchProcessor.
+func (b *Batcher) TaskType() TaskType {
+	return b.processor.TaskType()
+}
+
+// Process adds task to its pending batch and waits for the batch outcome. A
+// task whose context is done before its batch is dispatched leaves the batch
+// and returns the context error.
+func (b *Batcher) Process(ctx context.Context, task Task) error {
+	member := &batchMember{ctx: ctx, task: task, done: make(chan error, 1)}
+	key := ""
+	if b.byKey {
+		key = task.Key()
+	}
+
+	b.mu.Lock()
+	batch := b.pending[key]
+	if batch == nil {
+		batch = &pendingBatch{key: key}
+		batch.timer = time.AfterFunc(b.maxWait, func() { b.flushOnTimer(batch) })
+		b.pending[key] = batch
+	}
+	batch.members = append(batch.members, member)
+	full := len(batch.members) >= b.maxSize
+	if full {
+		b.detachLocked(batch)
+	}
+	b.mu.Unlock()
+	if full {
+		go b.run(batch)
+	}
+
+	select {
+	case err := <-member.done:
+		return err
+	case <-ctx.Done():
+	}
+	b.mu.Lock()
+	withdrawn := b.withdrawLocked(key, member)
+	b.mu.Unlock()
+	if withdrawn {
+		return ctx.Err()
+	}
+	// The batch was already dispatched; report what actually happened.
+	return <-member.done
+}
+
+// Flush dispatches all pending batches without waiting for them to fill.
+func (b *Batcher) Flush() {
+	b.mu.Lock()
+	batches := make([]*pendingBatch, 0, len(b.pending))
+	for _, batch := range b.pending {
+		b.detachLocked(batch)
+		batches = append(batches, batch)
+	}
+	b.mu.Unlock()
+	for _, batch := range batches {
+		go b.run(batch)
+	}
+}
+
+func (b *Batcher) flushOnTimer(batch *pendingBatch) {
+	b.mu.Lock()
+	current := b.pending[batch.key] == batch
+	if current {
+		b.detachLocked(batch)
+	}
+	b.mu.Unlock()
+	if current {
+		b.run(batch)
+	}
+}
+
+func (b *Batcher) detachLocked(batch *pendingBatch) {
+	batch.timer.Stop()
+	if b.pending[batch.key] == batch {
+		delete(b.pending, batch.key)
+	}
+}
+
+func (b *Batcher) withdrawLocked(key string, member *batchMember) bool {
+	batch := b.pending[key]
+	if batch == nil {
+		return false
+	}
+	for i, candidate := range batch.members {
+		if candidate != member {
+			continue
+		}
+		batch.members = append(batch.members[:i], batch.members[i+1:]...)
+		if len(batch.members) == 0 {
+			b.detachLocked(batch)
+		}
+		return true
+	}
+	return false
+}
+
+func (b *Batcher) run(batch *pendingBatch) {
+	if len(batch.members) == 0 {
+		return
+	}
+	tasks := make([]Task, len(batch.members))
+	for i, member := range batch.members {
+		tasks[i] = member.task
+	}
+	ctx, cancel := batchContext(batch.members)
+	defer cancel()
+
+	errs := batchOutcomes(b.processBatch(ctx, tasks), len(tasks))
+	for i, member := range batch.members {
+		member.done <- errs[i]
+	}
+}
+
+func (b *Batcher) processBatch(ctx context.Context, tasks []Task) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = panicAsError(recovered)
+		}
+	}()
+	return b.processor.ProcessBatch(ctx, tasks)
+}
+
+// batchContext keeps the values of the first member context and the earliest
+// member deadline, but is not canceled when one member gives up.
+func batchContext(members []*batchMember) (context.Context, context.CancelFunc) {
+	ctx := context.WithoutCancel(members[0].ctx)
+	var deadline time.Time
+	for _, member := range members {
+		if d, ok := member.ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+			deadline = d
+		}
+	}
+	if deadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+func batchOutcomes(err error, size int) []error {
+	errs := make([]error, size)
+	if err == nil {
+		return errs
+	}
+	var batchErrs BatchErrors
+	if errors.As(err, &batchErrs) {
+		if len(batchErrs) == size {
+			copy(errs, batchErrs)
+			return errs
+		}
+		err = fmt.Errorf("%w: got %d results for %d tasks", ErrInvalidBatchResult, len(batchErrs), size)
+	}
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
@@ -0,0 +1,169 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Intent: A full batch should be processed in one call and each member should
+// receive its own outcome.
+func TestBatcherReportsPerTaskOutcomes(t *testing.T) {
+	failure := errors.New("index conflict")
+	calls := make(chan []Task, 1)
+	batcher, err := NewBatcher(NewBatchProcessor("search.index", func(_ context.Context, tasks []Task) error {
+		calls <- tasks
+		errs := make(BatchErrors, len(tasks))
+		for i, task := range tasks {
+			if task.Key() == "doc-2" {
+				errs[i] = failure
+			}
+		}
+		return errs
+	}), WithMaxBatchSize(3), WithMaxBatchWait(time.Hour))
+	if err != nil {
+		t.Fatalf("NewBatcher: %v", err)
+	}
+
+	results := processConcurrently(t, batcher, "doc-1", "doc-2", "doc-3")
+	if got := len(<-calls); got != 3 {
+		t.Fatalf("batch size = %d, want 3", got)
+	}
+	for key, err := range results {
+		if key == "doc-2" && !errors.Is(err, failure) {
+			t.Fatalf("%s error = %v, want failure", key, err)
+		}
+		if key != "doc-2" && err != nil {
+			t.Fatalf("%s error = %v, want nil", key, err)
+		}
+	}
+}
+
+// Intent: A batch that never fills should still be processed after max wait,
+// and a plain error should fail every member.
+func TestBatcherFlushesAfterMaxWait(t *testing.T) {
+	failure := errors.New("search unavailable")
+	batcher, err := NewBatcher(NewBatchProcessor("search.index", func(context.Context, []Task) error {
+		return failure
+	}), WithMaxBatchSize(10), WithMaxBatchWait(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewBatcher: %v", err)
+	}
+
+	for key, err := range processConcurrently(t, batcher, "doc-1", "doc-2") {
+		if !errors.Is(err, failure) {
+			t.Fatalf("%s error = %v, want failure", key, err)
+		}
+	}
+}
+
+// Intent: WithBatchByKey should never mix keys within one batch.
+func TestBatcherGroupsByKey(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]Task
+	batcher, err := NewBatcher(NewBatchProcessor("search.index", func(_ context.Context, tasks []Task) error {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, tasks)
+		return nil
+	}), WithBatchByKey(), WithMaxBatchWait(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewBatcher: %v", err)
+	}
+
+	processConcurrently(t, batcher, "tenant-a", "tenant-b", "tenant-a")
+	if len(batches) != 2 {
+		t.Fatalf("batches = %d, want 2", len(batches))
+	}
+	for _, batch := range batches {
+		for _, task := range batch {
+			if task.Key() != batch[0].Key() {
+				t.Fatalf("batch mixes keys %q and %q", batch[0].Key(), task.Key())
+			}
+		}
+	}
+}
+
+// Intent: A result with the wrong length should fail the batch instead of
+// misattributing outcomes.
+func TestBatcherRejectsMismatchedResults(t *testing.T) {
+	batcher, err := NewBatcher(NewBatchProcessor("search.index", func(context.Context, []Task) error {
+		return BatchErrors{nil}
+	}), WithMaxBatchSize(2), WithMaxBatchWait(time.Hour))
+	if err != nil {
+		t.Fatalf("NewBatcher: %v", err)
+	}
+
+	for key, err := range processConcurrently(t, batcher, "doc-1", "doc-2") {
+		if !errors.Is(err, ErrInvalidBatchResult) {
+			t.Fatalf("%s error = %v, want ErrInvalidBatchResult", key, err)
+		}
+	}
+}
+
+// Intent: A member whose context ends before dispatch should leave the batch.
+func TestBatcherWithdrawsCanceledMember(t *testing.T) {
+	calls := make(chan int, 1)
+	batcher, err := NewBatcher(NewBatchProcessor("search.index", func(_ context.Context, tasks []Task) error {
+		calls <- len(tasks)
+		return nil
+	}), WithMaxBatchWait(time.Hour))
+	if err != nil {
+		t.Fatalf("NewBatcher: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := batcher.Process(ctx, newBatchTask(t, "doc-1")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Process error = %v, want deadline exceeded", err)
+	}
+	batcher.Flush()
+	select {
+	case size := <-calls:
+		t.Fatalf("processed batch of %d after withdrawal", size)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// Intent: Constructor errors should match processor registration errors.
+func TestNewBatcherValidation(t *testing.T) {
+	if _, err := NewBatcher(nil); !errors.Is(err, ErrNilProcessor) {
+		t.Fatalf("nil processor error = %v, want ErrNilProcessor", err)
+	}
+	if _, err := NewBatcher(NewBatchProcessor("", nil)); !errors.Is(err, ErrEmptyType) {
+		t.Fatalf("empty type error = %v, want ErrEmptyType", err)
+	}
+}
+
+func processConcurrently(t *testing.T, batcher *Batcher, keys ...string) map[string]error {
+	t.Helper()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]error, len(keys))
+	for _, key := range keys {
+		task := newBatchTask(t, key)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := batcher.Process(context.Background(), task)
+			mu.Lock()
+			defer mu.Unlock()
+			if results[key] == nil {
+				results[key] = err
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func newBatchTask(t *testing.T, key string) Task {
+	t.Helper()
+	task, err := New(Definition{Type: "search.index"}, nil, WithKey(key))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return task
+}
@@ -0,0 +1,223 @@
+package taskqueue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronExpression is a parsed standard five-field cron expression.
+//
+// Fields are minute (0-59), hour (0-23), day of month (1-31), month (1-12 or
+// JAN-DEC), and day of week (0-7 or SUN-SAT, where both 0 and 7 are Sunday).
+// Each field accepts *, single values, ranges (a-b), steps (*/n, a-b/n, a/n),
+// and comma-separated lists. When both day of month and day of week are
+// restricted, a day matches if either field matches, as in Vixie cron.
+type CronExpression struct {
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	domStar bool
+	dowStar bool
+}
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	cronMinute = cronField{name: "minute", min: 0, max: 59}
+	cronHour   = cronField{name: "hour", min: 0, max: 23}
+	cronDOM    = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDOW = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// ParseCron parses a standard five-field cron expression.
+func ParseCron(spec string) (CronExpression, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return CronExpression{}, ErrEmptySchedule
+	}
+	if len(fields) != 5 {
+		return CronExpression{}, ErrInvalidSchedule
+	}
+
+	var (
+		expr CronExpression
+		err  error
+	)
+	if expr.minute, err = cronMinute.parse(fields[0]); err != nil {
+		return CronExpression{}, err
+	}
+	if expr.hour, err = cronHour.parse(fields[1]); err != nil {
+		return CronExpression{}, err
+	}
+	if expr.dom, err = cronDOM.parse(fields[2]); err != nil {
+		return CronExpression{}, err
+	}
+	if expr.month, err = cronMonth.parse(fields[3]); err != nil {
+		return CronExpression{}, err
+	}
+	if expr.dow, err = cronDOW.parse(fields[4]); err != nil {
+		return CronExpression{}, err
+	}
+	if expr.dow&(1<<7) != 0 {
+		expr.dow |= 1
+	}
+	expr.domStar = strings.HasPrefix(fields[2], "*")
+	expr.dowStar = strings.HasPrefix(fields[4], "*")
+	return expr, nil
+}
+
+func (f cronField) parse(field string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		partBits, err := f.parsePart(part)
+		if err != nil {
+			return 0, err
+		}
+		bits |= partBits
+	}
+	return bits, nil
+}
+
+func (f cronField) parsePart(part string) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(part, "/")
+	step := 1
+	if hasStep {
+		parsed, err := strconv.Atoi(stepPart)
+		if err != nil || parsed <= 0 {
+			return 0, f.invalid(part)
+		}
+		step = parsed
+	}
+
+	var start, end int
+	switch {
+	case rangePart == "*":
+		start, end = f.min, f.max
+	case strings.Contains(rangePart, "-"):
+		low, high, _ := strings.Cut(rangePart, "-")
+		var err error
+		if start, err = f.value(low); err != nil {
+			return 0, f.invalid(part)
+		}
+		if end, err = f.value(high); err != nil {
+			return 0, f.invalid(part)
+		}
+	default:
+		value, err := f.value(rangePart)
+		if err != nil {
+			return 0, f.invalid(part)
+		}
+		start, end = value, value
+		if hasStep {
+			end = f.max
+		}
+	}
+	if start < f.min || end > f.max || start > end {
+		return 0, f.invalid(part)
+	}
+
+	var bits uint64
+	for value := start; value <= end; value += step {
+		bits |= 1 << uint(value)
+	}
+	return bits, nil
+}
+
+func (f cronField) value(text string) (int, error) {
+	if value, ok := f.names[strings.ToLower(text)]; ok {
+		return value, nil
+	}
+	return strconv.Atoi(text)
+}
+
+func (f cronField) invalid(part string) error {
+	return fmt.Errorf("%w: %s field %q", ErrInvalidSchedule, f.name, part)
+}
+
+// Next returns the first time strictly after after that matches the
+// expression, evaluated in after's location. It returns the zero time when no
+// match exists within five years, such as for "0 0 30 2 *".
+//
+// Matching uses the wall clock of each real minute, so on daylight saving
+// transitions times skipped by a spring-forward jump never fire and times
+// repeated by a fall-back jump fire once for each occurrence.
+func (e CronExpression) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	added := false
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+	for e.month&(1<<uint(t.Month())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+	for !e.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		// Midnight may not exist or may shift on daylight saving transitions.
+		if t.Hour() != 0 {
+			if t.Hour() > 12 {
+				t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
+			} else {
+				t = t.Add(time.Duration(-t.Hour()) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+	for e.hour&(1<<uint(t.Hour())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+	for e.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+	return t
+}
+
+func (e CronExpression) dayMatches(t time.Time) bool {
+	domMatch := e.dom&(1<<uint(t.Day())) != 0
+	dowMatch := e.dow&(1<<uint(t.Weekday())) != 0
+	if e.domStar || e.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,221 @@
+package taskqueue
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// Intent: Cron expressions should support the portable field syntax so a
+// schedule means the same thing to every scheduler built on this package.
+func TestCronExpressionNextSupportsFieldSyntax(t *testing.T) {
+	start := time.Date(2026, 5, 29, 10, 7, 30, 0, time.UTC) // Friday
+	tests := []struct {
+		name string
+		spec string
+		want []time.Time
+	}{
+		{
+			name: "step",
+			spec: "*/20 * * * *",
+			want: []time.Time{
+				time.Date(2026, 5, 29, 10, 20, 0, 0, time.UTC),
+				time.Date(2026, 5, 29, 10, 40, 0, 0, time.UTC),
+				time.Date(2026, 5, 29, 11, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "list and range",
+			spec: "0 9-10,17 * * *",
+			want: []time.Time{
+				time.Date(2026, 5, 29, 17, 0, 0, 0, time.UTC),
+				time.Date(2026, 5, 30, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 5, 30, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "named weekdays",
+			spec: "15 8 * * mon-wed",
+			want: []time.Time{
+				time.Date(2026, 6, 1, 8, 15, 0, 0, time.UTC),
+				time.Date(2026, 6, 2, 8, 15, 0, 0, time.UTC),
+				time.Date(2026, 6, 3, 8, 15, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "named month with range step",
+			spec: "0 0 1 JAN-DEC/6 *",
+			want: []time.Time{
+				time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "sunday as seven",
+			spec: "0 12 * * 7",
+			want: []time.Time{
+				time.Date(2026, 5, 31, 12, 0, 0, 0, time.UTC),
+				time.Date(2026, 6, 7, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "day of month or day of week",
+			spec: "0 0 1 * fri",
+			want: []time.Time{
+				time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2026, 6, 5, 0, 0, 0, 0, time.UTC),
+				time.Date(2026, 6, 12, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "leap day",
+			spec: "0 0 29 2 *",
+			want: []time.Time{
+				time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseCron(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseCron: %v", err)
+			}
+			current := start
+			for _, want := range tt.want {
+				current = expr.Next(current)
+				if !current.Equal(want) {
+					t.Fatalf("next = %v, want %v", current, want)
+				}
+			}
+		})
+	}
+}
+
+// Intent: Expressions that can never fire should report the zero time instead
+// of searching forever.
+func TestCronExpressionNextReturnsZeroWhenUnsatisfiable(t *testing.T) {
+	expr, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("ParseCron: %v", err)
+	}
+
+	if next := expr.Next(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)); !next.IsZero() {
+		t.Fatalf("next = %v, want zero", next)
+	}
+}
+
+// Intent: Out-of-range values and malformed fields should fail at parse time
+// with the stable schedule error.
+func TestParseCronRejectsInvalidFields(t *testing.T) {
+	for _, spec := range []string{
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"1,,2 * * * *",
+	} {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := ParseCron(spec); !errors.Is(err, ErrInvalidSchedule) {
+				t.Fatalf("error = %v, want ErrInvalidSchedule", err)
+			}
+		})
+	}
+	if _, err := CronSchedule("61 * * * *"); !errors.Is(err, ErrInvalidSchedule) {
+		t.Fatalf("CronSchedule error = %v, want ErrInvalidSchedule", err)
+	}
+}
+
+// Intent: Wall-clock times skipped by a spring-forward transition should not
+// fire, and the schedule should resume on the next day.
+func TestScheduleNextSkipsSpringForwardGap(t *testing.T) {
+	schedule, err := CronSchedule("30 2 * * *", WithLocation("America/New_York"))
+	if err != nil {
+		t.Fatalf("CronSchedule: %v", err)
+	}
+	loc := mustLoadLocation(t, "America/New_York")
+
+	next, err := schedule.Next(time.Date(2026, 3, 7, 3, 0, 0, 0, loc))
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+
+	if want := time.Date(2026, 3, 9, 2, 30, 0, 0, loc); !next.Equal(want) {
+		t.Fatalf("next = %v, want %v", next, want)
+	}
+}
+
+// Intent: Wall-clock times repeated by a fall-back transition should fire for
+// each real occurrence.
+func TestScheduleNextFiresRepeatedFallBackHourTwice(t *testing.T) {
+	schedule, err := CronSchedule("30 1 * * *", WithLocation("America/New_York"))
+	if err != nil {
+		t.Fatalf("CronSchedule: %v", err)
+	}
+	loc := mustLoadLocation(t, "America/New_York")
+	current := time.Date(2026, 11, 1, 0, 0, 0, 0, loc)
+	firstOccurrence := time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC)
+
+	want := []time.Time{
+		firstOccurrence,
+		firstOccurrence.Add(time.Hour),
+		time.Date(2026, 11, 2, 1, 30, 0, 0, loc),
+	}
+	for _, expected := range want {
+		current, err = schedule.Next(current)
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if !current.Equal(expected) {
+			t.Fatalf("next = %v, want %v", current, expected.In(loc))
+		}
+	}
+}
+
+// Intent: Schedule.Next should evaluate cron specs in the configured location
+// and advance interval schedules by their duration.
+func TestScheduleNextUsesLocationAndInterval(t *testing.T) {
+	cron, err := CronSchedule("0 2 * * *", WithLocation("Asia/Shanghai"))
+	if err != nil {
+		t.Fatalf("CronSchedule: %v", err)
+	}
+	after := time.Date(2026, 5, 29, 0, 0, 0, 0, time.UTC)
+
+	next, err := cron.Next(after)
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if want := time.Date(2026, 5, 29, 18, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Fatalf("cron next = %v, want %v", next, want)
+	}
+
+	interval, err := IntervalSchedule(90 * time.Second)
+	if err != nil {
+		t.Fatalf("IntervalSchedule: %v", err)
+	}
+	next, err = interval.Next(after)
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if want := after.Add(90 * time.Second); !next.Equal(want) {
+		t.Fatalf("interval next = %v, want %v", next, want)
+	}
+
+	if _, err := (Schedule{}).Next(after); !errors.Is(err, ErrEmptySchedule) {
+		t.Fatalf("empty schedule error = %v, want ErrEmptySchedule", err)
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone database unavailable: %v", err)
+	}
+	return loc
+}
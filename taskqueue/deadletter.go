@@ -0,0 +1,92 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Attempt records the outcome of one failed processing attempt.
+type Attempt struct {
+	RetryCount int
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Error      string
+}
+
+// DeadLetter is a task that a provider stopped retrying, either because it
+// exhausted its max retry or because it failed with ErrSkipRetry.
+type DeadLetter struct {
+	Task Task
+	// Info is the execution metadata of the final attempt.
+	Info ExecutionInfo
+	// Err is the error returned by the final attempt.
+	Err error
+	// Attempts lists the failed attempts known to the reporter, oldest first.
+	Attempts []Attempt
+}
+
+// DeadLetterSink receives tasks that will not be retried.
+type DeadLetterSink interface {
+	Put(ctx context.Context, letter DeadLetter) error
+}
+
+// DeadLetterSinkFunc adapts a function to DeadLetterSink.
+type DeadLetterSinkFunc func(ctx context.Context, letter DeadLetter) error
+
+// Put calls f.
+func (f DeadLetterSinkFunc) Put(ctx context.Context, letter DeadLetter) error {
+	return f(ctx, letter)
+}
+
+// CaptureDeadLetters sends the final failed attempt of a task to sink, for
+// providers without a native dead-letter hook. An attempt is final when its
+// error wraps ErrSkipRetry or its ExecutionInfo retry count has reached the
+// max retry. Place it before Retry so policy refusals are captured too.
+//
+// Only the final attempt is recorded in DeadLetter.Attempts. A sink failure is
+// joined to the processor error.
+func CaptureDeadLetters(sink DeadLetterSink) Middleware {
+	return func(next ProcessorFunc) ProcessorFunc {
+		return func(ctx context.Context, task Task) error {
+			if next == nil {
+				return ErrNilProcessor
+			}
+			startedAt := time.Now()
+			err := next(ctx, task)
+			if err == nil || sink == nil {
+				return err
+			}
+			info, _ := ExecutionInfoFromContext(ctx)
+			if !finalAttempt(info, err) {
+				return err
+			}
+			retryCount, _ := info.RetryCount()
+			letter := DeadLetter{
+				Task: task,
+				Info: info,
+				Err:  err,
+				Attempts: []Attempt{{
+					RetryCount: retryCount,
+					StartedAt:  startedAt,
+					FinishedAt: time.Now(),
+					Error:      err.Error(),
+				}},
+			}
+			if sinkErr := sink.Put(context.WithoutCancel(ctx), letter); sinkErr != nil {
+				return errors.Join(err, fmt.Errorf("put dead letter: %w", sinkErr))
+			}
+			return err
+		}
+	}
+}
+
+func finalAttempt(info ExecutionInfo, err error) bool {
+	if errors.Is(err, ErrSkipRetry) {
+		return true
+	}
+	retryCount, countOK := info.RetryCount()
+	maxRetry, maxOK := info.MaxRetry()
+	return countOK && maxOK && retryCount >= maxRetry
+}
@@ -0,0 +1,11 @@
+// Package deadletter stores taskqueue dead letters for inspection and replay.
+//
+// Providers and the taskqueue.CaptureDeadLetters middleware hand tasks that
+// will not be retried to a taskqueue.DeadLetterSink. A Store is a sink that
+// also lists, fetches, and deletes the captured letters, and Requeue replays a
+// stored letter through any taskqueue.Enqueuer once the underlying fault has
+// been fixed.
+//
+// MemorySink is a bounded, non-durable Store for tests, local development,
+// and single-process services.
+package deadletter
@@ -0,0 +1,9 @@
+package deadletter
+
+import "errors"
+
+var (
+	ErrNotFound    = errors.New("dead letter is not found")
+	ErrNilStore    = errors.New("dead letter store is nil")
+	ErrNilEnqueuer = errors.New("dead letter enqueuer is nil")
+)
@@ -0,0 +1,114 @@
+package deadletter
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+const defaultCapacity = 1000
+
+// MemorySink is an in-process Store. When full it evicts the oldest entry.
+type MemorySink struct {
+	mu       sync.Mutex
+	entries  []Entry
+	nextID   uint64
+	capacity int
+	now      func() time.Time
+}
+
+var _ Store = (*MemorySink)(nil)
+
+// MemoryOption configures a MemorySink during construction.
+type MemoryOption func(*MemorySink)
+
+// WithCapacity sets the maximum number of retained entries. The default is
+// 1000.
+func WithCapacity(capacity int) MemoryOption {
+	return func(s *MemorySink) {
+		if capacity > 0 {
+			s.capacity = capacity
+		}
+	}
+}
+
+// WithClock sets the time source used for Entry.ReceivedAt.
+func WithClock(now func() time.Time) MemoryOption {
+	return func(s *MemorySink) {
+		if now != nil {
+			s.now = now
+		}
+	}
+}
+
+// NewMemorySink creates an empty in-process dead letter store.
+func NewMemorySink(opts ...MemoryOption) *MemorySink {
+	s := &MemorySink{capacity: defaultCapacity, now: time.Now}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+	return s
+}
+
+// Put stores letter under a new sequential ID.
+func (s *MemorySink) Put(ctx context.Context, letter taskqueue.DeadLetter) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	letter.Attempts = append([]taskqueue.Attempt(nil), letter.Attempts...)
+	s.entries = append(s.entries, Entry{
+		ID:         strconv.FormatUint(s.nextID, 10),
+		Letter:     letter,
+		ReceivedAt: s.now(),
+	})
+	if overflow := len(s.entries) - s.capacity; overflow > 0 {
+		s.entries = append(s.entries[:0], s.entries[overflow:]...)
+	}
+	return nil
+}
+
+// List returns stored entries, oldest first.
+func (s *MemorySink) List(context.Context) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Entry(nil), s.entries...), nil
+}
+
+// Get returns the entry with id or ErrNotFound.
+func (s *MemorySink) Get(_ context.Context, id string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.indexLocked(id); i >= 0 {
+		return s.entries[i], nil
+	}
+	return Entry{}, ErrNotFound
+}
+
+// Delete removes the entry with id or returns ErrNotFound.
+func (s *MemorySink) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.indexLocked(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.entries = append(s.entries[:i], s.entries[i+1:]...)
+	return nil
+}
+
+func (s *MemorySink) indexLocked(id string) int {
+	for i, entry := range s.entries {
+		if entry.ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,152 @@
+package deadletter_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+	"github.com/go-jimu/components/taskqueue/deadletter"
+	"github.com/go-jimu/components/taskqueue/memory"
+)
+
+const waitTimeout = 2 * time.Second
+
+// A task that exhausts its retries should be captured with its attempt
+// history and replayed successfully once the processor is fixed.
+func TestMemorySink_CapturesAndRequeuesPoisonedTask(t *testing.T) {
+	sink := deadletter.NewMemorySink()
+	router := taskqueue.NewRouter()
+	var fixed atomic.Bool
+	processed := make(chan struct{}, 1)
+	if err := router.Register(taskqueue.NewProcessor("email.welcome", func(context.Context, taskqueue.Task) error {
+		if !fixed.Load() {
+			return errors.New("template missing")
+		}
+		processed <- struct{}{}
+		return nil
+	})); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	queue, err := memory.New(router, memory.WithRetryBackoff(0), memory.WithDeadLetterSink(sink))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := queue.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { _ = queue.Shutdown(context.Background()) })
+
+	task, err := taskqueue.NewJSONTask(taskqueue.Definition{Type: "email.welcome"}, struct{}{}, taskqueue.WithKey("user-1"))
+	if err != nil {
+		t.Fatalf("NewJSONTask: %v", err)
+	}
+	if err := queue.Enqueue(context.Background(), task, taskqueue.WithMaxRetry(2)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	entries := waitForEntries(t, sink, 1)
+	letter := entries[0].Letter
+	if letter.Task.Key() != "user-1" || letter.Err == nil || letter.Err.Error() != "template missing" {
+		t.Fatalf("letter = %#v", letter)
+	}
+	if len(letter.Attempts) != 3 {
+		t.Fatalf("attempts = %d, want 3", len(letter.Attempts))
+	}
+	for i, attempt := range letter.Attempts {
+		if attempt.RetryCount != i || attempt.Error != "template missing" || attempt.StartedAt.IsZero() {
+			t.Fatalf("attempt %d = %#v", i, attempt)
+		}
+	}
+	if retryCount, _ := letter.Info.RetryCount(); retryCount != 2 {
+		t.Fatalf("final retry count = %d, want 2", retryCount)
+	}
+
+	fixed.Store(true)
+	if err := deadletter.Requeue(context.Background(), sink, entries[0].ID, queue); err != nil {
+		t.Fatalf("Requeue: %v", err)
+	}
+	select {
+	case <-processed:
+	case <-time.After(waitTimeout):
+		t.Fatal("requeued task was not processed")
+	}
+	if _, err := sink.Get(context.Background(), entries[0].ID); !errors.Is(err, deadletter.ErrNotFound) {
+		t.Fatalf("Get after requeue error = %v, want ErrNotFound", err)
+	}
+}
+
+// A failed requeue should keep the entry so it can be retried later.
+func TestRequeue_KeepsEntryWhenEnqueueFails(t *testing.T) {
+	sink := deadletter.NewMemorySink()
+	if err := sink.Put(context.Background(), taskqueue.DeadLetter{}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	entries, _ := sink.List(context.Background())
+	failing := enqueuerFunc(func(context.Context, taskqueue.Task, ...taskqueue.EnqueueOption) error {
+		return errors.New("broker down")
+	})
+
+	if err := deadletter.Requeue(context.Background(), sink, entries[0].ID, failing); err == nil {
+		t.Fatal("Requeue succeeded, want enqueue error")
+	}
+	if _, err := sink.Get(context.Background(), entries[0].ID); err != nil {
+		t.Fatalf("Get after failed requeue: %v", err)
+	}
+	if err := deadletter.Requeue(context.Background(), sink, "missing", failing); !errors.Is(err, deadletter.ErrNotFound) {
+		t.Fatalf("missing entry error = %v, want ErrNotFound", err)
+	}
+}
+
+// The sink should be bounded and evict the oldest entries first.
+func TestMemorySink_EvictsOldestBeyondCapacity(t *testing.T) {
+	sink := deadletter.NewMemorySink(deadletter.WithCapacity(2))
+	for _, key := range []string{"a", "b", "c"} {
+		task, err := taskqueue.New(taskqueue.Definition{Type: "email.welcome"}, nil, taskqueue.WithKey(key))
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		if err := sink.Put(context.Background(), taskqueue.DeadLetter{Task: task}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	entries, err := sink.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Letter.Task.Key() != "b" || entries[1].Letter.Task.Key() != "c" {
+		t.Fatalf("entries = %#v, want b and c", entries)
+	}
+	if err := sink.Delete(context.Background(), entries[0].ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := sink.Delete(context.Background(), entries[0].ID); !errors.Is(err, deadletter.ErrNotFound) {
+		t.Fatalf("second Delete error = %v, want ErrNotFound", err)
+	}
+}
+
+type enqueuerFunc func(context.Context, taskqueue.Task, ...taskqueue.EnqueueOption) error
+
+func (f enqueuerFunc) Enqueue(ctx context.Context, task taskqueue.Task, opts ...taskqueue.EnqueueOption) error {
+	return f(ctx, task, opts...)
+}
+
+func waitForEntries(t *testing.T, sink *deadletter.MemorySink, n int) []deadletter.Entry {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for time.Now().Before(deadline) {
+		entries, err := sink.List(context.Background())
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(entries) >= n {
+			return entries
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d dead letters", n)
+	return nil
+}
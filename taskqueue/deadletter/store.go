@@ -0,0 +1,47 @@
+package deadletter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// Entry is a stored dead letter.
+type Entry struct {
+	ID         string
+	Letter     taskqueue.DeadLetter
+	ReceivedAt time.Time
+}
+
+// Store is a dead letter sink that supports inspection and removal.
+type Store interface {
+	taskqueue.DeadLetterSink
+	// List returns stored entries, oldest first.
+	List(ctx context.Context) ([]Entry, error)
+	// Get returns the entry with id or ErrNotFound.
+	Get(ctx context.Context, id string) (Entry, error)
+	// Delete removes the entry with id or returns ErrNotFound.
+	Delete(ctx context.Context, id string) error
+}
+
+// Requeue enqueues the task of the stored entry id again and removes the
+// entry once the enqueue succeeds. The replayed task starts with a fresh retry
+// count; opts supply its enqueue policy.
+func Requeue(ctx context.Context, store Store, id string, enqueuer taskqueue.Enqueuer, opts ...taskqueue.EnqueueOption) error {
+	if store == nil {
+		return ErrNilStore
+	}
+	if enqueuer == nil {
+		return ErrNilEnqueuer
+	}
+	entry, err := store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := enqueuer.Enqueue(ctx, entry.Letter.Task, opts...); err != nil {
+		return fmt.Errorf("requeue dead letter %s: %w", id, err)
+	}
+	return store.Delete(ctx, id)
+}
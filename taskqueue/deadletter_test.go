@@ -0,0 +1,53 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// Intent: CaptureDeadLetters should report only final attempts: ErrSkipRetry
+// failures and failures at the execution max retry.
+func TestCaptureDeadLettersReportsFinalAttempts(t *testing.T) {
+	var letters []DeadLetter
+	sink := DeadLetterSinkFunc(func(_ context.Context, letter DeadLetter) error {
+		letters = append(letters, letter)
+		return nil
+	})
+	failure := errors.New("smtp unavailable")
+	processor := Chain(func(_ context.Context, _ Task) error { return failure }, CaptureDeadLetters(sink))
+	attempt := func(retryCount int) context.Context {
+		return ContextWithExecutionInfo(context.Background(), NewExecutionInfo(
+			WithExecutionTaskID("task-1"), WithExecutionRetryCount(retryCount), WithExecutionMaxRetry(2)))
+	}
+
+	_ = processor(attempt(1), Task{})
+	if len(letters) != 0 {
+		t.Fatalf("letters after retryable attempt = %d, want 0", len(letters))
+	}
+	if err := processor(attempt(2), Task{}); !errors.Is(err, failure) {
+		t.Fatalf("error = %v, want processor failure", err)
+	}
+	if len(letters) != 1 || letters[0].Info.TaskID() != "task-1" || len(letters[0].Attempts) != 1 {
+		t.Fatalf("letters = %#v, want one final attempt", letters)
+	}
+
+	skipping := Chain(func(context.Context, Task) error { return fmt.Errorf("%w: bad payload", ErrSkipRetry) }, CaptureDeadLetters(sink))
+	_ = skipping(attempt(0), Task{})
+	if len(letters) != 2 {
+		t.Fatalf("letters after skip retry = %d, want 2", len(letters))
+	}
+}
+
+// Intent: A sink failure must not hide the processor error from the provider.
+func TestCaptureDeadLettersJoinsSinkFailure(t *testing.T) {
+	sinkErr := errors.New("sink unavailable")
+	sink := DeadLetterSinkFunc(func(context.Context, DeadLetter) error { return sinkErr })
+	processor := Chain(func(context.Context, Task) error { return ErrSkipRetry }, CaptureDeadLetters(sink))
+
+	err := processor(context.Background(), Task{})
+	if !errors.Is(err, ErrSkipRetry) || !errors.Is(err, sinkErr) {
+		t.Fatalf("error = %v, want ErrSkipRetry joined with sink failure", err)
+	}
+}
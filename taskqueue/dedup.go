@@ -0,0 +1,140 @@
+package taskqueue
+
+import (
+	"context"
+	"time"
+)
+
+// DedupKeyHeader carries an explicit deduplication key chosen by the
+// producer, such as an idempotency key that survives re-enqueueing.
+const DedupKeyHeader = "x-dedup-key"
+
+const (
+	defaultDedupTTL        = 24 * time.Hour
+	defaultDedupLease      = 5 * time.Minute
+	defaultDedupRetryDelay = time.Second
+)
+
+// DedupState is the state of a deduplication key.
+type DedupState int
+
+const (
+	// DedupAcquired means the caller reserved the key and must process the
+	// task.
+	DedupAcquired DedupState = iota
+	// DedupInProgress means another attempt holds an unexpired reservation.
+	DedupInProgress
+	// DedupCompleted means a task with the key was processed successfully.
+	DedupCompleted
+)
+
+// DedupStore records the keys of processed tasks.
+type DedupStore interface {
+	// Acquire reserves key for lease unless it is already reserved or
+	// completed, and returns the state found. Expired keys are acquired
+	// again.
+	Acquire(ctx context.Context, key string, lease time.Duration) (DedupState, error)
+	// Complete marks key as processed for ttl.
+	Complete(ctx context.Context, key string, ttl time.Duration) error
+	// Release drops the reservation of key so the task can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// DedupOption configures Idempotent.
+type DedupOption func(*dedupConfig)
+
+type dedupConfig struct {
+	ttl   time.Duration
+	lease time.Duration
+	key   func(context.Context, Task) string
+}
+
+// WithDedupTTL sets how long processed keys are remembered. The default is 24
+// hours.
+func WithDedupTTL(ttl time.Duration) DedupOption {
+	return func(cfg *dedupConfig) {
+		if ttl > 0 {
+			cfg.ttl = ttl
+		}
+	}
+}
+
+// WithDedupLease sets how long an attempt holds its reservation. It must
+// outlive the attempt: a reservation left by a crashed worker delays
+// duplicates until it expires. The default is five minutes.
+func WithDedupLease(lease time.Duration) DedupOption {
+	return func(cfg *dedupConfig) {
+		if lease > 0 {
+			cfg.lease = lease
+		}
+	}
+}
+
+// WithDedupKey sets the function that derives the deduplication key of a
+// task. An empty key processes the task without deduplication.
+func WithDedupKey(key func(context.Context, Task) string) DedupOption {
+	return func(cfg *dedupConfig) {
+		if key != nil {
+			cfg.key = key
+		}
+	}
+}
+
+// Idempotent skips tasks that were already processed successfully.
+//
+// Tasks are keyed by TaskType and the DedupKeyHeader, or by the provider task
+// ID from ExecutionInfo when the header is empty. Task.Key is an ordering key
+// shared by every task of an aggregate, so it is not used unless WithDedupKey
+// opts into it. Completed keys return nil without
+// calling the processor. A duplicate that arrives while another attempt holds
+// the key is retried later with a RetryLaterError. A failed attempt releases
+// the key; an error recording completion is not reported because the task
+// was processed, and the reservation then expires after the lease.
+func Idempotent(store DedupStore, opts ...DedupOption) Middleware {
+	cfg := dedupConfig{ttl: defaultDedupTTL, lease: defaultDedupLease, key: defaultDedupKey}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	return func(next ProcessorFunc) ProcessorFunc {
+		return func(ctx context.Context, task Task) error {
+			if next == nil {
+				return ErrNilProcessor
+			}
+			if store == nil {
+				return ErrNilDedupStore
+			}
+			key := cfg.key(ctx, task)
+			if key == "" {
+				return next(ctx, task)
+			}
+			state, err := store.Acquire(ctx, key, cfg.lease)
+			if err != nil {
+				return err
+			}
+			switch state {
+			case DedupCompleted:
+				return nil
+			case DedupInProgress:
+				return RetryLater(defaultDedupRetryDelay, "duplicate task is in progress")
+			}
+			if err := next(ctx, task); err != nil {
+				_ = store.Release(context.WithoutCancel(ctx), key)
+				return err
+			}
+			_ = store.Complete(context.WithoutCancel(ctx), key, cfg.ttl)
+			return nil
+		}
+	}
+}
+
+func defaultDedupKey(ctx context.Context, task Task) string {
+	if key := task.headers[DedupKeyHeader]; key != "" {
+		return string(task.Type()) + ":" + key
+	}
+	if info, ok := ExecutionInfoFromContext(ctx); ok && info.TaskID() != "" {
+		return string(task.Type()) + ":id:" + info.TaskID()
+	}
+	return ""
+}
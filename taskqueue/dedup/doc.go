@@ -0,0 +1,13 @@
+// Package dedup provides taskqueue.DedupStore implementations for the
+// taskqueue.Idempotent middleware.
+//
+// MemoryStore keeps keys in process memory with a least-recently-used
+// capacity bound. It deduplicates only the deliveries seen by one process, so
+// use it with the in-process provider or for tests.
+//
+// SQLStore keeps keys in a database/sql table shared by every worker. Keys
+// are stored as SHA-256 hashes with their expiry in Unix nanoseconds, and
+// reservations rely only on the primary key, so the same schema works on
+// PostgreSQL, MySQL, and SQLite. Expired rows are replaced when a key is
+// acquired again; call Purge periodically to delete the rest.
+package dedup
@@ -0,0 +1,9 @@
+package dedup
+
+import "errors"
+
+var (
+	ErrNilDB          = errors.New("dedup store database is nil")
+	ErrUnknownDialect = errors.New("dedup store dialect is unknown")
+	ErrInvalidTable   = errors.New("dedup store table name is invalid")
+)
@@ -0,0 +1,132 @@
+package dedup
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+const defaultCapacity = 100000
+
+// MemoryOption configures a MemoryStore.
+type MemoryOption func(*MemoryStore)
+
+// WithCapacity bounds how many keys are kept; the least recently used keys
+// are evicted first. The default is 100000.
+func WithCapacity(capacity int) MemoryOption {
+	return func(s *MemoryStore) {
+		if capacity > 0 {
+			s.capacity = capacity
+		}
+	}
+}
+
+// WithMemoryClock sets the time source used to expire keys.
+func WithMemoryClock(now func() time.Time) MemoryOption {
+	return func(s *MemoryStore) {
+		if now != nil {
+			s.now = now
+		}
+	}
+}
+
+type memoryEntry struct {
+	key       string
+	completed bool
+	expiresAt time.Time
+}
+
+// MemoryStore is an in-process taskqueue.DedupStore with LRU eviction.
+type MemoryStore struct {
+	mu       sync.Mutex
+	capacity int
+	now      func() time.Time
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+var _ taskqueue.DedupStore = (*MemoryStore)(nil)
+
+// NewMemoryStore constructs an empty MemoryStore.
+func NewMemoryStore(opts ...MemoryOption) *MemoryStore {
+	s := &MemoryStore{
+		capacity: defaultCapacity,
+		now:      time.Now,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+	return s
+}
+
+// Acquire reserves key for lease unless it holds an unexpired entry.
+func (s *MemoryStore) Acquire(_ context.Context, key string, lease time.Duration) (taskqueue.DedupState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if elem, ok := s.entries[key]; ok {
+		entry := elem.Value.(*memoryEntry)
+		if entry.expiresAt.After(now) {
+			s.order.MoveToFront(elem)
+			if entry.completed {
+				return taskqueue.DedupCompleted, nil
+			}
+			return taskqueue.DedupInProgress, nil
+		}
+		s.removeLocked(elem)
+	}
+	s.entries[key] = s.order.PushFront(&memoryEntry{key: key, expiresAt: now.Add(lease)})
+	for s.order.Len() > s.capacity {
+		s.removeLocked(s.order.Back())
+	}
+	return taskqueue.DedupAcquired, nil
+}
+
+// Complete marks key as processed for ttl.
+func (s *MemoryStore) Complete(_ context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[key]
+	if !ok {
+		elem = s.order.PushFront(&memoryEntry{key: key})
+		s.entries[key] = elem
+		for s.order.Len() > s.capacity {
+			s.removeLocked(s.order.Back())
+		}
+	}
+	entry := elem.Value.(*memoryEntry)
+	entry.completed = true
+	entry.expiresAt = s.now().Add(ttl)
+	s.order.MoveToFront(elem)
+	return nil
+}
+
+// Release drops the reservation of key. Completed keys are kept.
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.entries[key]; ok && !elem.Value.(*memoryEntry).completed {
+		s.removeLocked(elem)
+	}
+	return nil
+}
+
+// Len returns the number of stored keys, including expired ones not yet
+// evicted.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+func (s *MemoryStore) removeLocked(elem *list.Element) {
+	s.order.Remove(elem)
+	delete(s.entries, elem.Value.(*memoryEntry).key)
+}
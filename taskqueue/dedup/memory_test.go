@@ -0,0 +1,99 @@
+package dedup_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+	"github.com/go-jimu/components/taskqueue/dedup"
+	"github.com/go-jimu/components/taskqueue/memory"
+)
+
+// A task delivered twice with the same key should be processed once when the
+// provider runs the Idempotent middleware.
+func TestMemoryStore_ProcessesDuplicateDeliveriesOnce(t *testing.T) {
+	var calls atomic.Int32
+	done := make(chan struct{}, 2)
+	router := taskqueue.NewRouter()
+	if err := router.Register(taskqueue.NewProcessor("payment.capture", func(context.Context, taskqueue.Task) error {
+		calls.Add(1)
+		return nil
+	})); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	signal := func(next taskqueue.ProcessorFunc) taskqueue.ProcessorFunc {
+		return func(ctx context.Context, task taskqueue.Task) error {
+			err := next(ctx, task)
+			done <- struct{}{}
+			return err
+		}
+	}
+	queue, err := memory.New(router, memory.WithMiddleware(signal, taskqueue.Idempotent(dedup.NewMemoryStore())))
+	if err != nil {
+		t.Fatalf("memory.New: %v", err)
+	}
+	if err := queue.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { _ = queue.Shutdown(context.Background()) })
+
+	task, err := taskqueue.NewJSONTask(taskqueue.Definition{Type: "payment.capture"}, struct{}{},
+		taskqueue.WithHeaders(map[string]string{taskqueue.DedupKeyHeader: "order-1"}))
+	if err != nil {
+		t.Fatalf("NewJSONTask: %v", err)
+	}
+	for range 2 {
+		if err := queue.Enqueue(context.Background(), task); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for delivery")
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("calls = %d, want 1", calls.Load())
+	}
+}
+
+// Keys should expire after their TTL and the least recently used key should
+// be evicted beyond capacity.
+func TestMemoryStore_ExpiresAndEvictsKeys(t *testing.T) {
+	now := time.Unix(100, 0)
+	store := dedup.NewMemoryStore(dedup.WithCapacity(2), dedup.WithMemoryClock(func() time.Time { return now }))
+	ctx := context.Background()
+
+	acquire := func(key string) taskqueue.DedupState {
+		t.Helper()
+		state, err := store.Acquire(ctx, key, time.Minute)
+		if err != nil {
+			t.Fatalf("Acquire %s: %v", key, err)
+		}
+		return state
+	}
+	if acquire("a") != taskqueue.DedupAcquired || acquire("a") != taskqueue.DedupInProgress {
+		t.Fatal("second acquire of a reserved key is not in progress")
+	}
+	if err := store.Complete(ctx, "a", time.Hour); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if err := store.Release(ctx, "a"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if acquire("a") != taskqueue.DedupCompleted {
+		t.Fatal("completed key was released")
+	}
+
+	acquire("b")
+	now = now.Add(2 * time.Minute)
+	if acquire("b") != taskqueue.DedupAcquired {
+		t.Fatal("expired reservation was not acquired again")
+	}
+	acquire("c")
+	if store.Len() != 2 || acquire("a") != taskqueue.DedupAcquired {
+		t.Fatalf("len = %d; least recently used key a was not evicted", store.Len())
+	}
+}
@@ -0,0 +1,193 @@
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jimu/components/internal/sqlutil"
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// DefaultTable is the dedup table name used when WithTable is not supplied.
+const DefaultTable = "taskqueue_dedup"
+
+// Dialect selects placeholder syntax and column types for a database. Its
+// values match Dialect, so a dedup table can live next to the task
+// table without importing the provider.
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectMySQL    Dialect = "mysql"
+	DialectSQLite   Dialect = "sqlite"
+)
+
+// SQLOption configures a SQLStore.
+type SQLOption func(*SQLStore)
+
+// WithTable sets the dedup table name.
+func WithTable(table string) SQLOption {
+	return func(s *SQLStore) {
+		if table != "" {
+			s.table = table
+		}
+	}
+}
+
+// WithSQLClock sets the time source used to expire keys.
+func WithSQLClock(now func() time.Time) SQLOption {
+	return func(s *SQLStore) {
+		if now != nil {
+			s.now = now
+		}
+	}
+}
+
+// SQLStore is a taskqueue.DedupStore backed by database/sql.
+type SQLStore struct {
+	db      *sql.DB
+	dialect Dialect
+	table   string
+	now     func() time.Time
+}
+
+var _ taskqueue.DedupStore = (*SQLStore)(nil)
+
+// NewSQLStore constructs a SQLStore that uses the placeholder syntax and
+// column types of dialect.
+func NewSQLStore(db *sql.DB, dialect Dialect, opts ...SQLOption) (*SQLStore, error) {
+	if db == nil {
+		return nil, ErrNilDB
+	}
+	s := &SQLStore{db: db, dialect: dialect, table: DefaultTable, now: time.Now}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+	if _, err := Schema(dialect, s.table); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Schema returns the DDL statements that create the dedup table.
+func Schema(dialect Dialect, table string) ([]string, error) {
+	if !sqlutil.ValidTable(table) {
+		return nil, ErrInvalidTable
+	}
+	switch dialect {
+	case DialectPostgres, DialectMySQL:
+		return []string{fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	dedup_key VARCHAR(64) PRIMARY KEY,
+	completed INTEGER NOT NULL,
+	expires_at BIGINT NOT NULL
+)`, table)}, nil
+	case DialectSQLite:
+		return []string{fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	dedup_key TEXT PRIMARY KEY,
+	completed INTEGER NOT NULL,
+	expires_at INTEGER NOT NULL
+)`, table)}, nil
+	default:
+		return nil, ErrUnknownDialect
+	}
+}
+
+// Migrate creates the dedup table when it does not exist.
+func (s *SQLStore) Migrate(ctx context.Context) error {
+	statements, err := Schema(s.dialect, s.table)
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("migrate dedup table %s: %w", s.table, err)
+		}
+	}
+	return nil
+}
+
+// Acquire replaces an expired row for key and inserts a reservation. When
+// the insert conflicts, the existing row decides the state.
+func (s *SQLStore) Acquire(ctx context.Context, key string, lease time.Duration) (taskqueue.DedupState, error) {
+	hashed := hashKey(key)
+	now := s.now()
+	if _, err := s.exec(ctx, `DELETE FROM %s WHERE dedup_key = ? AND expires_at <= ?`, hashed, now.UnixNano()); err != nil {
+		return 0, fmt.Errorf("expire dedup key: %w", err)
+	}
+	_, insertErr := s.exec(ctx, `INSERT INTO %s (dedup_key, completed, expires_at) VALUES (?, 0, ?)`, hashed, now.Add(lease).UnixNano())
+	if insertErr == nil {
+		return taskqueue.DedupAcquired, nil
+	}
+	var completed int
+	err := s.db.QueryRowContext(ctx, s.query(`SELECT completed FROM %s WHERE dedup_key = ?`), hashed).Scan(&completed)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, fmt.Errorf("reserve dedup key: %w", insertErr)
+	case err != nil:
+		return 0, fmt.Errorf("read dedup key: %w", err)
+	case completed != 0:
+		return taskqueue.DedupCompleted, nil
+	default:
+		return taskqueue.DedupInProgress, nil
+	}
+}
+
+// Complete marks key as processed for ttl, inserting it when its reservation
+// has already been removed.
+func (s *SQLStore) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	hashed := hashKey(key)
+	expiresAt := s.now().Add(ttl).UnixNano()
+	result, err := s.exec(ctx, `UPDATE %s SET completed = 1, expires_at = ? WHERE dedup_key = ?`, expiresAt, hashed)
+	if err != nil {
+		return fmt.Errorf("complete dedup key: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+	if _, err := s.exec(ctx, `INSERT INTO %s (dedup_key, completed, expires_at) VALUES (?, 1, ?)`, hashed, expiresAt); err != nil {
+		return fmt.Errorf("complete dedup key: %w", err)
+	}
+	return nil
+}
+
+// Release deletes the reservation of key. Completed keys are kept.
+func (s *SQLStore) Release(ctx context.Context, key string) error {
+	if _, err := s.exec(ctx, `DELETE FROM %s WHERE dedup_key = ? AND completed = 0`, hashKey(key)); err != nil {
+		return fmt.Errorf("release dedup key: %w", err)
+	}
+	return nil
+}
+
+// Purge deletes expired keys and returns how many were deleted.
+func (s *SQLStore) Purge(ctx context.Context) (int64, error) {
+	result, err := s.exec(ctx, `DELETE FROM %s WHERE expires_at <= ?`, s.now().UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("purge dedup keys: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (s *SQLStore) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.db.ExecContext(ctx, s.query(query), args...)
+}
+
+// query fills in the table name and rewrites ? placeholders for PostgreSQL.
+func (s *SQLStore) query(query string) string {
+	query = fmt.Sprintf(query, s.table)
+	if s.dialect != DialectPostgres {
+		return query
+	}
+	return sqlutil.Rebind(query)
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
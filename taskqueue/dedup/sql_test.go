@@ -0,0 +1,84 @@
+package dedup_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+	"github.com/go-jimu/components/taskqueue/dedup"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// The SQL store should reserve, complete, release, and expire keys through
+// the shared table.
+func TestSQLStore_TracksKeyStates(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "dedup.db")+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	now := time.Unix(100, 0)
+	store, err := dedup.NewSQLStore(db, dedup.DialectSQLite, dedup.WithSQLClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("NewSQLStore: %v", err)
+	}
+	ctx := context.Background()
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	acquire := func(key string) taskqueue.DedupState {
+		t.Helper()
+		state, err := store.Acquire(ctx, key, time.Minute)
+		if err != nil {
+			t.Fatalf("Acquire %s: %v", key, err)
+		}
+		return state
+	}
+	if acquire("order-1") != taskqueue.DedupAcquired || acquire("order-1") != taskqueue.DedupInProgress {
+		t.Fatal("second acquire of a reserved key is not in progress")
+	}
+	if err := store.Release(ctx, "order-1"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if acquire("order-1") != taskqueue.DedupAcquired {
+		t.Fatal("released key was not acquired again")
+	}
+	if err := store.Complete(ctx, "order-1", time.Hour); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if acquire("order-1") != taskqueue.DedupCompleted {
+		t.Fatal("completed key is not reported as completed")
+	}
+
+	acquire("order-2")
+	now = now.Add(2 * time.Hour)
+	if acquire("order-1") != taskqueue.DedupAcquired {
+		t.Fatal("expired completed key was not acquired again")
+	}
+	if purged, err := store.Purge(ctx); err != nil || purged != 1 {
+		t.Fatalf("Purge = %d, %v; want 1 expired reservation", purged, err)
+	}
+}
+
+// Invalid construction should fail before any query runs.
+func TestSQLStore_ValidationErrors(t *testing.T) {
+	if _, err := dedup.NewSQLStore(nil, dedup.DialectSQLite); !errors.Is(err, dedup.ErrNilDB) {
+		t.Fatalf("nil db error = %v", err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if _, err := dedup.NewSQLStore(db, "oracle"); !errors.Is(err, dedup.ErrUnknownDialect) {
+		t.Fatalf("dialect error = %v", err)
+	}
+	if _, err := dedup.NewSQLStore(db, dedup.DialectSQLite, dedup.WithTable("dedup; DROP TABLE x")); !errors.Is(err, dedup.ErrInvalidTable) {
+		t.Fatalf("table error = %v", err)
+	}
+}
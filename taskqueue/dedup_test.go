@@ -0,0 +1,114 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type mapDedupStore struct {
+	states   map[string]DedupState
+	ttls     map[string]time.Duration
+	released []string
+}
+
+func newMapDedupStore() *mapDedupStore {
+	return &mapDedupStore{states: make(map[string]DedupState), ttls: make(map[string]time.Duration)}
+}
+
+func (s *mapDedupStore) Acquire(_ context.Context, key string, _ time.Duration) (DedupState, error) {
+	if state, ok := s.states[key]; ok {
+		return state, nil
+	}
+	s.states[key] = DedupInProgress
+	return DedupAcquired, nil
+}
+
+func (s *mapDedupStore) Complete(_ context.Context, key string, ttl time.Duration) error {
+	s.states[key] = DedupCompleted
+	s.ttls[key] = ttl
+	return nil
+}
+
+func (s *mapDedupStore) Release(_ context.Context, key string) error {
+	delete(s.states, key)
+	s.released = append(s.released, key)
+	return nil
+}
+
+// Intent: Idempotent should process a key once, skip completed duplicates as
+// success, and release the key when processing fails.
+func TestIdempotentSkipsCompletedDuplicates(t *testing.T) {
+	store := newMapDedupStore()
+	calls := 0
+	fail := true
+	process := Chain(func(context.Context, Task) error {
+		calls++
+		if fail {
+			return errors.New("smtp unavailable")
+		}
+		return nil
+	}, Idempotent(store, WithDedupTTL(time.Hour)))
+	task, err := NewJSONTask(Definition{Type: "email.send"}, struct{}{}, WithHeaders(map[string]string{DedupKeyHeader: "order-1"}))
+	if err != nil {
+		t.Fatalf("NewJSONTask: %v", err)
+	}
+
+	if err := process(context.Background(), task); err == nil {
+		t.Fatal("first attempt error = nil")
+	}
+	if len(store.released) != 1 || store.released[0] != "email.send:order-1" {
+		t.Fatalf("released = %v, want email.send:order-1", store.released)
+	}
+	fail = false
+	for range 2 {
+		if err := process(context.Background(), task); err != nil {
+			t.Fatalf("Process: %v", err)
+		}
+	}
+	if calls != 2 || store.ttls["email.send:order-1"] != time.Hour {
+		t.Fatalf("calls = %d, ttl = %s; want 2 calls and 1h ttl", calls, store.ttls["email.send:order-1"])
+	}
+}
+
+// Intent: Tasks sharing an ordering key are distinct tasks and must not be
+// skipped as duplicates of each other.
+func TestIdempotentIgnoresOrderingKey(t *testing.T) {
+	store := newMapDedupStore()
+	calls := 0
+	process := Chain(func(context.Context, Task) error {
+		calls++
+		return nil
+	}, Idempotent(store))
+	for _, id := range []string{"task-1", "task-2"} {
+		task, err := NewJSONTask(Definition{Type: "order.event"}, struct{}{}, WithKey("order-1"))
+		if err != nil {
+			t.Fatalf("NewJSONTask: %v", err)
+		}
+		ctx := ContextWithExecutionInfo(context.Background(), NewExecutionInfo(WithExecutionTaskID(id)))
+		if err := process(ctx, task); err != nil {
+			t.Fatalf("Process %s: %v", id, err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("calls = %d, want 2", calls)
+	}
+}
+
+// Intent: A duplicate that arrives while the key is held should be retried
+// later, and tasks without a dedup key fall back to the provider task ID.
+func TestIdempotentRetriesInProgressDuplicatesLater(t *testing.T) {
+	store := newMapDedupStore()
+	store.states["email.send:id:task-1"] = DedupInProgress
+	process := Chain(func(context.Context, Task) error { return nil }, Idempotent(store))
+
+	ctx := ContextWithExecutionInfo(context.Background(), NewExecutionInfo(WithExecutionTaskID("task-1")))
+	err := process(ctx, mustJSONTask(t, "email.send"))
+	if _, ok := RetryLaterDelay(err); !ok {
+		t.Fatalf("in-progress duplicate error = %v, want RetryLaterError", err)
+	}
+	if err := Chain(func(context.Context, Task) error { return nil }, Idempotent(nil))(ctx, mustJSONTask(t, "email.send")); !errors.Is(err, ErrNilDedupStore) {
+		t.Fatalf("nil store error = %v, want ErrNilDedupStore", err)
+	}
+}
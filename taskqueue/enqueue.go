@@ -101,6 +101,14 @@ func WithUnique(ttl time.Duration) EnqueueOption {
 	}
 }
 
+// WithEnqueueOptions replaces the enqueue policy with opts so a stored policy,
+// such as PeriodicTask.EnqueuePolicy, can be replayed on a later enqueue.
+func WithEnqueueOptions(opts EnqueueOptions) EnqueueOption {
+	return func(cfg *EnqueueOptions) {
+		*cfg = opts
+	}
+}
+
 // Delay returns the relative processing delay.
 func (o EnqueueOptions) Delay() time.Duration {
 	return o.delay
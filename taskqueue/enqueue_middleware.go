@@ -0,0 +1,191 @@
+package taskqueue
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// RequestIDHeader carries the request ID propagated by PropagateHeaders.
+	RequestIDHeader = "x-request-id"
+	// TenantHeader carries the tenant propagated by PropagateHeaders.
+	TenantHeader = "x-tenant-id"
+)
+
+type (
+	requestIDContextKey struct{}
+	tenantContextKey    struct{}
+)
+
+// EnqueueFunc adapts a function to Enqueuer.
+type EnqueueFunc func(context.Context, Task, ...EnqueueOption) error
+
+// Enqueue calls f.
+func (f EnqueueFunc) Enqueue(ctx context.Context, task Task, opts ...EnqueueOption) error {
+	return f(ctx, task, opts...)
+}
+
+// EnqueueMiddleware wraps producer-side enqueue calls.
+type EnqueueMiddleware func(EnqueueFunc) EnqueueFunc
+
+// ChainEnqueuer wraps enqueuer with middleware in declaration order, so the
+// first middleware sees each call first.
+func ChainEnqueuer(enqueuer Enqueuer, middleware ...EnqueueMiddleware) Enqueuer {
+	var next EnqueueFunc
+	if enqueuer != nil {
+		next = enqueuer.Enqueue
+	} else {
+		next = func(context.Context, Task, ...EnqueueOption) error { return ErrNilEnqueuer }
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		if middleware[i] != nil {
+			next = middleware[i](next)
+		}
+	}
+	return next
+}
+
+// ContextWithRequestID stores a request ID for PropagateHeaders.
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDContextKey{}).(string)
+	return requestID, ok && requestID != ""
+}
+
+// ContextWithTenant stores a tenant for PropagateHeaders.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant stored in ctx.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantContextKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// HeaderSource returns headers derived from an enqueue context.
+type HeaderSource func(context.Context) map[string]string
+
+// RequestIDSource propagates the request ID as RequestIDHeader.
+func RequestIDSource(ctx context.Context) map[string]string {
+	if requestID, ok := RequestIDFromContext(ctx); ok {
+		return map[string]string{RequestIDHeader: requestID}
+	}
+	return nil
+}
+
+// TenantSource propagates the tenant as TenantHeader.
+func TenantSource(ctx context.Context) map[string]string {
+	if tenant, ok := TenantFromContext(ctx); ok {
+		return map[string]string{TenantHeader: tenant}
+	}
+	return nil
+}
+
+// PropagateHeaders adds headers from sources to each enqueued task. Headers
+// already set on the task win. Without sources it propagates the request ID
+// and tenant; trace context is provided by the telemetry package.
+func PropagateHeaders(sources ...HeaderSource) EnqueueMiddleware {
+	if len(sources) == 0 {
+		sources = []HeaderSource{RequestIDSource, TenantSource}
+	}
+	return func(next EnqueueFunc) EnqueueFunc {
+		return func(ctx context.Context, task Task, opts ...EnqueueOption) error {
+			headers := make(map[string]string)
+			for _, source := range sources {
+				if source == nil {
+					continue
+				}
+				for key, value := range source(ctx) {
+					if _, exists := task.headers[key]; !exists {
+						headers[key] = value
+					}
+				}
+			}
+			return next(ctx, task.WithHeaders(headers), opts...)
+		}
+	}
+}
+
+// RestoreHeaders is the processor-side counterpart of PropagateHeaders: it
+// stores the request ID and tenant headers of the task in the processing
+// context.
+func RestoreHeaders() Middleware {
+	return func(next ProcessorFunc) ProcessorFunc {
+		return func(ctx context.Context, task Task) error {
+			if next == nil {
+				return ErrNilProcessor
+			}
+			if requestID := task.headers[RequestIDHeader]; requestID != "" {
+				ctx = ContextWithRequestID(ctx, requestID)
+			}
+			if tenant := task.headers[TenantHeader]; tenant != "" {
+				ctx = ContextWithTenant(ctx, tenant)
+			}
+			return next(ctx, task)
+		}
+	}
+}
+
+// MaxPayloadSize rejects tasks whose payload exceeds limit bytes with
+// ErrPayloadTooLarge.
+func MaxPayloadSize(limit int) EnqueueMiddleware {
+	return func(next EnqueueFunc) EnqueueFunc {
+		return func(ctx context.Context, task Task, opts ...EnqueueOption) error {
+			if size := len(task.payload); limit >= 0 && size > limit {
+				return fmt.Errorf("%w: %s payload is %d bytes, limit %d", ErrPayloadTooLarge, task.Type(), size, limit)
+			}
+			return next(ctx, task, opts...)
+		}
+	}
+}
+
+// DefaultQueues assigns queues[task.Type()] to tasks enqueued without a
+// Definition.Queue.
+func DefaultQueues(queues map[TaskType]string) EnqueueMiddleware {
+	copied := make(map[TaskType]string, len(queues))
+	for taskType, queue := range queues {
+		copied[taskType] = queue
+	}
+	return func(next EnqueueFunc) EnqueueFunc {
+		return func(ctx context.Context, task Task, opts ...EnqueueOption) error {
+			if task.Queue() == "" {
+				if queue, ok := copied[task.Type()]; ok {
+					task = task.WithQueue(queue)
+				}
+			}
+			return next(ctx, task, opts...)
+		}
+	}
+}
+
+// EnqueueLogging records enqueue success and failure events with slog.
+func EnqueueLogging(logger *slog.Logger) EnqueueMiddleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next EnqueueFunc) EnqueueFunc {
+		return func(ctx context.Context, task Task, opts ...EnqueueOption) error {
+			startedAt := time.Now()
+			attrs := []any{
+				"task_type", task.Type(),
+				"queue", task.Queue(),
+				"key", task.Key(),
+			}
+			if err := next(ctx, task, opts...); err != nil {
+				logger.ErrorContext(ctx, "taskqueue enqueue failed",
+					append(attrs, "elapsed", time.Since(startedAt).String(), "error", err)...)
+				return err
+			}
+			logger.InfoContext(ctx, "taskqueue task enqueued",
+				append(attrs, "elapsed", time.Since(startedAt).String())...)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package taskqueue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Intent: ChainEnqueuer should apply enqueue middleware in declaration order,
+// matching Chain for processors.
+func TestChainEnqueuerWrapsInDeclarationOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) EnqueueMiddleware {
+		return func(next EnqueueFunc) EnqueueFunc {
+			return func(ctx context.Context, task Task, opts ...EnqueueOption) error {
+				calls = append(calls, name)
+				return next(ctx, task, opts...)
+			}
+		}
+	}
+	enqueuer := ChainEnqueuer(EnqueueFunc(func(context.Context, Task, ...EnqueueOption) error {
+		calls = append(calls, "enqueuer")
+		return nil
+	}), record("first"), nil, record("second"))
+
+	if err := enqueuer.Enqueue(context.Background(), Task{}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if want := []string{"first", "second", "enqueuer"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	if err := ChainEnqueuer(nil).Enqueue(context.Background(), Task{}); !errors.Is(err, ErrNilEnqueuer) {
+		t.Fatalf("nil enqueuer error = %v, want ErrNilEnqueuer", err)
+	}
+}
+
+// Intent: Request ID and tenant should travel from the producer context to the
+// processor context through task headers without overriding explicit headers.
+func TestPropagateHeadersRoundTripsRequestIDAndTenant(t *testing.T) {
+	var enqueued Task
+	enqueuer := ChainEnqueuer(captureEnqueuer(&enqueued), PropagateHeaders())
+	ctx := ContextWithTenant(ContextWithRequestID(context.Background(), "req-1"), "acme")
+	task, err := New(Definition{Type: "email.welcome"}, nil, WithHeader(TenantHeader, "explicit"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if err := enqueuer.Enqueue(ctx, task); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	want := map[string]string{RequestIDHeader: "req-1", TenantHeader: "explicit"}
+	if got := enqueued.Headers(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("headers = %#v, want %#v", got, want)
+	}
+
+	processor := Chain(func(ctx context.Context, _ Task) error {
+		requestID, _ := RequestIDFromContext(ctx)
+		tenant, _ := TenantFromContext(ctx)
+		if requestID != "req-1" || tenant != "explicit" {
+			t.Fatalf("restored request id = %q, tenant = %q", requestID, tenant)
+		}
+		return nil
+	}, RestoreHeaders())
+	if err := processor(context.Background(), enqueued); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+}
+
+// Intent: Oversized payloads should be rejected before reaching the provider.
+func TestMaxPayloadSizeRejectsOversizedPayload(t *testing.T) {
+	var enqueued Task
+	enqueuer := ChainEnqueuer(captureEnqueuer(&enqueued), MaxPayloadSize(4))
+
+	small, _ := New(Definition{Type: "email.welcome"}, []byte("1234"))
+	if err := enqueuer.Enqueue(context.Background(), small); err != nil {
+		t.Fatalf("Enqueue small: %v", err)
+	}
+	large, _ := New(Definition{Type: "email.welcome"}, []byte("12345"))
+	if err := enqueuer.Enqueue(context.Background(), large); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("Enqueue large error = %v, want ErrPayloadTooLarge", err)
+	}
+}
+
+// Intent: Tasks without a queue should be routed to the configured default
+// for their type, while explicit queues are kept.
+func TestDefaultQueuesAssignsQueueByType(t *testing.T) {
+	var enqueued Task
+	enqueuer := ChainEnqueuer(captureEnqueuer(&enqueued), DefaultQueues(map[TaskType]string{"email.welcome": "mailers"}))
+
+	tests := []struct {
+		def  Definition
+		want string
+	}{
+		{def: Definition{Type: "email.welcome"}, want: "mailers"},
+		{def: Definition{Type: "email.welcome", Queue: "critical"}, want: "critical"},
+		{def: Definition{Type: "report.daily"}, want: ""},
+	}
+	for _, tt := range tests {
+		task, _ := New(tt.def, nil)
+		if err := enqueuer.Enqueue(context.Background(), task); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		if enqueued.Queue() != tt.want {
+			t.Fatalf("%s queue = %q, want %q", tt.def.Type, enqueued.Queue(), tt.want)
+		}
+	}
+}
+
+// Intent: Enqueue logging should record the task identity and failures.
+func TestEnqueueLoggingRecordsFailures(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	failure := errors.New("broker down")
+	enqueuer := ChainEnqueuer(EnqueueFunc(func(context.Context, Task, ...EnqueueOption) error { return failure }), EnqueueLogging(logger))
+	task, _ := New(Definition{Type: "email.welcome", Queue: "mailers"}, nil, WithKey("user-1"))
+
+	if err := enqueuer.Enqueue(context.Background(), task); !errors.Is(err, failure) {
+		t.Fatalf("Enqueue error = %v, want failure", err)
+	}
+	for _, want := range []string{"taskqueue enqueue failed", "task_type=email.welcome", "queue=mailers", "key=user-1", "broker down"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("log %q does not contain %q", buf.String(), want)
+		}
+	}
+}
+
+func captureEnqueuer(dst *Task) Enqueuer {
+	return EnqueueFunc(func(_ context.Context, task Task, _ ...EnqueueOption) error {
+		*dst = task
+		return nil
+	})
+}
@@ -93,3 +93,15 @@ func TestEnqueueOptionsValidateAcceptsSupportedPolicy(t *testing.T) {
 		t.Fatalf("Validate: %v", err)
 	}
 }
+
+// Intent: A stored enqueue policy should round-trip through an EnqueueOption so
+// schedulers can replay PeriodicTask.EnqueuePolicy on each fire.
+func TestWithEnqueueOptionsReplaysStoredPolicy(t *testing.T) {
+	stored := NewEnqueueOptions(WithDelay(time.Second), WithMaxRetry(0), WithUnique(time.Minute))
+
+	replayed := NewEnqueueOptions(WithTimeout(time.Hour), WithEnqueueOptions(stored))
+
+	if replayed != stored {
+		t.Fatalf("replayed = %#v, want %#v", replayed, stored)
+	}
+}
@@ -0,0 +1,326 @@
+package taskqueue
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Headers recording payload transformations applied by WithCompression and
+// WithEncryption.
+const (
+	CompressionHeader = "task.compression"
+	EncryptionHeader  = "task.encryption"
+	KeyIDHeader       = "task.key_id"
+)
+
+// Payload compression names.
+const (
+	GzipCompression = "gzip"
+	ZstdCompression = "zstd"
+)
+
+// MaxDecompressedPayloadSize caps the size of a decompressed task payload, so
+// a small crafted payload cannot exhaust worker memory.
+const MaxDecompressedPayloadSize = 64 << 20
+
+// AESGCMEncryption is the EncryptionHeader value of payloads sealed with
+// AES-GCM. The sealed payload is the random nonce followed by the ciphertext,
+// authenticated with the task type.
+const AESGCMEncryption = "aes-gcm"
+
+// Compressor compresses task payload bytes.
+type Compressor interface {
+	Name() string
+	Compress([]byte) ([]byte, error)
+	Decompress([]byte) ([]byte, error)
+}
+
+var (
+	compressorsMu sync.RWMutex
+	compressors   = map[string]Compressor{
+		GzipCompression: gzipCompressor{},
+		ZstdCompression: zstdCompressor{},
+	}
+)
+
+// RegisterCompressor makes compressor available to WithCompression and
+// payload decoding, replacing any compressor with the same name.
+func RegisterCompressor(compressor Compressor) {
+	if compressor == nil {
+		return
+	}
+	name := normalizePayloadCodec(compressor.Name())
+	if name == "" {
+		return
+	}
+	compressorsMu.Lock()
+	defer compressorsMu.Unlock()
+	compressors[name] = compressor
+}
+
+func lookupCompressor(name string) (Compressor, error) {
+	compressorsMu.RLock()
+	compressor, ok := compressors[normalizePayloadCodec(name)]
+	compressorsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCompression, name)
+	}
+	return compressor, nil
+}
+
+// Keyring holds the keys used to encrypt task payloads. Keys are 16, 24, or
+// 32 bytes and select AES-128, AES-192, or AES-256.
+type Keyring interface {
+	// EncryptionKey returns the ID and bytes of the key for new payloads.
+	EncryptionKey() (id string, key []byte, err error)
+	// DecryptionKey returns the key with id or ErrUnknownKey.
+	DecryptionKey(id string) ([]byte, error)
+}
+
+// StaticKeyring is a fixed Keyring. Rotate keys by constructing a new
+// keyring with a new current key while keeping the old keys for decryption.
+type StaticKeyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+var _ Keyring = (*StaticKeyring)(nil)
+
+// NewStaticKeyring constructs a keyring that encrypts with the key current
+// and decrypts with any of keys.
+func NewStaticKeyring(current string, keys map[string][]byte) (*StaticKeyring, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, current)
+	}
+	copied := make(map[string][]byte, len(keys))
+	for id, key := range keys {
+		if id == "" {
+			return nil, fmt.Errorf("%w: empty key id", ErrInvalidKey)
+		}
+		if _, err := aes.NewCipher(key); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidKey, id, err)
+		}
+		copied[id] = append([]byte(nil), key...)
+	}
+	return &StaticKeyring{current: current, keys: copied}, nil
+}
+
+// EncryptionKey returns the current key.
+func (k *StaticKeyring) EncryptionKey() (string, []byte, error) {
+	return k.current, k.keys[k.current], nil
+}
+
+// DecryptionKey returns the key with id.
+func (k *StaticKeyring) DecryptionKey(id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// DecodeOption configures how task payloads are decoded.
+type DecodeOption func(*decodeConfig)
+
+type decodeConfig struct {
+	keyring Keyring
+}
+
+// WithDecryptionKeyring sets the keyring used to decrypt payloads sealed by
+// WithEncryption. Consumers of encrypted tasks must pass a keyring with every
+// key that may still be in flight, either to DecodePayload or to
+// NewSchemaRegistry.
+func WithDecryptionKeyring(keyring Keyring) DecodeOption {
+	return func(cfg *decodeConfig) {
+		cfg.keyring = keyring
+	}
+}
+
+func newDecodeConfig(opts []DecodeOption) decodeConfig {
+	var cfg decodeConfig
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	return cfg
+}
+
+// WithCompression compresses the task payload with the named compressor and
+// records it in CompressionHeader. Empty payloads are left as is.
+func WithCompression(name string) Option {
+	return func(cfg *taskConfig) {
+		cfg.compression = name
+	}
+}
+
+// WithEncryption encrypts the task payload with the current key of keyring
+// and records the algorithm and key ID in EncryptionHeader and KeyIDHeader.
+// Payloads are compressed before they are encrypted. Empty payloads are left
+// as is.
+//
+// Encrypted payload bytes differ on every call, so unique tasks without a Key
+// are never detected as duplicates.
+func WithEncryption(keyring Keyring) Option {
+	return func(cfg *taskConfig) {
+		cfg.keyring = keyring
+		cfg.encrypt = true
+	}
+}
+
+// sealPayload applies the compression and encryption configured in cfg.
+func sealPayload(taskType TaskType, payload []byte, cfg *taskConfig) ([]byte, error) {
+	if len(payload) == 0 {
+		return payload, nil
+	}
+	if cfg.compression != "" {
+		compressor, err := lookupCompressor(cfg.compression)
+		if err != nil {
+			return nil, err
+		}
+		if payload, err = compressor.Compress(payload); err != nil {
+			return nil, fmt.Errorf("compress task payload with %q: %w", compressor.Name(), err)
+		}
+		cfg.setHeader(CompressionHeader, normalizePayloadCodec(compressor.Name()))
+	}
+	if cfg.encrypt {
+		if cfg.keyring == nil {
+			return nil, ErrNilKeyring
+		}
+		id, key, err := cfg.keyring.EncryptionKey()
+		if err != nil {
+			return nil, fmt.Errorf("task payload encryption key: %w", err)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidKey, id, err)
+		}
+		nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(payload)+aead.Overhead())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, fmt.Errorf("generate task payload nonce: %w", err)
+		}
+		payload = aead.Seal(nonce, nonce, payload, []byte(taskType))
+		cfg.setHeader(EncryptionHeader, AESGCMEncryption)
+		cfg.setHeader(KeyIDHeader, id)
+	}
+	return payload, nil
+}
+
+// openPayload reverses the transformations recorded in the task headers.
+func openPayload(task Task, cfg decodeConfig) ([]byte, error) {
+	payload := task.payload
+	if algorithm, ok := task.headers[EncryptionHeader]; ok {
+		if algorithm != AESGCMEncryption {
+			return nil, fmt.Errorf("%w: %w: encryption %q", ErrSkipRetry, ErrInvalidPayloadEnvelope, algorithm)
+		}
+		if cfg.keyring == nil {
+			return nil, ErrNilKeyring
+		}
+		id := task.headers[KeyIDHeader]
+		key, err := cfg.keyring.DecryptionKey(id)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidKey, id, err)
+		}
+		if len(payload) < aead.NonceSize() {
+			return nil, fmt.Errorf("%w: %w: sealed payload is too short", ErrSkipRetry, ErrInvalidPayloadEnvelope)
+		}
+		nonce, sealed := payload[:aead.NonceSize()], payload[aead.NonceSize():]
+		if payload, err = aead.Open(nil, nonce, sealed, []byte(task.Type())); err != nil {
+			return nil, fmt.Errorf("%w: %w: %w", ErrSkipRetry, ErrInvalidPayloadEnvelope, err)
+		}
+	}
+	if name, ok := task.headers[CompressionHeader]; ok {
+		compressor, err := lookupCompressor(name)
+		if err != nil {
+			return nil, err
+		}
+		if payload, err = compressor.Decompress(payload); err != nil {
+			return nil, fmt.Errorf("%w: %w: decompress with %q: %w", ErrSkipRetry, ErrInvalidPayloadEnvelope, name, err)
+		}
+		if len(payload) > MaxDecompressedPayloadSize {
+			return nil, fmt.Errorf("%w: %w: decompress with %q: %w", ErrSkipRetry, ErrInvalidPayloadEnvelope, name, errDecompressedTooLarge)
+		}
+	}
+	return payload, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string { return GzipCompression }
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err = io.ReadAll(io.LimitReader(r, MaxDecompressedPayloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxDecompressedPayloadSize {
+		return nil, errDecompressedTooLarge
+	}
+	return data, nil
+}
+
+type zstdCompressor struct{}
+
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) { return zstd.NewWriter(nil) })
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxDecompressedPayloadSize))
+	})
+
+	errDecompressedTooLarge = fmt.Errorf("decompressed payload exceeds %d bytes", MaxDecompressedPayloadSize)
+)
+
+func (zstdCompressor) Name() string { return ZstdCompression }
+
+func (zstdCompressor) Compress(data []byte) ([]byte, error) {
+	encoder, err := zstdEncoder()
+	if err != nil {
+		return nil, err
+	}
+	return encoder.EncodeAll(data, nil), nil
+}
+
+func (zstdCompressor) Decompress(data []byte) ([]byte, error) {
+	decoder, err := zstdDecoder()
+	if err != nil {
+		return nil, err
+	}
+	return decoder.DecodeAll(data, nil)
+}
@@ -0,0 +1,147 @@
+package taskqueue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type envelopePayload struct {
+	Email string `json:"email"`
+	Note  string `json:"note"`
+}
+
+func newTestKeyring(t *testing.T, current string) *StaticKeyring {
+	t.Helper()
+	keyring, err := NewStaticKeyring(current, map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	})
+	if err != nil {
+		t.Fatalf("NewStaticKeyring: %v", err)
+	}
+	return keyring
+}
+
+// Intent: Compressed payloads should be smaller on the wire, record the
+// compressor in a header, and decode transparently.
+func TestCompressionRoundTrips(t *testing.T) {
+	payload := envelopePayload{Email: "user@example.com", Note: strings.Repeat("hello ", 200)}
+	for _, name := range []string{GzipCompression, ZstdCompression} {
+		task, err := NewJSONTask(Definition{Type: "email.send"}, payload, WithCompression(name))
+		if err != nil {
+			t.Fatalf("NewJSONTask %s: %v", name, err)
+		}
+		if task.Headers()[CompressionHeader] != name || len(task.Payload()) >= len(payload.Note) {
+			t.Fatalf("%s task headers = %v, payload size = %d", name, task.Headers(), len(task.Payload()))
+		}
+		var decoded envelopePayload
+		if err := DecodePayload(task, &decoded); err != nil {
+			t.Fatalf("DecodePayload %s: %v", name, err)
+		}
+		if decoded != payload {
+			t.Fatalf("%s decoded = %#v", name, decoded)
+		}
+	}
+
+	if _, err := NewJSONTask(Definition{Type: "email.send"}, payload, WithCompression("lz4")); !errors.Is(err, ErrUnknownCompression) {
+		t.Fatalf("unknown compression error = %v, want ErrUnknownCompression", err)
+	}
+}
+
+// Intent: Encrypted payloads should hide plaintext, record the key ID, and
+// stay decodable after the current key rotates.
+func TestEncryptionSupportsKeyRotation(t *testing.T) {
+	def := Definition{Type: "email.send"}
+	newRegistry := func(opts ...DecodeOption) *SchemaRegistry {
+		registry := NewSchemaRegistry(opts...)
+		if err := registry.Register(def, func() any { return &envelopePayload{} }); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+		return registry
+	}
+	registry := newRegistry()
+	payload := &envelopePayload{Email: "user@example.com"}
+
+	task, err := registry.NewJSONTask(payload, WithCompression(GzipCompression), WithEncryption(newTestKeyring(t, "k1")))
+	if err != nil {
+		t.Fatalf("NewJSONTask: %v", err)
+	}
+	headers := task.Headers()
+	if headers[EncryptionHeader] != AESGCMEncryption || headers[KeyIDHeader] != "k1" || headers[CompressionHeader] != GzipCompression {
+		t.Fatalf("headers = %v", headers)
+	}
+	if bytes.Contains(task.Payload(), []byte("user@example.com")) {
+		t.Fatal("encrypted payload contains plaintext")
+	}
+	if _, err := registry.Decode(task); !errors.Is(err, ErrNilKeyring) {
+		t.Fatalf("Decode without keyring error = %v, want ErrNilKeyring", err)
+	}
+
+	decoded, err := newRegistry(WithDecryptionKeyring(newTestKeyring(t, "k2"))).Decode(task)
+	if err != nil {
+		t.Fatalf("Decode after rotation: %v", err)
+	}
+	if *decoded.(*envelopePayload) != *payload {
+		t.Fatalf("decoded = %#v", decoded)
+	}
+
+	sealed := task.Payload()
+	sealed[len(sealed)-1] ^= 0xff
+	tampered, err := New(def, sealed, WithHeaders(headers), WithPayloadCodec(JSONCodec))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	var target envelopePayload
+	if err := DecodePayload(tampered, &target, WithDecryptionKeyring(newTestKeyring(t, "k1"))); !errors.Is(err, ErrInvalidPayloadEnvelope) || !errors.Is(err, ErrSkipRetry) {
+		t.Fatalf("tampered decode error = %v, want ErrInvalidPayloadEnvelope and ErrSkipRetry", err)
+	}
+
+	retired, err := NewStaticKeyring("k3", map[string][]byte{"k3": bytes.Repeat([]byte{3}, 16)})
+	if err != nil {
+		t.Fatalf("NewStaticKeyring: %v", err)
+	}
+	processor, err := Handle(newRegistry(WithDecryptionKeyring(retired)), func(context.Context, Task, *envelopePayload) error { return nil })
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if err := processor.Process(context.Background(), task); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Process with retired key error = %v, want ErrUnknownKey", err)
+	}
+}
+
+// Intent: Decompression should stop at MaxDecompressedPayloadSize, so a small
+// crafted payload cannot exhaust worker memory.
+func TestDecompressionIsCapped(t *testing.T) {
+	bomb := bytes.Repeat([]byte{'a'}, MaxDecompressedPayloadSize+1)
+	for _, compressor := range []Compressor{gzipCompressor{}, zstdCompressor{}} {
+		compressed, err := compressor.Compress(bomb)
+		if err != nil {
+			t.Fatalf("Compress %s: %v", compressor.Name(), err)
+		}
+		task, err := New(Definition{Type: "email.send"}, compressed,
+			WithHeaders(map[string]string{CompressionHeader: compressor.Name()}), WithPayloadCodec(JSONCodec))
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		var target envelopePayload
+		if err := DecodePayload(task, &target); !errors.Is(err, ErrInvalidPayloadEnvelope) || !errors.Is(err, ErrSkipRetry) {
+			t.Fatalf("%s bomb decode error = %v, want ErrInvalidPayloadEnvelope and ErrSkipRetry", compressor.Name(), err)
+		}
+	}
+}
+
+// Intent: Keyrings should reject keys that cannot be used with AES-GCM.
+func TestNewStaticKeyringValidatesKeys(t *testing.T) {
+	if _, err := NewStaticKeyring("missing", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("missing current key error = %v, want ErrUnknownKey", err)
+	}
+	if _, err := NewStaticKeyring("k1", map[string][]byte{"k1": []byte("short")}); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("short key error = %v, want ErrInvalidKey", err)
+	}
+	if _, err := NewJSONTask(Definition{Type: "email.send"}, envelopePayload{}, WithEncryption(nil)); !errors.Is(err, ErrNilKeyring) {
+		t.Fatalf("nil keyring error = %v, want ErrNilKeyring", err)
+	}
+}
@@ -26,6 +26,30 @@ var (
 	ErrEmptyPeriodicTaskName     = errors.New("periodic task name is empty")
 	ErrInvalidPeriodicTaskPolicy = errors.New("periodic task enqueue policy is invalid")
 	ErrDuplicatePeriodicTask     = errors.New("periodic task is already registered")
+	ErrDuplicateTask             = errors.New("task is already enqueued")
+	ErrNilSchemaRegistry         = errors.New("task schema registry is nil")
+	ErrNilEnqueuer               = errors.New("task enqueuer is nil")
+	ErrPayloadTooLarge           = errors.New("task payload is too large")
+	ErrInvalidBatchResult        = errors.New("task batch result is invalid")
+	ErrNilResultStore            = errors.New("task result store is nil")
+	ErrResultNotFound            = errors.New("task result is not found")
+	ErrTaskFailed                = errors.New("task failed")
+	ErrInvalidResultTarget       = errors.New("task result decode target is invalid")
+	ErrTaskNotFound              = errors.New("task is not found")
+	ErrInvalidTaskState          = errors.New("task state does not allow the operation")
+	ErrInvalidSchemaVersion      = errors.New("task payload schema version is invalid")
+	ErrUnsupportedSchemaVersion  = errors.New("task payload schema version is unsupported")
+	ErrUnknownCompression        = errors.New("task payload compression is unknown")
+	ErrNilKeyring                = errors.New("task payload keyring is nil")
+	ErrUnknownKey                = errors.New("task payload key is unknown")
+	ErrInvalidKey                = errors.New("task payload key is invalid")
+	ErrInvalidPayloadEnvelope    = errors.New("task payload envelope is invalid")
+	ErrNilDedupStore             = errors.New("task dedup store is nil")
+	ErrInvalidQueuePriority      = errors.New("task queue priority is invalid")
+
+	// ErrRetryLater matches RetryLaterError: reschedule without counting a
+	// failure.
+	ErrRetryLater = errors.New("retry task later")
 
 	// ErrSkipRetry marks a failure as non-retryable for provider adapters.
 	ErrSkipRetry = errors.New("skip retry for task")
@@ -13,6 +13,8 @@ type ExecutionInfo struct {
 	retryCountSet bool
 	maxRetry      int
 	maxRetrySet   bool
+	priority      int
+	prioritySet   bool
 }
 
 // ExecutionInfoOption configures execution metadata.
@@ -59,6 +61,14 @@ func WithExecutionMaxRetry(maxRetry int) ExecutionInfoOption {
 	}
 }
 
+// WithExecutionPriority records the QueuePriority weight of the queue lane.
+func WithExecutionPriority(priority int) ExecutionInfoOption {
+	return func(info *ExecutionInfo) {
+		info.priority = priority
+		info.prioritySet = true
+	}
+}
+
 // TaskID returns the provider task identifier.
 func (i ExecutionInfo) TaskID() string {
 	return i.taskID
@@ -79,6 +89,12 @@ func (i ExecutionInfo) MaxRetry() (int, bool) {
 	return i.maxRetry, i.maxRetrySet
 }
 
+// Priority returns the QueuePriority weight of the queue lane and whether the
+// provider supplied it.
+func (i ExecutionInfo) Priority() (int, bool) {
+	return i.priority, i.prioritySet
+}
+
 // ContextWithExecutionInfo stores execution metadata in ctx.
 func ContextWithExecutionInfo(ctx context.Context, info ExecutionInfo) context.Context {
 	return context.WithValue(ctx, executionInfoContextKey{}, info)
@@ -0,0 +1,89 @@
+package taskqueue
+
+import (
+	"context"
+	"time"
+)
+
+// TaskState is the provider-neutral lifecycle state of a stored task.
+type TaskState string
+
+const (
+	// TaskStatePending tasks are due and waiting for a worker.
+	TaskStatePending TaskState = "pending"
+	// TaskStateScheduled tasks wait for their first attempt time.
+	TaskStateScheduled TaskState = "scheduled"
+	// TaskStateRetry tasks failed and wait for their next attempt time.
+	TaskStateRetry TaskState = "retry"
+	// TaskStateActive tasks are being processed.
+	TaskStateActive TaskState = "active"
+	// TaskStateArchived tasks failed for good and are kept for inspection.
+	TaskStateArchived TaskState = "archived"
+)
+
+// QueueInfo summarizes one queue.
+type QueueInfo struct {
+	Name      string
+	Paused    bool
+	Pending   int
+	Scheduled int
+	Retry     int
+	Active    int
+	Archived  int
+}
+
+// TaskInfo describes one stored task.
+type TaskInfo struct {
+	ID         string
+	Task       Task
+	Queue      string
+	State      TaskState
+	ProcessAt  time.Time
+	RetryCount int
+	MaxRetry   int
+	LastError  string
+	Attempts   []Attempt
+}
+
+// Pagination selects a page of a listing. A Limit of zero or less returns
+// every remaining item.
+type Pagination struct {
+	Offset int
+	Limit  int
+}
+
+// Inspector is an optional operational capability for providers that can
+// enumerate and manage stored tasks.
+//
+// Queue names are provider lane names, as reported by ExecutionInfo. Task IDs
+// are provider task IDs.
+type Inspector interface {
+	// Queues returns every known queue ordered by name.
+	Queues(ctx context.Context) ([]QueueInfo, error)
+	// ListTasks returns a page of the tasks of queue in state, oldest
+	// process time first.
+	ListTasks(ctx context.Context, queue string, state TaskState, page Pagination) ([]TaskInfo, error)
+	// GetTask returns the task with id or ErrTaskNotFound.
+	GetTask(ctx context.Context, id string) (TaskInfo, error)
+	// CancelTask stops a task from running again. Waiting tasks are removed;
+	// active tasks have their processing context canceled and are neither
+	// retried nor dead-lettered. Archived tasks return ErrInvalidTaskState.
+	CancelTask(ctx context.Context, id string) error
+	// DeleteTask removes a task that is not active. Active tasks return
+	// ErrInvalidTaskState.
+	DeleteTask(ctx context.Context, id string) error
+	// PauseQueue stops dispatching tasks of queue; active tasks finish.
+	PauseQueue(ctx context.Context, queue string) error
+	// ResumeQueue resumes dispatching tasks of queue.
+	ResumeQueue(ctx context.Context, queue string) error
+}
+
+// Paginate returns the page of items selected by page.
+func Paginate[T any](items []T, page Pagination) []T {
+	offset := min(max(page.Offset, 0), len(items))
+	items = items[offset:]
+	if page.Limit > 0 && page.Limit < len(items) {
+		items = items[:page.Limit]
+	}
+	return items
+}
@@ -0,0 +1,39 @@
+// Package memory provides an in-process taskqueue provider.
+//
+// Queue implements taskqueue.Enqueuer, taskqueue.Worker, and taskqueue.Runner
+// on top of a taskqueue.Router. Tasks live only in process memory: they are
+// lost when the process exits and are never shared between Queue instances.
+// The provider is intended for unit tests, local development, and small
+// services that do not need durable background jobs.
+//
+// Enqueue policy is honoured as follows:
+//
+//   - WithDelay and WithProcessAt schedule the first attempt.
+//   - WithMaxRetry overrides the default retry count; failed attempts are
+//     retried after the configured retry backoff.
+//   - WithTimeout bounds each attempt through the processor context.
+//   - WithDeadline bounds every attempt and drops the task once it has passed.
+//   - WithUnique rejects tasks with the same TaskType, Queue, and Key with
+//     taskqueue.ErrDuplicateTask while an earlier task is still queued or
+//     running and its uniqueness window has not expired. Tasks without a Key
+//     use their payload bytes instead.
+//
+// WithWorkers bounds the tasks running at once across queues, and
+// WithQueuePriority decides which queue a free worker serves next; the weight
+// of the queue is reported by taskqueue.ExecutionInfo.Priority.
+//
+// WithKeyOrdering processes tasks sharing a Task.Key one at a time in the
+// order they became due, holding the key across retries.
+//
+// Queue implements taskqueue.Inspector. Tasks that fail for good are archived
+// for inspection, up to WithArchiveCapacity; canceled tasks are dropped
+// without a retry or a dead letter.
+//
+// Processor errors wrapping taskqueue.ErrSkipRetry are never retried; errors
+// wrapping taskqueue.RetryLaterError, such as those from WithLimiter, are
+// rescheduled without counting a retry. Tasks are dispatched through the
+// router with taskqueue.ExecutionInfo carrying the provider task ID, queue,
+// retry count, and max retry. Processor registration is allowed at any time;
+// a task without a registered processor fails with taskqueue.ErrUnhandledType
+// and follows the normal retry rules.
+package memory
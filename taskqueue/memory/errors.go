@@ -0,0 +1,9 @@
+package memory
+
+import "errors"
+
+var (
+	ErrNilRouter      = errors.New("memory queue router is nil")
+	ErrClosed         = errors.New("memory queue is closed")
+	ErrAlreadyStarted = errors.New("memory queue is already started")
+)
@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"container/heap"
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// Queues returns every lane with tasks or explicit pause state, ordered by
+// name.
+func (q *Queue) Queues(context.Context) ([]taskqueue.QueueInfo, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	infos := make(map[string]*taskqueue.QueueInfo)
+	info := func(name string) *taskqueue.QueueInfo {
+		if infos[name] == nil {
+			infos[name] = &taskqueue.QueueInfo{Name: name}
+		}
+		return infos[name]
+	}
+	for name, l := range q.lanes {
+		info(name).Paused = l.paused
+	}
+	for _, e := range q.entries {
+		qi := info(e.queue)
+		switch e.state {
+		case taskqueue.TaskStatePending:
+			qi.Pending++
+		case taskqueue.TaskStateScheduled:
+			qi.Scheduled++
+		case taskqueue.TaskStateRetry:
+			qi.Retry++
+		case taskqueue.TaskStateActive:
+			qi.Active++
+		case taskqueue.TaskStateArchived:
+			qi.Archived++
+		}
+	}
+	out := make([]taskqueue.QueueInfo, 0, len(infos))
+	for _, qi := range infos {
+		out = append(out, *qi)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// ListTasks returns a page of the tasks of queue in state, ordered by process
+// time and then ID. An empty queue means DefaultQueue.
+func (q *Queue) ListTasks(_ context.Context, queue string, state taskqueue.TaskState, page taskqueue.Pagination) ([]taskqueue.TaskInfo, error) {
+	queue = laneName(queue)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var matched []*entry
+	for _, e := range q.entries {
+		if e.queue == queue && e.state == state {
+			matched = append(matched, e)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].processAt.Equal(matched[j].processAt) {
+			return matched[i].processAt.Before(matched[j].processAt)
+		}
+		return matched[i].id < matched[j].id
+	})
+	matched = taskqueue.Paginate(matched, page)
+	out := make([]taskqueue.TaskInfo, len(matched))
+	for i, e := range matched {
+		out[i] = taskInfo(e)
+	}
+	return out, nil
+}
+
+// GetTask returns the task with id.
+func (q *Queue) GetTask(_ context.Context, id string) (taskqueue.TaskInfo, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.entries[id]
+	if !ok {
+		return taskqueue.TaskInfo{}, taskqueue.ErrTaskNotFound
+	}
+	return taskInfo(e), nil
+}
+
+// CancelTask removes a waiting task, or cancels the context of an active task
+// and drops it once its processor returns.
+func (q *Queue) CancelTask(_ context.Context, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.entries[id]
+	if !ok {
+		return taskqueue.ErrTaskNotFound
+	}
+	switch e.state {
+	case taskqueue.TaskStateArchived:
+		return taskqueue.ErrInvalidTaskState
+	case taskqueue.TaskStateActive:
+		e.canceled = true
+		if e.cancel != nil {
+			e.cancel()
+		}
+	default:
+		q.removeLocked(e)
+		q.signal()
+	}
+	return nil
+}
+
+// DeleteTask removes a task that is not active.
+func (q *Queue) DeleteTask(_ context.Context, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.entries[id]
+	if !ok {
+		return taskqueue.ErrTaskNotFound
+	}
+	if e.state == taskqueue.TaskStateActive {
+		return taskqueue.ErrInvalidTaskState
+	}
+	q.removeLocked(e)
+	q.signal()
+	return nil
+}
+
+// PauseQueue stops dispatching tasks of queue. Tasks keep being accepted and
+// active tasks finish.
+func (q *Queue) PauseQueue(_ context.Context, queue string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.laneLocked(laneName(queue)).paused = true
+	return nil
+}
+
+// ResumeQueue resumes dispatching tasks of queue.
+func (q *Queue) ResumeQueue(_ context.Context, queue string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.laneLocked(laneName(queue)).paused = false
+	q.signal()
+	return nil
+}
+
+// removeLocked forgets e and releases its unique lock and ordering key.
+func (q *Queue) removeLocked(e *entry) {
+	switch e.state {
+	case taskqueue.TaskStateScheduled, taskqueue.TaskStateRetry:
+		if e.index >= 0 {
+			heap.Remove(&q.scheduled, e.index)
+		}
+	case taskqueue.TaskStatePending:
+		l := q.laneLocked(e.queue)
+		l.ready = slices.DeleteFunc(l.ready, func(candidate *entry) bool { return candidate == e })
+		if line := q.keys[e.task.Key()]; line != nil {
+			line.waiting = slices.DeleteFunc(line.waiting, func(candidate *entry) bool { return candidate == e })
+		}
+	case taskqueue.TaskStateArchived:
+		q.archived = slices.DeleteFunc(q.archived, func(candidate *entry) bool { return candidate == e })
+	}
+	q.releaseLocked(e)
+	q.releaseKeyLocked(e)
+	delete(q.entries, e.id)
+}
+
+// archiveLocked keeps a task that failed for good, evicting the oldest
+// archived task beyond the archive capacity.
+func (q *Queue) archiveLocked(e *entry) {
+	q.releaseLocked(e)
+	q.releaseKeyLocked(e)
+	if q.archiveCapacity == 0 {
+		delete(q.entries, e.id)
+		return
+	}
+	e.state = taskqueue.TaskStateArchived
+	q.archived = append(q.archived, e)
+	if len(q.archived) > q.archiveCapacity {
+		oldest := q.archived[0]
+		q.archived[0] = nil
+		q.archived = q.archived[1:]
+		delete(q.entries, oldest.id)
+	}
+}
+
+func taskInfo(e *entry) taskqueue.TaskInfo {
+	info := taskqueue.TaskInfo{
+		ID:         e.id,
+		Task:       e.task,
+		Queue:      e.queue,
+		State:      e.state,
+		ProcessAt:  e.processAt,
+		RetryCount: e.retried,
+		MaxRetry:   e.maxRetry,
+		Attempts:   slices.Clone(e.attempts),
+	}
+	if len(e.attempts) > 0 {
+		info.LastError = e.attempts[len(e.attempts)-1].Error
+	}
+	return info
+}
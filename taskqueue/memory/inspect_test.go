@@ -0,0 +1,163 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+	"github.com/go-jimu/components/taskqueue/memory"
+)
+
+// Scheduled tasks should be listed in process order with pagination, and
+// deleting one should remove it from the listing and the queue counts.
+func TestQueue_InspectorListsAndDeletesScheduledTasks(t *testing.T) {
+	router := taskqueue.NewRouter()
+	mustRegister(t, router, "report.build", func(context.Context, taskqueue.Task) error { return nil })
+	queue := startQueue(t, router)
+	ctx := context.Background()
+
+	for _, delay := range []time.Duration{3 * time.Hour, time.Hour, 2 * time.Hour} {
+		if err := queue.Enqueue(ctx, newTask(t, "report.build", "reports", ""), taskqueue.WithDelay(delay)); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	page, err := queue.ListTasks(ctx, "reports", taskqueue.TaskStateScheduled, taskqueue.Pagination{Offset: 1, Limit: 5})
+	if err != nil {
+		t.Fatalf("ListTasks: %v", err)
+	}
+	if len(page) != 2 || !page[0].ProcessAt.Before(page[1].ProcessAt) {
+		t.Fatalf("page = %+v, want the two latest tasks in process order", page)
+	}
+	info, err := queue.GetTask(ctx, page[0].ID)
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if info.Queue != "reports" || info.State != taskqueue.TaskStateScheduled || info.Task.Type() != "report.build" {
+		t.Fatalf("task info = %+v", info)
+	}
+
+	if err := queue.DeleteTask(ctx, info.ID); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if _, err := queue.GetTask(ctx, info.ID); !errors.Is(err, taskqueue.ErrTaskNotFound) {
+		t.Fatalf("GetTask after delete error = %v, want ErrTaskNotFound", err)
+	}
+	queues, err := queue.Queues(ctx)
+	if err != nil {
+		t.Fatalf("Queues: %v", err)
+	}
+	if len(queues) != 1 || queues[0].Name != "reports" || queues[0].Scheduled != 2 {
+		t.Fatalf("queues = %+v, want reports with 2 scheduled", queues)
+	}
+}
+
+// Canceling an active task should cancel its context and drop it without a
+// retry.
+func TestQueue_InspectorCancelsActiveTask(t *testing.T) {
+	router := taskqueue.NewRouter()
+	started := make(chan string, 2)
+	mustRegister(t, router, "report.build", func(ctx context.Context, _ taskqueue.Task) error {
+		info, _ := taskqueue.ExecutionInfoFromContext(ctx)
+		started <- info.TaskID()
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	queue := startQueue(t, router, memory.WithRetryBackoff(0))
+	ctx := context.Background()
+
+	if err := queue.Enqueue(ctx, newTask(t, "report.build", "", ""), taskqueue.WithMaxRetry(3)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	id := receive(t, started)
+	info, err := queue.GetTask(ctx, id)
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if info.State != taskqueue.TaskStateActive {
+		t.Fatalf("state = %q, want active", info.State)
+	}
+	if err := queue.DeleteTask(ctx, id); !errors.Is(err, taskqueue.ErrInvalidTaskState) {
+		t.Fatalf("DeleteTask active error = %v, want ErrInvalidTaskState", err)
+	}
+
+	if err := queue.CancelTask(ctx, id); err != nil {
+		t.Fatalf("CancelTask: %v", err)
+	}
+	expectNone(t, started)
+	if _, err := queue.GetTask(ctx, id); !errors.Is(err, taskqueue.ErrTaskNotFound) {
+		t.Fatalf("GetTask after cancel error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+// Tasks that exhaust their retries should be archived with their last error.
+func TestQueue_InspectorArchivesFinalFailures(t *testing.T) {
+	router := taskqueue.NewRouter()
+	done := make(chan struct{}, 2)
+	mustRegister(t, router, "report.build", func(context.Context, taskqueue.Task) error {
+		done <- struct{}{}
+		return errors.New("disk full")
+	})
+	queue := startQueue(t, router, memory.WithRetryBackoff(0))
+	ctx := context.Background()
+
+	if err := queue.Enqueue(ctx, newTask(t, "report.build", "", ""), taskqueue.WithMaxRetry(1)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	receive(t, done)
+	receive(t, done)
+
+	var archived []taskqueue.TaskInfo
+	deadline := time.Now().Add(waitTimeout)
+	for len(archived) == 0 && time.Now().Before(deadline) {
+		var err error
+		archived, err = queue.ListTasks(ctx, "", taskqueue.TaskStateArchived, taskqueue.Pagination{})
+		if err != nil {
+			t.Fatalf("ListTasks: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(archived) != 1 {
+		t.Fatalf("archived = %+v, want one task", archived)
+	}
+	if archived[0].LastError != "disk full" || len(archived[0].Attempts) != 2 || archived[0].RetryCount != 1 {
+		t.Fatalf("archived task = %+v", archived[0])
+	}
+	if err := queue.CancelTask(ctx, archived[0].ID); !errors.Is(err, taskqueue.ErrInvalidTaskState) {
+		t.Fatalf("CancelTask archived error = %v, want ErrInvalidTaskState", err)
+	}
+}
+
+// A paused queue should accept tasks without dispatching them until resumed.
+func TestQueue_InspectorPausesAndResumesQueue(t *testing.T) {
+	router := taskqueue.NewRouter()
+	done := make(chan struct{}, 1)
+	mustRegister(t, router, "report.build", func(context.Context, taskqueue.Task) error {
+		done <- struct{}{}
+		return nil
+	})
+	queue := startQueue(t, router)
+	ctx := context.Background()
+
+	if err := queue.PauseQueue(ctx, "reports"); err != nil {
+		t.Fatalf("PauseQueue: %v", err)
+	}
+	if err := queue.Enqueue(ctx, newTask(t, "report.build", "reports", "")); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	expectNone(t, done)
+	queues, err := queue.Queues(ctx)
+	if err != nil {
+		t.Fatalf("Queues: %v", err)
+	}
+	if len(queues) != 1 || !queues[0].Paused || queues[0].Pending != 1 {
+		t.Fatalf("queues = %+v, want paused reports with 1 pending", queues)
+	}
+
+	if err := queue.ResumeQueue(ctx, "reports"); err != nil {
+		t.Fatalf("ResumeQueue: %v", err)
+	}
+	receive(t, done)
+}
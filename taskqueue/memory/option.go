@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+const (
+	// DefaultQueue is the lane used for tasks without a Definition.Queue.
+	DefaultQueue = "default"
+
+	defaultConcurrency     = 10
+	defaultMaxRetry        = 3
+	defaultRetryBackoff    = time.Second
+	defaultArchiveCapacity = 1000
+)
+
+// Option configures a Queue during construction.
+type Option func(*Queue)
+
+// WithConcurrency sets the maximum number of concurrently running tasks for
+// queue. An empty queue name configures DefaultQueue.
+func WithConcurrency(queue string, concurrency int) Option {
+	return func(q *Queue) {
+		if concurrency > 0 {
+			q.concurrency[laneName(queue)] = concurrency
+		}
+	}
+}
+
+// WithDefaultConcurrency sets the concurrency for queues without an explicit
+// WithConcurrency setting.
+func WithDefaultConcurrency(concurrency int) Option {
+	return func(q *Queue) {
+		if concurrency > 0 {
+			q.defaultConcurrency = concurrency
+		}
+	}
+}
+
+// WithDefaultMaxRetry sets the retry count for tasks enqueued without
+// taskqueue.WithMaxRetry.
+func WithDefaultMaxRetry(maxRetry int) Option {
+	return func(q *Queue) {
+		if maxRetry >= 0 {
+			q.defaultMaxRetry = maxRetry
+		}
+	}
+}
+
+// WithRetryBackoff sets a fixed delay before a failed attempt is retried. It
+// is ignored when WithRetryPolicy is set.
+func WithRetryBackoff(backoff time.Duration) Option {
+	return func(q *Queue) {
+		if backoff >= 0 {
+			q.retryBackoff = backoff
+		}
+	}
+}
+
+// WithWorkers bounds how many tasks run at once across all queues. Free
+// workers go to queues by WithQueuePriority, within each queue's concurrency.
+// By default only per-queue concurrency applies.
+func WithWorkers(workers int) Option {
+	return func(q *Queue) {
+		if workers > 0 {
+			q.workers = workers
+		}
+	}
+}
+
+// WithQueuePriority sets how free workers choose between queues with ready
+// tasks. It only takes effect with WithWorkers; otherwise queues do not share
+// workers. The default weighs every queue equally. New rejects an invalid
+// priority with taskqueue.ErrInvalidQueuePriority.
+func WithQueuePriority(priority taskqueue.QueuePriority) Option {
+	return func(q *Queue) {
+		q.priority = priority
+	}
+}
+
+// WithArchiveCapacity sets how many tasks that failed for good are kept for
+// inspection through the taskqueue.Inspector methods; the oldest are dropped
+// first. Zero keeps none.
+func WithArchiveCapacity(capacity int) Option {
+	return func(q *Queue) {
+		if capacity >= 0 {
+			q.archiveCapacity = capacity
+		}
+	}
+}
+
+// WithRetryPolicy sets the policy that decides whether and when failed
+// attempts are retried. ErrSkipRetry and the task max retry still stop retries.
+func WithRetryPolicy(policy taskqueue.RetryPolicy) Option {
+	return func(q *Queue) {
+		if policy != nil {
+			q.retryPolicy = policy
+		}
+	}
+}
+
+// WithDeadLetterSink sends tasks that exhaust their retries or fail with
+// taskqueue.ErrSkipRetry to sink, with their attempt history.
+func WithDeadLetterSink(sink taskqueue.DeadLetterSink) Option {
+	return func(q *Queue) {
+		q.deadLetters = sink
+	}
+}
+
+// WithKeyOrdering runs tasks that share a non-empty Task.Key one at a time,
+// in the order they became due, across all queues. A task holds its key
+// through retries, so a later task with the same key waits until the earlier
+// one succeeds or fails for good. Waiting tasks do not occupy concurrency
+// slots, so tasks with other keys keep running.
+func WithKeyOrdering() Option {
+	return func(q *Queue) {
+		q.keyOrdering = true
+	}
+}
+
+// WithLimiter enforces limiter before router dispatch. Tasks over a limit are
+// rescheduled without counting a retry.
+func WithLimiter(limiter *taskqueue.Limiter) Option {
+	return func(q *Queue) {
+		q.limiter = limiter
+	}
+}
+
+// WithMiddleware wraps router dispatch with middleware in declaration order.
+func WithMiddleware(middleware ...taskqueue.Middleware) Option {
+	return func(q *Queue) {
+		q.middleware = append(q.middleware, middleware...)
+	}
+}
+
+// WithLogger sets the logger for runtime diagnostics such as dead letter sink
+// failures.
+func WithLogger(logger *slog.Logger) Option {
+	return func(q *Queue) {
+		if logger != nil {
+			q.logger = logger
+		}
+	}
+}
+
+// WithClock sets the time source used for scheduling decisions.
+func WithClock(now func() time.Time) Option {
+	return func(q *Queue) {
+		if now != nil {
+			q.now = now
+		}
+	}
+}
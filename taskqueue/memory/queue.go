@@ -0,0 +1,590 @@
+package memory
+
+import (
+	"container/heap"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// Queue is an in-process task queue provider dispatching through a Router.
+type Queue struct {
+	process taskqueue.ProcessorFunc
+
+	mu        sync.Mutex
+	lanes     map[string]*lane
+	laneOrder []string
+	busy      int
+	scheduled scheduleHeap
+	unique    map[string]uniqueLock
+	keys      map[string]*keyLine
+	entries   map[string]*entry
+	archived  []*entry
+	started   bool
+	closed    bool
+
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopped  chan struct{}
+	inflight sync.WaitGroup
+	rootCtx  context.Context
+	cancel   context.CancelFunc
+
+	concurrency        map[string]int
+	defaultConcurrency int
+	workers            int
+	priority           taskqueue.QueuePriority
+	defaultMaxRetry    int
+	retryBackoff       time.Duration
+	retryPolicy        taskqueue.RetryPolicy
+	deadLetters        taskqueue.DeadLetterSink
+	keyOrdering        bool
+	archiveCapacity    int
+	limiter            *taskqueue.Limiter
+	middleware         []taskqueue.Middleware
+	logger             *slog.Logger
+	now                func() time.Time
+}
+
+var (
+	_ taskqueue.Enqueuer  = (*Queue)(nil)
+	_ taskqueue.Worker    = (*Queue)(nil)
+	_ taskqueue.Runner    = (*Queue)(nil)
+	_ taskqueue.Inspector = (*Queue)(nil)
+)
+
+type lane struct {
+	ready  []*entry
+	active int
+	paused bool
+}
+
+type entry struct {
+	id        string
+	task      taskqueue.Task
+	queue     string
+	processAt time.Time
+	deadline  time.Time
+	timeout   time.Duration
+	maxRetry  int
+	retried   int
+	uniqueKey string
+	attempts  []taskqueue.Attempt
+	index     int
+	state     taskqueue.TaskState
+	cancel    context.CancelFunc
+	canceled  bool
+}
+
+// keyLine tracks the entry holding an ordering key and the due entries
+// waiting for it.
+type keyLine struct {
+	owner   *entry
+	waiting []*entry
+}
+
+type uniqueLock struct {
+	taskID    string
+	expiresAt time.Time
+}
+
+// New creates an in-process queue that dispatches tasks through router.
+//
+// Tasks may be enqueued before Start; they are dispatched once the queue is
+// started.
+func New(router *taskqueue.Router, opts ...Option) (*Queue, error) {
+	if router == nil {
+		return nil, ErrNilRouter
+	}
+	rootCtx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		lanes:              make(map[string]*lane),
+		unique:             make(map[string]uniqueLock),
+		keys:               make(map[string]*keyLine),
+		entries:            make(map[string]*entry),
+		wake:               make(chan struct{}, 1),
+		stop:               make(chan struct{}),
+		stopped:            make(chan struct{}),
+		rootCtx:            rootCtx,
+		cancel:             cancel,
+		concurrency:        make(map[string]int),
+		defaultConcurrency: defaultConcurrency,
+		defaultMaxRetry:    defaultMaxRetry,
+		archiveCapacity:    defaultArchiveCapacity,
+		retryBackoff:       defaultRetryBackoff,
+		logger:             slog.Default(),
+		now:                time.Now,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(q)
+		}
+	}
+	if err := q.priority.Validate(); err != nil {
+		cancel()
+		return nil, err
+	}
+	if q.retryPolicy == nil {
+		q.retryPolicy = taskqueue.NewRetryPolicy(taskqueue.FixedBackoff(q.retryBackoff))
+	}
+	middleware := append([]taskqueue.Middleware{taskqueue.Recover(), taskqueue.Limit(q.limiter)}, q.middleware...)
+	q.process = taskqueue.Chain(router.Process, middleware...)
+	return q, nil
+}
+
+// Enqueue validates policy and stores task until it is due for processing.
+func (q *Queue) Enqueue(ctx context.Context, task taskqueue.Task, opts ...taskqueue.EnqueueOption) error {
+	if task.Type() == "" {
+		return taskqueue.ErrEmptyType
+	}
+	policy := taskqueue.NewEnqueueOptions(opts...)
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	id, err := generateID()
+	if err != nil {
+		return fmt.Errorf("generate task id: %w", err)
+	}
+
+	now := q.now()
+	e := &entry{
+		id:        id,
+		task:      task,
+		queue:     laneName(task.Queue()),
+		processAt: now,
+		deadline:  policy.Deadline(),
+		timeout:   policy.Timeout(),
+		maxRetry:  q.defaultMaxRetry,
+		index:     -1,
+	}
+	if delay := policy.Delay(); delay > 0 {
+		e.processAt = now.Add(delay)
+	} else if processAt := policy.ProcessAt(); !processAt.IsZero() {
+		e.processAt = processAt
+	}
+	if maxRetry, ok := policy.MaxRetry(); ok {
+		e.maxRetry = maxRetry
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	if ttl := policy.UniqueTTL(); ttl > 0 {
+		key := uniqueKey(task)
+		if lock, ok := q.unique[key]; ok && now.Before(lock.expiresAt) {
+			return taskqueue.ErrDuplicateTask
+		}
+		q.unique[key] = uniqueLock{taskID: e.id, expiresAt: now.Add(ttl)}
+		e.uniqueKey = key
+	}
+	q.entries[e.id] = e
+	q.scheduleLocked(e, now)
+	return nil
+}
+
+// Start begins dispatching due tasks in a background goroutine.
+func (q *Queue) Start(context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	if q.started {
+		return ErrAlreadyStarted
+	}
+	q.started = true
+	go q.loop()
+	return nil
+}
+
+// Shutdown stops dispatching new tasks and waits for in-flight tasks to finish
+// until ctx is done. Queued tasks that have not started are discarded. When
+// ctx is done first, in-flight processor contexts are canceled and ctx.Err()
+// is returned.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	started := q.started
+	q.mu.Unlock()
+	defer q.cancel()
+	if !started {
+		return nil
+	}
+
+	q.stopOnce.Do(func() { close(q.stop) })
+	<-q.stopped
+
+	done := make(chan struct{})
+	go func() {
+		q.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run starts the queue, blocks until ctx is done, and then drains in-flight
+// tasks before returning.
+func (q *Queue) Run(ctx context.Context) error {
+	if err := q.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return q.Shutdown(context.WithoutCancel(ctx))
+}
+
+func (q *Queue) loop() {
+	defer close(q.stopped)
+	timer := time.NewTimer(time.Hour)
+	stopTimer(timer)
+	for {
+		wait, ok := q.dispatch()
+		var timeout <-chan time.Time
+		if ok {
+			timer.Reset(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-q.stop:
+			stopTimer(timer)
+			return
+		case <-q.wake:
+		case <-timeout:
+		}
+		stopTimer(timer)
+	}
+}
+
+// dispatch promotes due tasks, starts as many ready tasks as lane concurrency
+// and the shared worker limit allow, picking lanes by queue priority, and
+// reports how long to wait for the next scheduled task.
+func (q *Queue) dispatch() (time.Duration, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	for len(q.scheduled) > 0 && !q.scheduled[0].processAt.After(now) {
+		e, _ := heap.Pop(&q.scheduled).(*entry)
+		e.state = taskqueue.TaskStatePending
+		l := q.laneLocked(e.queue)
+		l.ready = append(l.ready, e)
+	}
+	var candidates []string
+	for q.workers == 0 || q.busy < q.workers {
+		candidates = candidates[:0]
+		for _, name := range q.laneOrder {
+			if l := q.lanes[name]; !l.paused && len(l.ready) > 0 && l.active < q.concurrencyOf(name) {
+				candidates = append(candidates, name)
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		l := q.lanes[candidates[q.priority.Pick(candidates)]]
+		e := l.ready[0]
+		l.ready[0] = nil
+		l.ready = l.ready[1:]
+		if !q.holdKeyLocked(e) {
+			continue
+		}
+		l.active++
+		q.busy++
+		e.state = taskqueue.TaskStateActive
+		q.inflight.Add(1)
+		go q.execute(e)
+	}
+	if len(q.scheduled) == 0 {
+		return 0, false
+	}
+	return q.scheduled[0].processAt.Sub(now), true
+}
+
+func (q *Queue) execute(e *entry) {
+	defer q.inflight.Done()
+	startedAt := q.now()
+	err := q.attempt(e)
+	if q.finish(e, err, startedAt) {
+		q.deadLetter(e, err)
+	}
+	q.signal()
+}
+
+func (q *Queue) attempt(e *entry) error {
+	if !e.deadline.IsZero() && !q.now().Before(e.deadline) {
+		return fmt.Errorf("%w: %w", taskqueue.ErrSkipRetry, context.DeadlineExceeded)
+	}
+
+	ctx, cancel := context.WithCancel(taskqueue.ContextWithExecutionInfo(q.rootCtx, q.executionInfo(e)))
+	defer cancel()
+	q.mu.Lock()
+	e.cancel = cancel
+	canceled := e.canceled
+	q.mu.Unlock()
+	if canceled {
+		return context.Canceled
+	}
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+	if !e.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, e.deadline)
+		defer cancel()
+	}
+	return q.process(ctx, e.task)
+}
+
+// finish records the attempt, then retries, archives, or releases e and
+// reports whether it failed for good. Tasks that would be retried after
+// Shutdown, and tasks canceled through CancelTask, are dropped rather than
+// dead-lettered. A taskqueue.RetryLaterError reschedules e without counting a
+// retry.
+func (q *Queue) finish(e *entry, err error, startedAt time.Time) bool {
+	delay, later := taskqueue.RetryLaterDelay(err)
+	retry := later
+	if !later {
+		delay, retry = q.nextRetry(e, err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.laneLocked(e.queue).active--
+	q.busy--
+	e.cancel = nil
+	if e.canceled {
+		q.removeLocked(e)
+		return false
+	}
+	if err != nil && !later {
+		e.attempts = append(e.attempts, taskqueue.Attempt{
+			RetryCount: e.retried,
+			StartedAt:  startedAt,
+			FinishedAt: q.now(),
+			Error:      err.Error(),
+		})
+	}
+	if !retry || q.closed {
+		failed := err != nil && !retry
+		if failed {
+			q.archiveLocked(e)
+		} else {
+			q.removeLocked(e)
+		}
+		return failed
+	}
+	now := q.now()
+	if !later {
+		e.retried++
+	}
+	e.processAt = now.Add(delay)
+	q.scheduleLocked(e, now)
+	return false
+}
+
+func (q *Queue) deadLetter(e *entry, err error) {
+	if q.deadLetters == nil {
+		return
+	}
+	letter := taskqueue.DeadLetter{
+		Task:     e.task,
+		Info:     q.executionInfo(e),
+		Err:      err,
+		Attempts: e.attempts,
+	}
+	if putErr := q.deadLetters.Put(context.WithoutCancel(q.rootCtx), letter); putErr != nil {
+		q.logger.Error("taskqueue dead letter failed",
+			slog.String("task_id", e.id), slog.String("task_type", string(e.task.Type())), slog.Any("error", putErr))
+	}
+}
+
+func (q *Queue) nextRetry(e *entry, err error) (time.Duration, bool) {
+	if err == nil || errors.Is(err, taskqueue.ErrSkipRetry) || e.retried >= e.maxRetry {
+		return 0, false
+	}
+	decision := q.retryPolicy.NextRetry(e.task, q.executionInfo(e), err)
+	return max(decision.Delay, 0), decision.Retry
+}
+
+func (q *Queue) executionInfo(e *entry) taskqueue.ExecutionInfo {
+	return taskqueue.NewExecutionInfo(
+		taskqueue.WithExecutionTaskID(e.id),
+		taskqueue.WithExecutionQueue(e.queue),
+		taskqueue.WithExecutionRetryCount(e.retried),
+		taskqueue.WithExecutionMaxRetry(e.maxRetry),
+		taskqueue.WithExecutionPriority(q.priority.Weight(e.queue)),
+	)
+}
+
+func (q *Queue) scheduleLocked(e *entry, now time.Time) {
+	if e.processAt.After(now) {
+		e.state = taskqueue.TaskStateScheduled
+		if e.retried > 0 {
+			e.state = taskqueue.TaskStateRetry
+		}
+		heap.Push(&q.scheduled, e)
+	} else {
+		e.state = taskqueue.TaskStatePending
+		l := q.laneLocked(e.queue)
+		l.ready = append(l.ready, e)
+	}
+	q.signal()
+}
+
+func (q *Queue) releaseLocked(e *entry) {
+	if e.uniqueKey == "" {
+		return
+	}
+	if lock, ok := q.unique[e.uniqueKey]; ok && lock.taskID == e.id {
+		delete(q.unique, e.uniqueKey)
+	}
+}
+
+// holdKeyLocked reports whether e may start. With key ordering, an entry whose
+// key is held by another entry waits in that key's line instead.
+func (q *Queue) holdKeyLocked(e *entry) bool {
+	key := e.task.Key()
+	if !q.keyOrdering || key == "" {
+		return true
+	}
+	line, ok := q.keys[key]
+	if !ok {
+		q.keys[key] = &keyLine{owner: e}
+		return true
+	}
+	if line.owner == e {
+		return true
+	}
+	line.waiting = append(line.waiting, e)
+	return false
+}
+
+// releaseKeyLocked hands the key of a finished entry to the next waiting entry.
+func (q *Queue) releaseKeyLocked(e *entry) {
+	key := e.task.Key()
+	line, ok := q.keys[key]
+	if !ok || line.owner != e {
+		return
+	}
+	if len(line.waiting) == 0 {
+		delete(q.keys, key)
+		return
+	}
+	next := line.waiting[0]
+	line.waiting[0] = nil
+	line.waiting = line.waiting[1:]
+	line.owner = next
+	// next became due before the entries queued behind it, so it goes first.
+	l := q.laneLocked(next.queue)
+	l.ready = append([]*entry{next}, l.ready...)
+}
+
+func (q *Queue) laneLocked(name string) *lane {
+	l, ok := q.lanes[name]
+	if ok {
+		return l
+	}
+	l = &lane{}
+	q.lanes[name] = l
+	i := sort.SearchStrings(q.laneOrder, name)
+	q.laneOrder = append(q.laneOrder, "")
+	copy(q.laneOrder[i+1:], q.laneOrder[i:])
+	q.laneOrder[i] = name
+	return l
+}
+
+func (q *Queue) concurrencyOf(name string) int {
+	if concurrency, ok := q.concurrency[name]; ok {
+		return concurrency
+	}
+	return q.defaultConcurrency
+}
+
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func laneName(queue string) string {
+	if queue == "" {
+		return DefaultQueue
+	}
+	return queue
+}
+
+func uniqueKey(task taskqueue.Task) string {
+	key := task.Key()
+	if key == "" {
+		sum := sha256.Sum256(task.Payload())
+		key = hex.EncodeToString(sum[:])
+	}
+	return string(task.Type()) + "\x00" + laneName(task.Queue()) + "\x00" + key
+}
+
+func generateID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+func stopTimer(timer *time.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+}
+
+type scheduleHeap []*entry
+
+func (h scheduleHeap) Len() int { return len(h) }
+
+func (h scheduleHeap) Less(i, j int) bool { return h[i].processAt.Before(h[j].processAt) }
+
+func (h scheduleHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *scheduleHeap) Push(x any) {
+	e, _ := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *scheduleHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*h = old[:n-1]
+	return e
+}
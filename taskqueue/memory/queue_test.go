@@ -0,0 +1,564 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+	"github.com/go-jimu/components/taskqueue/memory"
+)
+
+const waitTimeout = 2 * time.Second
+
+// Enqueued tasks should be dispatched through the router with execution
+// metadata so processors can observe provider task identity and retry caps.
+func TestQueue_DispatchesThroughRouterWithExecutionInfo(t *testing.T) {
+	router := taskqueue.NewRouter()
+	infos := make(chan taskqueue.ExecutionInfo, 1)
+	mustRegister(t, router, "email.welcome", func(ctx context.Context, _ taskqueue.Task) error {
+		info, _ := taskqueue.ExecutionInfoFromContext(ctx)
+		infos <- info
+		return nil
+	})
+	queue := startQueue(t, router)
+
+	if err := queue.Enqueue(context.Background(), newTask(t, "email.welcome", "mailers", "user-1"), taskqueue.WithMaxRetry(5)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	info := receive(t, infos)
+	if info.TaskID() == "" {
+		t.Fatal("task id is empty")
+	}
+	if info.Queue() != "mailers" {
+		t.Fatalf("queue = %q, want mailers", info.Queue())
+	}
+	if retryCount, ok := info.RetryCount(); !ok || retryCount != 0 {
+		t.Fatalf("retry count = %d, %t; want 0, true", retryCount, ok)
+	}
+	if maxRetry, ok := info.MaxRetry(); !ok || maxRetry != 5 {
+		t.Fatalf("max retry = %d, %t; want 5, true", maxRetry, ok)
+	}
+}
+
+// Failed attempts should be retried up to the enqueue max retry with an
+// increasing retry count, keeping the same provider task ID.
+func TestQueue_RetriesFailedAttempts(t *testing.T) {
+	router := taskqueue.NewRouter()
+	infos := make(chan taskqueue.ExecutionInfo, 3)
+	mustRegister(t, router, "email.welcome", func(ctx context.Context, _ taskqueue.Task) error {
+		info, _ := taskqueue.ExecutionInfoFromContext(ctx)
+		infos <- info
+		return errors.New("smtp unavailable")
+	})
+	queue := startQueue(t, router, memory.WithRetryBackoff(0))
+
+	if err := queue.Enqueue(context.Background(), newTask(t, "email.welcome", "", ""), taskqueue.WithMaxRetry(2)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	var taskID string
+	for want := 0; want <= 2; want++ {
+		info := receive(t, infos)
+		if retryCount, _ := info.RetryCount(); retryCount != want {
+			t.Fatalf("retry count = %d, want %d", retryCount, want)
+		}
+		if taskID == "" {
+			taskID = info.TaskID()
+		}
+		if info.TaskID() != taskID {
+			t.Fatalf("task id = %q, want %q", info.TaskID(), taskID)
+		}
+	}
+	expectNone(t, infos)
+}
+
+// ErrSkipRetry should end processing after the first attempt even when retries
+// remain.
+func TestQueue_SkipRetryStopsRetries(t *testing.T) {
+	router := taskqueue.NewRouter()
+	calls := make(chan struct{}, 2)
+	mustRegister(t, router, "email.welcome", func(context.Context, taskqueue.Task) error {
+		calls <- struct{}{}
+		return taskqueue.ErrSkipRetry
+	})
+	queue := startQueue(t, router, memory.WithRetryBackoff(0))
+
+	if err := queue.Enqueue(context.Background(), newTask(t, "email.welcome", "", ""), taskqueue.WithMaxRetry(3)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	receive(t, calls)
+	expectNone(t, calls)
+}
+
+// A configured retry policy should decide retries with the attempt's execution
+// metadata, and a policy refusal should stop retries before max retry.
+func TestQueue_ConsultsRetryPolicy(t *testing.T) {
+	router := taskqueue.NewRouter()
+	calls := make(chan struct{}, 3)
+	mustRegister(t, router, "email.welcome", func(context.Context, taskqueue.Task) error {
+		calls <- struct{}{}
+		return errors.New("smtp unavailable")
+	})
+	infos := make(chan taskqueue.ExecutionInfo, 3)
+	policy := taskqueue.RetryPolicyFunc(func(_ taskqueue.Task, info taskqueue.ExecutionInfo, _ error) taskqueue.RetryDecision {
+		infos <- info
+		retryCount, _ := info.RetryCount()
+		return taskqueue.RetryDecision{Retry: retryCount < 1}
+	})
+	queue := startQueue(t, router, memory.WithRetryPolicy(policy))
+
+	if err := queue.Enqueue(context.Background(), newTask(t, "email.welcome", "", ""), taskqueue.WithMaxRetry(5)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	receive(t, calls)
+	receive(t, calls)
+	expectNone(t, calls)
+	first := receive(t, infos)
+	if maxRetry, _ := first.MaxRetry(); first.TaskID() == "" || maxRetry != 5 {
+		t.Fatalf("policy info = %#v, want task id and max retry 5", first)
+	}
+}
+
+// A processor retry-after hint should replace the configured retry backoff.
+func TestQueue_HonoursRetryAfterHint(t *testing.T) {
+	router := taskqueue.NewRouter()
+	attempts := make(chan time.Time, 2)
+	mustRegister(t, router, "email.welcome", func(context.Context, taskqueue.Task) error {
+		attempts <- time.Now()
+		return taskqueue.RetryAfter(50*time.Millisecond, errors.New("rate limited"))
+	})
+	queue := startQueue(t, router, memory.WithRetryBackoff(time.Hour))
+
+	if err := queue.Enqueue(context.Background(), newTask(t, "email.welcome", "", ""), taskqueue.WithMaxRetry(1)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	first := receive(t, attempts)
+	if elapsed := receive(t, attempts).Sub(first); elapsed < 50*time.Millisecond {
+		t.Fatalf("retried after %v, want at least 50ms", elapsed)
+	}
+}
+
+// A Batcher registered with the router should group concurrently running
+// tasks and retry only the members its BatchProcessor reported as failed.
+func TestQueue_BatcherRetriesOnlyFailedMembers(t *testing.T) {
+	router := taskqueue.NewRouter()
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	batches := make(chan int, 4)
+	batcher, err := taskqueue.NewBatcher(taskqueue.NewBatchProcessor("search.index", func(_ context.Context, tasks []taskqueue.Task) error {
+		mu.Lock()
+		defer mu.Unlock()
+		errs := make(taskqueue.BatchErrors, len(tasks))
+		for i, task := range tasks {
+			attempts[task.Key()]++
+			if task.Key() == "doc-2" && attempts[task.Key()] == 1 {
+				errs[i] = errors.New("index conflict")
+			}
+		}
+		batches <- len(tasks)
+		return errs
+	}), taskqueue.WithMaxBatchSize(3), taskqueue.WithMaxBatchWait(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewBatcher: %v", err)
+	}
+	if err := router.Register(batcher); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	queue := startQueue(t, router, memory.WithRetryBackoff(0))
+
+	for _, key := range []string{"doc-1", "doc-2", "doc-3"} {
+		if err := queue.Enqueue(context.Background(), newTask(t, "search.index", "", key), taskqueue.WithMaxRetry(1)); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	if size := receive(t, batches); size != 3 {
+		t.Fatalf("first batch size = %d, want 3", size)
+	}
+	if size := receive(t, batches); size != 1 {
+		t.Fatalf("retry batch size = %d, want 1", size)
+	}
+	expectNone(t, batches)
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts["doc-1"] != 1 || attempts["doc-2"] != 2 || attempts["doc-3"] != 1 {
+		t.Fatalf("attempts = %v", attempts)
+	}
+}
+
+// With key ordering, a task should wait for an earlier task with the same key
+// to finish all of its retries, while tasks with other keys keep running.
+func TestQueue_KeyOrderingHoldsKeyAcrossRetries(t *testing.T) {
+	router := taskqueue.NewRouter()
+	var mu sync.Mutex
+	failedOnce := false
+	processed := make(chan string, 4)
+	mustRegister(t, router, "account.event", func(_ context.Context, task taskqueue.Task) error {
+		name := task.Key() + "/" + string(task.Payload())
+		mu.Lock()
+		retry := name == `account-a/"first"` && !failedOnce
+		failedOnce = failedOnce || retry
+		mu.Unlock()
+		if retry {
+			processed <- name + " failed"
+			return errors.New("transient")
+		}
+		processed <- name
+		return nil
+	})
+	queue := startQueue(t, router, memory.WithKeyOrdering(), memory.WithRetryBackoff(30*time.Millisecond))
+
+	for _, event := range []struct{ key, payload string }{{"account-a", "first"}, {"account-a", "second"}, {"account-b", "first"}} {
+		task, err := taskqueue.NewJSONTask(taskqueue.Definition{Type: "account.event"}, event.payload, taskqueue.WithKey(event.key))
+		if err != nil {
+			t.Fatalf("NewJSONTask: %v", err)
+		}
+		if err := queue.Enqueue(context.Background(), task); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	var accountA []string
+	for range 4 {
+		if name := receive(t, processed); strings.HasPrefix(name, "account-a/") {
+			accountA = append(accountA, name)
+		} else if len(accountA) > 1 {
+			t.Fatalf("account-b waited for account-a retries: %v", accountA)
+		}
+	}
+	want := []string{`account-a/"first" failed`, `account-a/"first"`, `account-a/"second"`}
+	if !reflect.DeepEqual(accountA, want) {
+		t.Fatalf("account-a processed = %v, want %v", accountA, want)
+	}
+}
+
+// Tasks over a limiter quota should be rescheduled without consuming their
+// retry budget or being recorded as failed attempts.
+func TestQueue_LimiterReschedulesWithoutCountingRetry(t *testing.T) {
+	router := taskqueue.NewRouter()
+	release := make(chan struct{})
+	infos := make(chan taskqueue.ExecutionInfo, 2)
+	mustRegister(t, router, "api.call", func(ctx context.Context, _ taskqueue.Task) error {
+		info, _ := taskqueue.ExecutionInfoFromContext(ctx)
+		infos <- info
+		<-release
+		return nil
+	})
+	limiter := taskqueue.NewLimiter(
+		taskqueue.WithTypeConcurrency("api.call", 1),
+		taskqueue.WithQuotaRetryDelay(5*time.Millisecond),
+	)
+	queue := startQueue(t, router, memory.WithLimiter(limiter))
+
+	for _, key := range []string{"call-1", "call-2"} {
+		if err := queue.Enqueue(context.Background(), newTask(t, "api.call", "", key), taskqueue.WithMaxRetry(0)); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	receive(t, infos)
+	time.Sleep(30 * time.Millisecond) // the second task is deferred several times
+	expectNone(t, infos)
+	close(release)
+
+	if retryCount, _ := receive(t, infos).RetryCount(); retryCount != 0 {
+		t.Fatalf("retry count = %d, want 0", retryCount)
+	}
+}
+
+// Delayed tasks should not be dispatched before their delay has elapsed.
+func TestQueue_HonoursDelay(t *testing.T) {
+	router := taskqueue.NewRouter()
+	processed := make(chan time.Time, 1)
+	mustRegister(t, router, "email.welcome", func(context.Context, taskqueue.Task) error {
+		processed <- time.Now()
+		return nil
+	})
+	queue := startQueue(t, router)
+
+	enqueuedAt := time.Now()
+	if err := queue.Enqueue(context.Background(), newTask(t, "email.welcome", "", ""), taskqueue.WithDelay(50*time.Millisecond)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	if elapsed := receive(t, processed).Sub(enqueuedAt); elapsed < 50*time.Millisecond {
+		t.Fatalf("processed after %v, want at least 50ms", elapsed)
+	}
+}
+
+// Unique tasks should be rejected while an earlier task with the same identity
+// is still queued.
+func TestQueue_RejectsDuplicateUniqueTask(t *testing.T) {
+	queue, err := memory.New(taskqueue.NewRouter())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	task := newTask(t, "email.welcome", "mailers", "user-1")
+
+	if err := queue.Enqueue(context.Background(), task, taskqueue.WithUnique(time.Minute)); err != nil {
+		t.Fatalf("first Enqueue: %v", err)
+	}
+	err = queue.Enqueue(context.Background(), task, taskqueue.WithUnique(time.Minute))
+	if !errors.Is(err, taskqueue.ErrDuplicateTask) {
+		t.Fatalf("duplicate error = %v, want ErrDuplicateTask", err)
+	}
+	other := newTask(t, "email.welcome", "mailers", "user-2")
+	if err := queue.Enqueue(context.Background(), other, taskqueue.WithUnique(time.Minute)); err != nil {
+		t.Fatalf("other key Enqueue: %v", err)
+	}
+}
+
+// Timeouts and deadlines should bound the processor context.
+func TestQueue_AppliesTimeoutToAttemptContext(t *testing.T) {
+	router := taskqueue.NewRouter()
+	remaining := make(chan time.Duration, 1)
+	mustRegister(t, router, "email.welcome", func(ctx context.Context, _ taskqueue.Task) error {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			remaining <- 0
+			return nil
+		}
+		remaining <- time.Until(deadline)
+		return nil
+	})
+	queue := startQueue(t, router)
+
+	if err := queue.Enqueue(context.Background(), newTask(t, "email.welcome", "", ""), taskqueue.WithTimeout(time.Minute)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	got := receive(t, remaining)
+	if got <= 0 || got > time.Minute {
+		t.Fatalf("remaining = %v, want within one minute", got)
+	}
+}
+
+// Tasks whose deadline has already passed should be dropped without calling
+// the processor.
+func TestQueue_DropsTaskPastDeadline(t *testing.T) {
+	router := taskqueue.NewRouter()
+	calls := make(chan struct{}, 1)
+	mustRegister(t, router, "email.welcome", func(context.Context, taskqueue.Task) error {
+		calls <- struct{}{}
+		return nil
+	})
+	queue := startQueue(t, router)
+
+	if err := queue.Enqueue(context.Background(), newTask(t, "email.welcome", "", ""), taskqueue.WithDeadline(time.Now().Add(-time.Second))); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	expectNone(t, calls)
+}
+
+// Per-queue concurrency should cap how many tasks of one lane run at once.
+func TestQueue_LimitsPerQueueConcurrency(t *testing.T) {
+	router := taskqueue.NewRouter()
+	var active, peak atomic.Int32
+	var wg sync.WaitGroup
+	wg.Add(4)
+	mustRegister(t, router, "email.welcome", func(context.Context, taskqueue.Task) error {
+		defer wg.Done()
+		current := active.Add(1)
+		for {
+			seen := peak.Load()
+			if current <= seen || peak.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		active.Add(-1)
+		return nil
+	})
+	queue := startQueue(t, router, memory.WithConcurrency("mailers", 1))
+
+	for i := 0; i < 4; i++ {
+		if err := queue.Enqueue(context.Background(), newTask(t, "email.welcome", "mailers", "")); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	wg.Wait()
+
+	if peak.Load() != 1 {
+		t.Fatalf("peak concurrency = %d, want 1", peak.Load())
+	}
+}
+
+// Shutdown should wait for in-flight tasks and reject new enqueues afterwards.
+func TestQueue_ShutdownDrainsInFlightTasks(t *testing.T) {
+	router := taskqueue.NewRouter()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var completed atomic.Bool
+	mustRegister(t, router, "email.welcome", func(context.Context, taskqueue.Task) error {
+		close(started)
+		<-release
+		completed.Store(true)
+		return nil
+	})
+	queue, err := memory.New(router)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := queue.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := queue.Enqueue(context.Background(), newTask(t, "email.welcome", "", "")); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	receive(t, started)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	if err := queue.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if !completed.Load() {
+		t.Fatal("Shutdown returned before in-flight task completed")
+	}
+	if err := queue.Enqueue(context.Background(), newTask(t, "email.welcome", "", "")); !errors.Is(err, memory.ErrClosed) {
+		t.Fatalf("Enqueue after shutdown error = %v, want ErrClosed", err)
+	}
+}
+
+// Run should treat context cancellation as a normal shutdown signal.
+func TestQueue_RunReturnsNilOnCancellation(t *testing.T) {
+	queue, err := memory.New(taskqueue.NewRouter())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- queue.Run(ctx) }()
+
+	cancel()
+	if err := receive(t, done); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+// Invalid enqueue policy and missing routers should be rejected up front.
+func TestQueue_ValidationErrors(t *testing.T) {
+	if _, err := memory.New(nil); !errors.Is(err, memory.ErrNilRouter) {
+		t.Fatalf("nil router error = %v, want ErrNilRouter", err)
+	}
+	queue, err := memory.New(taskqueue.NewRouter())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	err = queue.Enqueue(context.Background(), newTask(t, "email.welcome", "", ""), taskqueue.WithDelay(-time.Second))
+	if !errors.Is(err, taskqueue.ErrInvalidEnqueueOption) {
+		t.Fatalf("invalid policy error = %v, want ErrInvalidEnqueueOption", err)
+	}
+	if err := queue.Enqueue(context.Background(), taskqueue.Task{}); !errors.Is(err, taskqueue.ErrEmptyType) {
+		t.Fatalf("empty task error = %v, want ErrEmptyType", err)
+	}
+}
+
+func startQueue(t *testing.T, router *taskqueue.Router, opts ...memory.Option) *memory.Queue {
+	t.Helper()
+	queue, err := memory.New(router, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := queue.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+		defer cancel()
+		if err := queue.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	})
+	return queue
+}
+
+func mustRegister(t *testing.T, router *taskqueue.Router, taskType taskqueue.TaskType, fn taskqueue.ProcessorFunc) {
+	t.Helper()
+	if err := router.Register(taskqueue.NewProcessor(taskType, fn)); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+}
+
+func newTask(t *testing.T, taskType taskqueue.TaskType, queue, key string) taskqueue.Task {
+	t.Helper()
+	task, err := taskqueue.NewJSONTask(taskqueue.Definition{Type: taskType, Queue: queue}, struct{}{}, taskqueue.WithKey(key))
+	if err != nil {
+		t.Fatalf("NewJSONTask: %v", err)
+	}
+	return task
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case value := <-ch:
+		return value
+	case <-time.After(waitTimeout):
+		t.Fatal("timed out waiting for value")
+		var zero T
+		return zero
+	}
+}
+
+func expectNone[T any](t *testing.T, ch <-chan T) {
+	t.Helper()
+	select {
+	case value := <-ch:
+		t.Fatalf("unexpected value %v", value)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// With one shared worker and strict priority, an urgent task should run
+// before bulk tasks that were enqueued earlier, and see its priority.
+func TestQueue_StrictPriorityServesUrgentQueueFirst(t *testing.T) {
+	router := taskqueue.NewRouter()
+	started := make(chan taskqueue.ExecutionInfo, 4)
+	mustRegister(t, router, "email.send", func(ctx context.Context, _ taskqueue.Task) error {
+		info, _ := taskqueue.ExecutionInfoFromContext(ctx)
+		started <- info
+		return nil
+	})
+	queue, err := memory.New(router, memory.WithWorkers(1),
+		memory.WithQueuePriority(taskqueue.StrictPriority(map[string]int{"critical": 10})))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for _, lane := range []string{"bulk", "bulk", "bulk", "critical"} {
+		if err := queue.Enqueue(context.Background(), newTask(t, "email.send", lane, "")); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	if err := queue.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { _ = queue.Shutdown(context.Background()) })
+
+	first := receive(t, started)
+	if priority, ok := first.Priority(); first.Queue() != "critical" || !ok || priority != 10 {
+		t.Fatalf("first = %s with priority %d, %t; want critical with 10", first.Queue(), priority, ok)
+	}
+	for range 3 {
+		if info := receive(t, started); info.Queue() != "bulk" {
+			t.Fatalf("queue = %s, want bulk", info.Queue())
+		}
+	}
+
+	_, err = memory.New(router, memory.WithQueuePriority(taskqueue.WeightedPriority(map[string]int{"bulk": -1})))
+	if !errors.Is(err, taskqueue.ErrInvalidQueuePriority) {
+		t.Fatalf("invalid priority error = %v, want ErrInvalidQueuePriority", err)
+	}
+}
@@ -0,0 +1,150 @@
+package messagetask
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-jimu/components/ddd/message"
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// Headers carrying the message metadata that has no task envelope field.
+const (
+	MessageIDHeader  = "x-message-id"
+	OccurredAtHeader = "x-message-occurred-at"
+)
+
+// Option configures how messages are converted to and from tasks.
+type Option func(*config)
+
+type config struct {
+	queue       string
+	taskOpts    []taskqueue.Option
+	enqueueOpts []taskqueue.EnqueueOption
+	decodeOpts  []taskqueue.DecodeOption
+}
+
+// WithQueue assigns converted tasks to queue.
+func WithQueue(queue string) Option {
+	return func(cfg *config) {
+		cfg.queue = queue
+	}
+}
+
+// WithTaskOptions adds task options, such as taskqueue.WithCompression, to
+// every converted task. They are applied before the message metadata, so the
+// message key and headers win. Tasks encrypted with taskqueue.WithEncryption
+// can only be converted back when the worker side passes the keyring with
+// WithDecodeOptions.
+func WithTaskOptions(opts ...taskqueue.Option) Option {
+	return func(cfg *config) {
+		cfg.taskOpts = append(cfg.taskOpts, opts...)
+	}
+}
+
+// WithEnqueueOptions sets the enqueue policy Publisher uses for every task.
+// ToTask ignores it.
+func WithEnqueueOptions(opts ...taskqueue.EnqueueOption) Option {
+	return func(cfg *config) {
+		cfg.enqueueOpts = append(cfg.enqueueOpts, opts...)
+	}
+}
+
+// WithDecodeOptions sets the options, such as taskqueue.WithDecryptionKeyring,
+// that FromTask and the processors use to decode task payloads. ToTask and
+// Publisher ignore them.
+func WithDecodeOptions(opts ...taskqueue.DecodeOption) Option {
+	return func(cfg *config) {
+		cfg.decodeOpts = append(cfg.decodeOpts, opts...)
+	}
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	return cfg
+}
+
+// TaskType returns the task type that carries messages of kind.
+func TaskType(kind message.Kind) taskqueue.TaskType {
+	return taskqueue.TaskType(kind)
+}
+
+// ToTask converts msg into a protobuf-encoded task.
+func ToTask(msg message.Message, opts ...Option) (taskqueue.Task, error) {
+	return toTask(msg, newConfig(opts))
+}
+
+func toTask(msg message.Message, cfg config) (taskqueue.Task, error) {
+	if msg.Kind() == "" {
+		return taskqueue.Task{}, message.ErrEmptyKind
+	}
+	if msg.Payload() == nil {
+		return taskqueue.Task{}, message.ErrNilPayload
+	}
+	taskOpts := append([]taskqueue.Option(nil), cfg.taskOpts...)
+	if msg.Key() != "" {
+		taskOpts = append(taskOpts, taskqueue.WithKey(msg.Key()))
+	}
+	taskOpts = append(taskOpts,
+		taskqueue.WithHeaders(msg.Headers()),
+		taskqueue.WithHeader(MessageIDHeader, msg.ID()),
+		taskqueue.WithHeader(OccurredAtHeader, msg.OccurredAt().UTC().Format(time.RFC3339Nano)),
+	)
+	def := taskqueue.Definition{Type: TaskType(msg.Kind()), Queue: cfg.queue}
+	return taskqueue.NewProtoTask(def, msg.Payload(), taskOpts...)
+}
+
+// FromTask rebuilds the message carried by task, resolving the protobuf
+// payload for the task type through resolver. A task without MessageIDHeader
+// gets a generated message ID and one without OccurredAtHeader the current
+// time. Only WithDecodeOptions applies to FromTask.
+func FromTask(task taskqueue.Task, resolver message.PayloadResolver, opts ...Option) (message.Message, error) {
+	return fromTask(task, resolver, "", newConfig(opts).decodeOpts)
+}
+
+func fromTask(task taskqueue.Task, resolver message.PayloadResolver, fallbackID string, decodeOpts []taskqueue.DecodeOption) (message.Message, error) {
+	if resolver == nil {
+		return message.Message{}, ErrNilResolver
+	}
+	if codec := task.PayloadCodec(); codec != taskqueue.ProtoCodec {
+		return message.Message{}, fmt.Errorf("%w: %w: %q", taskqueue.ErrSkipRetry, ErrUnsupportedCodec, codec)
+	}
+	kind := message.Kind(task.Type())
+	payload, err := resolver.Resolve(kind)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("%w: resolve payload for %s: %w", taskqueue.ErrSkipRetry, kind, err)
+	}
+	if err := taskqueue.DecodeProto(task, payload, decodeOpts...); err != nil {
+		return message.Message{}, err
+	}
+
+	headers := task.Headers()
+	id := headers[MessageIDHeader]
+	if id == "" {
+		id = fallbackID
+	}
+	var occurredAt time.Time
+	if value := headers[OccurredAtHeader]; value != "" {
+		occurredAt, err = time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return message.Message{}, fmt.Errorf("%w: %w: %w", taskqueue.ErrSkipRetry, ErrInvalidOccurredAt, err)
+		}
+	}
+	delete(headers, MessageIDHeader)
+	delete(headers, OccurredAtHeader)
+
+	opts := []message.Option{
+		message.WithKey(task.Key()),
+		message.WithOccurredAt(occurredAt),
+		message.WithHeaders(headers),
+	}
+	if id != "" {
+		opts = append(opts, message.WithID(id))
+	}
+	return message.New(kind, payload, opts...)
+}
@@ -0,0 +1,24 @@
+// Package messagetask bridges ddd/message integration messages and taskqueue
+// tasks.
+//
+// ToTask converts a message.Message into a taskqueue.Task: the message Kind
+// becomes the TaskType, the protobuf payload is encoded with
+// taskqueue.ProtoCodec, the message Key becomes the task key, and headers are
+// copied. The message ID and OccurredAt travel in MessageIDHeader and
+// OccurredAtHeader so FromTask can rebuild the same message on the worker side.
+//
+// Publisher implements message.Publisher over any taskqueue.Enqueuer, so an
+// outbox Relay can feed a task queue directly. Register installs a
+// message.Handler on a taskqueue.Registrar with one Processor per Kind it
+// listens to.
+//
+// Task options given with WithTaskOptions, such as compression or encryption,
+// are reversed on the worker side. Decompression needs no configuration, but
+// encrypted tasks need the keyring passed to FromTask, NewProcessor, or
+// Register with WithDecodeOptions.
+//
+// A task that cannot be decoded back into a message, for example because its
+// kind has no registered payload or its bytes are not valid protobuf, fails
+// with an error wrapping taskqueue.ErrSkipRetry. Handler errors are returned
+// unchanged and follow the provider's retry policy.
+package messagetask
@@ -0,0 +1,10 @@
+package messagetask
+
+import "errors"
+
+var (
+	ErrNilResolver       = errors.New("message task payload resolver is nil")
+	ErrNilRegistrar      = errors.New("message task registrar is nil")
+	ErrUnsupportedCodec  = errors.New("message task payload is not protobuf encoded")
+	ErrInvalidOccurredAt = errors.New("message task occurred-at header is invalid")
+)
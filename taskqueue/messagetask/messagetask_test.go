@@ -0,0 +1,209 @@
+package messagetask_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/ddd/message"
+	"github.com/go-jimu/components/taskqueue"
+	"github.com/go-jimu/components/taskqueue/messagetask"
+	"github.com/go-jimu/components/taskqueue/tasktest"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const orderPlaced message.Kind = "orders.v1.OrderPlaced"
+
+func newResolver(t *testing.T) *message.PayloadRegistry {
+	t.Helper()
+	registry := message.NewPayloadRegistry()
+	if err := registry.Register(orderPlaced, func() proto.Message { return &wrapperspb.StringValue{} }); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return registry
+}
+
+func newMessage(t *testing.T) message.Message {
+	t.Helper()
+	msg, err := message.New(orderPlaced, wrapperspb.String("order-1"),
+		message.WithID("message-1"),
+		message.WithKey("customer-1"),
+		message.WithOccurredAt(time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)),
+		message.WithHeader("trace", "abc"),
+	)
+	if err != nil {
+		t.Fatalf("message.New: %v", err)
+	}
+	return msg
+}
+
+type recordingHandler struct {
+	kinds    []message.Kind
+	messages []message.Message
+	err      error
+}
+
+func (h *recordingHandler) Listening() []message.Kind {
+	return h.kinds
+}
+
+func (h *recordingHandler) Handle(_ context.Context, msg message.Message) error {
+	h.messages = append(h.messages, msg)
+	return h.err
+}
+
+// A message converted to a task and back should keep its kind, payload, ID,
+// key, occurrence time, and headers.
+func TestToTaskFromTask_RoundTrip(t *testing.T) {
+	msg := newMessage(t)
+	task, err := messagetask.ToTask(msg, messagetask.WithQueue("events"))
+	if err != nil {
+		t.Fatalf("ToTask: %v", err)
+	}
+	if task.Type() != "orders.v1.OrderPlaced" || task.Queue() != "events" || task.Key() != "customer-1" {
+		t.Fatalf("task = %s/%s/%s, want orders.v1.OrderPlaced/events/customer-1", task.Type(), task.Queue(), task.Key())
+	}
+	if task.PayloadCodec() != taskqueue.ProtoCodec {
+		t.Fatalf("PayloadCodec = %q, want %q", task.PayloadCodec(), taskqueue.ProtoCodec)
+	}
+	if got := task.Headers()[messagetask.MessageIDHeader]; got != "message-1" {
+		t.Fatalf("message id header = %q, want message-1", got)
+	}
+
+	got, err := messagetask.FromTask(task, newResolver(t))
+	if err != nil {
+		t.Fatalf("FromTask: %v", err)
+	}
+	if got.ID() != msg.ID() || got.Kind() != msg.Kind() || got.Key() != msg.Key() || !got.OccurredAt().Equal(msg.OccurredAt()) {
+		t.Fatalf("message = %s/%s/%s/%v, want %s/%s/%s/%v", got.ID(), got.Kind(), got.Key(), got.OccurredAt(), msg.ID(), msg.Kind(), msg.Key(), msg.OccurredAt())
+	}
+	if !proto.Equal(got.Payload(), msg.Payload()) {
+		t.Fatalf("payload = %v, want %v", got.Payload(), msg.Payload())
+	}
+	if headers := got.Headers(); len(headers) != 1 || headers["trace"] != "abc" {
+		t.Fatalf("headers = %v, want only trace", headers)
+	}
+}
+
+// Tasks that cannot be turned back into a message should not be retried.
+func TestFromTask_UndecodableTasksSkipRetry(t *testing.T) {
+	resolver := newResolver(t)
+	unknown, err := taskqueue.NewProtoTask(taskqueue.Definition{Type: "orders.v1.Unknown"}, wrapperspb.String("x"))
+	if err != nil {
+		t.Fatalf("NewProtoTask: %v", err)
+	}
+	jsonTask, err := taskqueue.NewJSONTask(taskqueue.Definition{Type: messagetask.TaskType(orderPlaced)}, "x")
+	if err != nil {
+		t.Fatalf("NewJSONTask: %v", err)
+	}
+	badTime, err := taskqueue.NewProtoTask(taskqueue.Definition{Type: messagetask.TaskType(orderPlaced)}, wrapperspb.String("x"),
+		taskqueue.WithHeader(messagetask.OccurredAtHeader, "yesterday"))
+	if err != nil {
+		t.Fatalf("NewProtoTask: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		task taskqueue.Task
+		want error
+	}{
+		"unknown kind": {task: unknown, want: message.ErrUnknownKind},
+		"json payload": {task: jsonTask, want: messagetask.ErrUnsupportedCodec},
+		"bad time":     {task: badTime, want: messagetask.ErrInvalidOccurredAt},
+	} {
+		_, err := messagetask.FromTask(tc.task, resolver)
+		if !errors.Is(err, taskqueue.ErrSkipRetry) || !errors.Is(err, tc.want) {
+			t.Fatalf("%s: FromTask error = %v, want ErrSkipRetry and %v", name, err, tc.want)
+		}
+	}
+}
+
+// Encrypted tasks should convert back to messages when the keyring is passed
+// with WithDecodeOptions, both through FromTask and registered processors.
+func TestFromTask_DecodesEncryptedTasks(t *testing.T) {
+	keyring, err := taskqueue.NewStaticKeyring("k1", map[string][]byte{"k1": make([]byte, 32)})
+	if err != nil {
+		t.Fatalf("NewStaticKeyring: %v", err)
+	}
+	task, err := messagetask.ToTask(newMessage(t), messagetask.WithTaskOptions(taskqueue.WithEncryption(keyring)))
+	if err != nil {
+		t.Fatalf("ToTask: %v", err)
+	}
+	if _, err := messagetask.FromTask(task, newResolver(t)); !errors.Is(err, taskqueue.ErrNilKeyring) {
+		t.Fatalf("FromTask without keyring error = %v, want ErrNilKeyring", err)
+	}
+
+	decode := messagetask.WithDecodeOptions(taskqueue.WithDecryptionKeyring(keyring))
+	got, err := messagetask.FromTask(task, newResolver(t), decode)
+	if err != nil {
+		t.Fatalf("FromTask: %v", err)
+	}
+	if got.Payload().(*wrapperspb.StringValue).GetValue() != "order-1" {
+		t.Fatalf("payload = %v, want order-1", got.Payload())
+	}
+
+	handler := &recordingHandler{kinds: []message.Kind{orderPlaced}}
+	router := taskqueue.NewRouter()
+	if err := messagetask.Register(router, handler, newResolver(t), decode); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := router.Process(context.Background(), task); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if len(handler.messages) != 1 || handler.messages[0].ID() != "message-1" {
+		t.Fatalf("handled messages = %v, want message-1", handler.messages)
+	}
+}
+
+// Publisher should let message producers, such as an outbox relay, enqueue
+// messages as tasks with the configured enqueue policy.
+func TestPublisher_EnqueuesMessages(t *testing.T) {
+	recorder := tasktest.NewRecorder()
+	publisher, err := messagetask.NewPublisher(recorder, messagetask.WithEnqueueOptions(taskqueue.WithMaxRetry(5)))
+	if err != nil {
+		t.Fatalf("NewPublisher: %v", err)
+	}
+	if err := publisher.Publish(context.Background(), newMessage(t)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	enqueued := recorder.AssertEnqueued(t, messagetask.TaskType(orderPlaced))
+	tasktest.AssertOptions(t, enqueued, taskqueue.WithMaxRetry(5))
+	if _, err := messagetask.NewPublisher(nil); !errors.Is(err, taskqueue.ErrNilEnqueuer) {
+		t.Fatalf("NewPublisher(nil) error = %v, want ErrNilEnqueuer", err)
+	}
+}
+
+// Register should route tasks of every listened kind to the message handler,
+// falling back to the provider task ID when the task has no message ID.
+func TestRegister_HandlesTasksAsMessages(t *testing.T) {
+	handler := &recordingHandler{kinds: []message.Kind{orderPlaced, orderPlaced}}
+	router := taskqueue.NewRouter()
+	if err := messagetask.Register(router, handler, newResolver(t)); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	task, err := taskqueue.NewProtoTask(taskqueue.Definition{Type: messagetask.TaskType(orderPlaced)}, wrapperspb.String("order-2"))
+	if err != nil {
+		t.Fatalf("NewProtoTask: %v", err)
+	}
+	ctx := tasktest.ExecutionContext(context.Background(), taskqueue.WithExecutionTaskID("task-7"))
+	if err := router.Process(ctx, task); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if len(handler.messages) != 1 {
+		t.Fatalf("handled %d messages, want 1", len(handler.messages))
+	}
+	if got := handler.messages[0]; got.ID() != "task-7" || got.Payload().(*wrapperspb.StringValue).GetValue() != "order-2" {
+		t.Fatalf("message = %s/%v, want task-7/order-2", got.ID(), got.Payload())
+	}
+
+	handler.err = errors.New("downstream unavailable")
+	if err := router.Process(ctx, task); !errors.Is(err, handler.err) || errors.Is(err, taskqueue.ErrSkipRetry) {
+		t.Fatalf("Process error = %v, want retryable handler error", err)
+	}
+	if err := messagetask.Register(router, &recordingHandler{}, newResolver(t)); !errors.Is(err, message.ErrNoListening) {
+		t.Fatalf("Register error = %v, want ErrNoListening", err)
+	}
+}
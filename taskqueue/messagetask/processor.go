@@ -0,0 +1,87 @@
+package messagetask
+
+import (
+	"context"
+
+	"github.com/go-jimu/components/ddd/message"
+	"github.com/go-jimu/components/taskqueue"
+)
+
+type processor struct {
+	kind       message.Kind
+	handler    message.Handler
+	resolver   message.PayloadResolver
+	decodeOpts []taskqueue.DecodeOption
+}
+
+// NewProcessor constructs a Processor that rebuilds messages of kind with
+// FromTask and passes them to handler. A task without MessageIDHeader uses the
+// provider task ID as its message ID, so redeliveries keep the same ID. Only
+// WithDecodeOptions applies to processors.
+func NewProcessor(kind message.Kind, handler message.Handler, resolver message.PayloadResolver, opts ...Option) taskqueue.Processor {
+	return processor{kind: kind, handler: handler, resolver: resolver, decodeOpts: newConfig(opts).decodeOpts}
+}
+
+// NewProcessors constructs one Processor for each distinct kind handler
+// listens to.
+func NewProcessors(handler message.Handler, resolver message.PayloadResolver, opts ...Option) ([]taskqueue.Processor, error) {
+	if handler == nil {
+		return nil, message.ErrNilHandler
+	}
+	if resolver == nil {
+		return nil, ErrNilResolver
+	}
+	kinds := handler.Listening()
+	if len(kinds) == 0 {
+		return nil, message.ErrNoListening
+	}
+	seen := make(map[message.Kind]struct{}, len(kinds))
+	processors := make([]taskqueue.Processor, 0, len(kinds))
+	for _, kind := range kinds {
+		if kind == "" {
+			return nil, message.ErrEmptyKind
+		}
+		if _, ok := seen[kind]; ok {
+			continue
+		}
+		seen[kind] = struct{}{}
+		processors = append(processors, NewProcessor(kind, handler, resolver, opts...))
+	}
+	return processors, nil
+}
+
+// Register registers handler on registrar for every kind it listens to.
+func Register(registrar taskqueue.Registrar, handler message.Handler, resolver message.PayloadResolver, opts ...Option) error {
+	if registrar == nil {
+		return ErrNilRegistrar
+	}
+	processors, err := NewProcessors(handler, resolver, opts...)
+	if err != nil {
+		return err
+	}
+	for _, p := range processors {
+		if err := registrar.Register(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p processor) TaskType() taskqueue.TaskType {
+	return TaskType(p.kind)
+}
+
+func (p processor) Process(ctx context.Context, task taskqueue.Task) error {
+	if p.handler == nil {
+		return message.ErrNilHandler
+	}
+	var fallbackID string
+	if info, ok := taskqueue.ExecutionInfoFromContext(ctx); ok {
+		fallbackID = info.TaskID()
+	}
+	msg, err := fromTask(task, p.resolver, fallbackID, p.decodeOpts)
+	if err != nil {
+		return err
+	}
+	return p.handler.Handle(ctx, msg)
+}
@@ -0,0 +1,34 @@
+package messagetask
+
+import (
+	"context"
+
+	"github.com/go-jimu/components/ddd/message"
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// Publisher publishes messages by enqueuing them as tasks.
+type Publisher struct {
+	enqueuer taskqueue.Enqueuer
+	cfg      config
+}
+
+var _ message.Publisher = (*Publisher)(nil)
+
+// NewPublisher constructs a Publisher that enqueues converted messages through
+// enqueuer.
+func NewPublisher(enqueuer taskqueue.Enqueuer, opts ...Option) (*Publisher, error) {
+	if enqueuer == nil {
+		return nil, taskqueue.ErrNilEnqueuer
+	}
+	return &Publisher{enqueuer: enqueuer, cfg: newConfig(opts)}, nil
+}
+
+// Publish converts msg with ToTask and enqueues it.
+func (p *Publisher) Publish(ctx context.Context, msg message.Message) error {
+	task, err := toTask(msg, p.cfg)
+	if err != nil {
+		return err
+	}
+	return p.enqueuer.Enqueue(ctx, task, p.cfg.enqueueOpts...)
+}
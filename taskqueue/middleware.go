@@ -90,6 +90,9 @@ func appendExecutionInfoAttrs(ctx context.Context, attrs []any) []any {
 	if maxRetry, ok := info.MaxRetry(); ok {
 		attrs = append(attrs, "max_retry", maxRetry)
 	}
+	if priority, ok := info.Priority(); ok {
+		attrs = append(attrs, "priority", priority)
+	}
 	return attrs
 }
 
@@ -7,6 +7,16 @@ type taskConfig struct {
 	key          string
 	headers      map[string]string
 	payloadCodec string
+	compression  string
+	keyring      Keyring
+	encrypt      bool
+}
+
+func (cfg *taskConfig) setHeader(key, value string) {
+	if cfg.headers == nil {
+		cfg.headers = make(map[string]string)
+	}
+	cfg.headers[key] = value
 }
 
 // WithKey sets the task idempotency or ordering key.
@@ -19,10 +29,7 @@ func WithKey(key string) Option {
 // WithHeader adds one task metadata header.
 func WithHeader(key, value string) Option {
 	return func(cfg *taskConfig) {
-		if cfg.headers == nil {
-			cfg.headers = make(map[string]string)
-		}
-		cfg.headers[key] = value
+		cfg.setHeader(key, value)
 	}
 }
 
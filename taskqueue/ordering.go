@@ -0,0 +1,172 @@
+package taskqueue
+
+import (
+	"context"
+	"sync"
+)
+
+const defaultMaxOrderedKeys = 10000
+
+// KeyOrder serializes work that shares a key while work with different keys
+// runs concurrently. Holders of a key are served in arrival order.
+//
+// Memory is bounded: state is kept only for keys that are held or awaited,
+// and at most WithMaxOrderedKeys keys are tracked at once. Callers for further
+// keys wait for a slot in arrival order, so one busy key cannot starve others.
+type KeyOrder struct {
+	mu        sync.Mutex
+	maxKeys   int
+	keys      map[string][]*keyWaiter
+	admission []*keyWaiter
+}
+
+type keyWaiter struct {
+	key   string
+	ready chan struct{}
+}
+
+// KeyOrderOption configures a KeyOrder.
+type KeyOrderOption func(*KeyOrder)
+
+// WithMaxOrderedKeys limits how many distinct keys are tracked at once.
+func WithMaxOrderedKeys(limit int) KeyOrderOption {
+	return func(o *KeyOrder) {
+		if limit > 0 {
+			o.maxKeys = limit
+		}
+	}
+}
+
+// NewKeyOrder constructs an empty KeyOrder.
+func NewKeyOrder(opts ...KeyOrderOption) *KeyOrder {
+	o := &KeyOrder{
+		maxKeys: defaultMaxOrderedKeys,
+		keys:    make(map[string][]*keyWaiter),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// Acquire blocks until the caller holds key or ctx is done. The returned
+// release function hands key to the next waiter and is safe to call more
+// than once. An empty key is never serialized.
+func (o *KeyOrder) Acquire(ctx context.Context, key string) (func(), error) {
+	if key == "" {
+		return func() {}, nil
+	}
+	o.mu.Lock()
+	waiters, held := o.keys[key]
+	if !held && len(o.keys) < o.maxKeys && len(o.admission) == 0 {
+		o.keys[key] = nil
+		o.mu.Unlock()
+		return o.releaser(key), nil
+	}
+	w := &keyWaiter{key: key, ready: make(chan struct{})}
+	if held {
+		o.keys[key] = append(waiters, w)
+	} else {
+		o.admission = append(o.admission, w)
+	}
+	o.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return o.releaser(key), nil
+	case <-ctx.Done():
+	}
+	o.mu.Lock()
+	withdrawn := o.withdrawLocked(w)
+	o.mu.Unlock()
+	if !withdrawn {
+		// Ownership was handed over while giving up; pass it on.
+		o.release(key)
+	}
+	return nil, ctx.Err()
+}
+
+// Len reports how many keys are currently held.
+func (o *KeyOrder) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.keys)
+}
+
+func (o *KeyOrder) releaser(key string) func() {
+	var once sync.Once
+	return func() { once.Do(func() { o.release(key) }) }
+}
+
+func (o *KeyOrder) release(key string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if waiters := o.keys[key]; len(waiters) > 0 {
+		next := waiters[0]
+		waiters[0] = nil
+		o.keys[key] = waiters[1:]
+		close(next.ready)
+		return
+	}
+	delete(o.keys, key)
+	for len(o.keys) < o.maxKeys && len(o.admission) > 0 {
+		next := o.admission[0]
+		o.admission[0] = nil
+		o.admission = o.admission[1:]
+		if waiters, held := o.keys[next.key]; held {
+			o.keys[next.key] = append(waiters, next)
+			continue
+		}
+		o.keys[next.key] = nil
+		close(next.ready)
+	}
+}
+
+func (o *KeyOrder) withdrawLocked(w *keyWaiter) bool {
+	if removeWaiter(&o.admission, w) {
+		return true
+	}
+	waiters := o.keys[w.key]
+	if removeWaiter(&waiters, w) {
+		o.keys[w.key] = waiters
+		return true
+	}
+	return false
+}
+
+func removeWaiter(waiters *[]*keyWaiter, w *keyWaiter) bool {
+	for i, candidate := range *waiters {
+		if candidate == w {
+			*waiters = append((*waiters)[:i], (*waiters)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// OrderByKey serializes processing of tasks that share a non-empty Task.Key,
+// in the order they reach the middleware. Tasks with different keys, and
+// tasks without a key, run concurrently.
+//
+// As middleware it orders attempts, not enqueues: a failed task that is
+// retried later rejoins the end of its key's line, and a task waiting for its
+// key occupies a provider worker. In-process providers that support key
+// ordering natively avoid both; see memory.WithKeyOrdering.
+func OrderByKey(opts ...KeyOrderOption) Middleware {
+	order := NewKeyOrder(opts...)
+	return func(next ProcessorFunc) ProcessorFunc {
+		return func(ctx context.Context, task Task) error {
+			if next == nil {
+				return ErrNilProcessor
+			}
+			release, err := order.Acquire(ctx, task.Key())
+			if err != nil {
+				return err
+			}
+			defer release()
+			return next(ctx, task)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Intent: Tasks sharing a key should run one at a time in arrival order while
+// other keys proceed.
+func TestOrderByKeySerializesSameKey(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	started := make(chan string, 4)
+	unblock := make(chan struct{})
+	processor := Chain(func(_ context.Context, task Task) error {
+		started <- string(task.Payload())
+		if string(task.Payload()) == "a1" {
+			<-unblock
+		}
+		mu.Lock()
+		order = append(order, string(task.Payload()))
+		mu.Unlock()
+		return nil
+	}, OrderByKey())
+
+	var wg sync.WaitGroup
+	run := func(key, payload string) {
+		task, _ := New(Definition{Type: "account.event"}, []byte(payload), WithKey(key))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = processor(context.Background(), task)
+		}()
+	}
+	run("account-a", "a1")
+	if got := <-started; got != "a1" {
+		t.Fatalf("first started = %q, want a1", got)
+	}
+	run("account-a", "a2")
+	time.Sleep(20 * time.Millisecond) // let a2 queue behind a1
+	run("account-b", "b1")
+	if got := <-started; got != "b1" {
+		t.Fatalf("started = %q while account-a is held, want b1", got)
+	}
+	close(unblock)
+	wg.Wait()
+
+	if want := []string{"b1", "a1", "a2"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+}
+
+// Intent: With the key limit reached, new keys should wait for a free slot
+// instead of growing state without bound.
+func TestKeyOrderBoundsTrackedKeys(t *testing.T) {
+	order := NewKeyOrder(WithMaxOrderedKeys(1))
+	releaseA, err := order.Acquire(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("Acquire a: %v", err)
+	}
+
+	acquired := make(chan func(), 1)
+	go func() {
+		release, _ := order.Acquire(context.Background(), "b")
+		acquired <- release
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired b beyond the key limit")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if order.Len() != 1 {
+		t.Fatalf("tracked keys = %d, want 1", order.Len())
+	}
+
+	releaseA()
+	releaseA()
+	select {
+	case release := <-acquired:
+		release()
+	case <-time.After(time.Second):
+		t.Fatal("b was not admitted after a was released")
+	}
+	if order.Len() != 0 {
+		t.Fatalf("tracked keys = %d, want 0", order.Len())
+	}
+}
+
+// Intent: A waiter whose context ends should leave the line without blocking
+// the waiters behind it.
+func TestKeyOrderAcquireHonoursContext(t *testing.T) {
+	order := NewKeyOrder()
+	release, _ := order.Acquire(context.Background(), "a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := order.Acquire(ctx, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire error = %v, want deadline exceeded", err)
+	}
+	release()
+
+	next, err := order.Acquire(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("Acquire after release: %v", err)
+	}
+	next()
+	if order.Len() != 0 {
+		t.Fatalf("tracked keys = %d, want 0", order.Len())
+	}
+}
@@ -0,0 +1,90 @@
+package taskqueue
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// PriorityMode selects how workers that share capacity choose between queues
+// with ready tasks.
+type PriorityMode int
+
+const (
+	// PriorityWeighted picks a queue at random in proportion to its weight,
+	// so every queue keeps making progress.
+	PriorityWeighted PriorityMode = iota
+	// PriorityStrict always picks the queue with the highest weight; lower
+	// queues run only when higher ones have no ready task.
+	PriorityStrict
+)
+
+// QueuePriority is a provider-neutral priority model for queue lanes. The
+// zero value weighs every queue equally. It only decides between queues that
+// compete for shared workers; taskqueue/memory applies it when WithWorkers
+// bounds the shared pool.
+type QueuePriority struct {
+	Mode PriorityMode
+	// Weights maps queue names to positive weights. Queues not listed weigh
+	// 1.
+	Weights map[string]int
+}
+
+// WeightedPriority returns a PriorityWeighted model with weights.
+func WeightedPriority(weights map[string]int) QueuePriority {
+	return QueuePriority{Mode: PriorityWeighted, Weights: weights}
+}
+
+// StrictPriority returns a PriorityStrict model with weights as priority
+// levels.
+func StrictPriority(weights map[string]int) QueuePriority {
+	return QueuePriority{Mode: PriorityStrict, Weights: weights}
+}
+
+// Validate reports whether the mode is known and every weight is positive.
+func (p QueuePriority) Validate() error {
+	if p.Mode != PriorityWeighted && p.Mode != PriorityStrict {
+		return fmt.Errorf("%w: unknown mode %d", ErrInvalidQueuePriority, p.Mode)
+	}
+	for queue, weight := range p.Weights {
+		if weight < 1 {
+			return fmt.Errorf("%w: queue %q weight %d", ErrInvalidQueuePriority, queue, weight)
+		}
+	}
+	return nil
+}
+
+// Weight returns the weight of queue.
+func (p QueuePriority) Weight(queue string) int {
+	if weight, ok := p.Weights[queue]; ok && weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// Pick returns the index in queues of the queue to serve next, or -1 when
+// queues is empty. Strict ties go to the earlier queue.
+func (p QueuePriority) Pick(queues []string) int {
+	if len(queues) == 0 {
+		return -1
+	}
+	if p.Mode == PriorityStrict {
+		best := 0
+		for i := 1; i < len(queues); i++ {
+			if p.Weight(queues[i]) > p.Weight(queues[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	total := 0
+	for _, queue := range queues {
+		total += p.Weight(queue)
+	}
+	n := rand.IntN(total)
+	for i, queue := range queues {
+		if n -= p.Weight(queue); n < 0 {
+			return i
+		}
+	}
+	return len(queues) - 1
+}
@@ -0,0 +1,50 @@
+package taskqueue
+
+import (
+	"errors"
+	"testing"
+)
+
+// Intent: Strict priority should always serve the heaviest ready queue.
+func TestStrictPriorityPicksHeaviestQueue(t *testing.T) {
+	priority := StrictPriority(map[string]int{"critical": 10, "default": 5})
+	queues := []string{"bulk", "default", "critical"}
+	if i := priority.Pick(queues); queues[i] != "critical" {
+		t.Fatalf("Pick = %q, want critical", queues[i])
+	}
+	if priority.Pick(nil) != -1 {
+		t.Fatal("Pick of no queues is not -1")
+	}
+}
+
+// Intent: Weighted priority should serve queues in proportion to their weight
+// without starving light queues.
+func TestWeightedPrioritySharesByWeight(t *testing.T) {
+	priority := WeightedPriority(map[string]int{"critical": 9})
+	queues := []string{"bulk", "critical"}
+	counts := map[string]int{}
+	for range 10000 {
+		counts[queues[priority.Pick(queues)]]++
+	}
+	if counts["bulk"] < 700 || counts["bulk"] > 1300 {
+		t.Fatalf("counts = %v, want about 10%% bulk", counts)
+	}
+}
+
+// Intent: Invalid weights and modes should be rejected before a provider
+// uses them.
+func TestQueuePriorityValidate(t *testing.T) {
+	if err := (QueuePriority{}).Validate(); err != nil {
+		t.Fatalf("zero value Validate: %v", err)
+	}
+	if err := WeightedPriority(map[string]int{"bulk": 0}).Validate(); !errors.Is(err, ErrInvalidQueuePriority) {
+		t.Fatalf("zero weight error = %v, want ErrInvalidQueuePriority", err)
+	}
+	if err := (QueuePriority{Mode: 7}).Validate(); !errors.Is(err, ErrInvalidQueuePriority) {
+		t.Fatalf("unknown mode error = %v, want ErrInvalidQueuePriority", err)
+	}
+	info := NewExecutionInfo(WithExecutionPriority(10))
+	if priority, ok := info.Priority(); !ok || priority != 10 {
+		t.Fatalf("execution priority = %d, %t; want 10, true", priority, ok)
+	}
+}
@@ -0,0 +1,255 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const defaultQuotaRetryDelay = 100 * time.Millisecond
+
+// RetryLaterError asks the provider to reschedule a task after Delay without
+// counting the attempt as a failure: the retry count is unchanged, no attempt
+// is recorded, and the task is never dead-lettered because of it.
+type RetryLaterError struct {
+	Delay  time.Duration
+	Reason string
+}
+
+// RetryLater returns a RetryLaterError for delay.
+func RetryLater(delay time.Duration, reason string) error {
+	return &RetryLaterError{Delay: max(delay, 0), Reason: reason}
+}
+
+func (e *RetryLaterError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("retry task later after %s", e.Delay)
+	}
+	return fmt.Sprintf("retry task later after %s: %s", e.Delay, e.Reason)
+}
+
+// Is reports whether target is ErrRetryLater.
+func (e *RetryLaterError) Is(target error) bool {
+	return target == ErrRetryLater
+}
+
+// RetryLaterDelay returns the delay of the first RetryLaterError in err's
+// chain.
+func RetryLaterDelay(err error) (time.Duration, bool) {
+	var target *RetryLaterError
+	if !errors.As(err, &target) {
+		return 0, false
+	}
+	return target.Delay, true
+}
+
+// RateLimit configures a token bucket.
+type RateLimit struct {
+	// Rate is the number of tasks admitted per second.
+	Rate float64
+	// Burst is the bucket size. Values below 1 mean 1.
+	Burst int
+}
+
+// LimiterOption configures a Limiter.
+type LimiterOption func(*Limiter)
+
+// WithTypeRateLimit limits how often tasks of taskType start.
+func WithTypeRateLimit(taskType TaskType, limit RateLimit) LimiterOption {
+	return func(l *Limiter) {
+		if taskType != "" && limit.Rate > 0 {
+			l.typeRates[taskType] = newTokenBucket(limit)
+		}
+	}
+}
+
+// WithQueueRateLimit limits how often tasks of queue start.
+func WithQueueRateLimit(queue string, limit RateLimit) LimiterOption {
+	return func(l *Limiter) {
+		if limit.Rate > 0 {
+			l.queueRates[queue] = newTokenBucket(limit)
+		}
+	}
+}
+
+// WithTypeConcurrency limits how many tasks of taskType run at once.
+func WithTypeConcurrency(taskType TaskType, limit int) LimiterOption {
+	return func(l *Limiter) {
+		if taskType != "" && limit > 0 {
+			l.typeQuotas[taskType] = &concurrencyQuota{limit: limit}
+		}
+	}
+}
+
+// WithQueueConcurrency limits how many tasks of queue run at once.
+func WithQueueConcurrency(queue string, limit int) LimiterOption {
+	return func(l *Limiter) {
+		if limit > 0 {
+			l.queueQuotas[queue] = &concurrencyQuota{limit: limit}
+		}
+	}
+}
+
+// WithQuotaRetryDelay sets the delay requested when a concurrency quota is
+// exhausted. Rate limits request the time until their next token instead.
+func WithQuotaRetryDelay(delay time.Duration) LimiterOption {
+	return func(l *Limiter) {
+		if delay > 0 {
+			l.quotaRetryDelay = delay
+		}
+	}
+}
+
+// WithLimiterClock sets the time source used to refill token buckets.
+func WithLimiterClock(now func() time.Time) LimiterOption {
+	return func(l *Limiter) {
+		if now != nil {
+			l.now = now
+		}
+	}
+}
+
+// Limiter enforces token-bucket rate limits and concurrency quotas per
+// TaskType and per queue within one process. A task must satisfy every limit
+// that applies to it; when one is exhausted, nothing is consumed and a
+// RetryLaterError is returned.
+type Limiter struct {
+	mu              sync.Mutex
+	now             func() time.Time
+	quotaRetryDelay time.Duration
+	typeRates       map[TaskType]*tokenBucket
+	queueRates      map[string]*tokenBucket
+	typeQuotas      map[TaskType]*concurrencyQuota
+	queueQuotas     map[string]*concurrencyQuota
+}
+
+type tokenBucket struct {
+	rate     float64
+	burst    float64
+	tokens   float64
+	filledAt time.Time
+}
+
+type concurrencyQuota struct {
+	limit  int
+	active int
+}
+
+// NewLimiter constructs a Limiter. Without options it admits every task.
+func NewLimiter(opts ...LimiterOption) *Limiter {
+	l := &Limiter{
+		now:             time.Now,
+		quotaRetryDelay: defaultQuotaRetryDelay,
+		typeRates:       make(map[TaskType]*tokenBucket),
+		queueRates:      make(map[string]*tokenBucket),
+		typeQuotas:      make(map[TaskType]*concurrencyQuota),
+		queueQuotas:     make(map[string]*concurrencyQuota),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(l)
+		}
+	}
+	return l
+}
+
+// Acquire admits task on queue or returns a RetryLaterError. The release
+// function returns concurrency slots and must be called when the task ends.
+func (l *Limiter) Acquire(task Task, queue string) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var buckets []*tokenBucket
+	var quotas []*concurrencyQuota
+	if bucket := l.typeRates[task.Type()]; bucket != nil {
+		buckets = append(buckets, bucket)
+	}
+	if bucket := l.queueRates[queue]; bucket != nil {
+		buckets = append(buckets, bucket)
+	}
+	if quota := l.typeQuotas[task.Type()]; quota != nil {
+		quotas = append(quotas, quota)
+	}
+	if quota := l.queueQuotas[queue]; quota != nil {
+		quotas = append(quotas, quota)
+	}
+
+	var wait time.Duration
+	for _, bucket := range buckets {
+		wait = max(wait, bucket.wait(now))
+	}
+	for _, quota := range quotas {
+		if quota.active >= quota.limit {
+			wait = max(wait, l.quotaRetryDelay)
+		}
+	}
+	if wait > 0 {
+		return nil, RetryLater(wait, fmt.Sprintf("%s on queue %q is over its limit", task.Type(), queue))
+	}
+
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	for _, quota := range quotas {
+		quota.active++
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			for _, quota := range quotas {
+				quota.active--
+			}
+		})
+	}, nil
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	burst := float64(max(limit.Burst, 1))
+	return &tokenBucket{rate: limit.Rate, burst: burst, tokens: burst}
+}
+
+// wait refills the bucket and returns how long until one token is available.
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+	if !b.filledAt.IsZero() && now.After(b.filledAt) {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.filledAt).Seconds()*b.rate)
+	}
+	if b.filledAt.IsZero() || now.After(b.filledAt) {
+		b.filledAt = now
+	}
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// Limit enforces limiter around processing. Tasks over a limit are not
+// processed and return a RetryLaterError, which providers reschedule without
+// counting a failure. The queue is taken from ExecutionInfo when present, so
+// per-queue limits use provider lane names.
+func Limit(limiter *Limiter) Middleware {
+	return func(next ProcessorFunc) ProcessorFunc {
+		return func(ctx context.Context, task Task) error {
+			if next == nil {
+				return ErrNilProcessor
+			}
+			if limiter == nil {
+				return next(ctx, task)
+			}
+			queue := task.Queue()
+			if info, ok := ExecutionInfoFromContext(ctx); ok && info.Queue() != "" {
+				queue = info.Queue()
+			}
+			release, err := limiter.Acquire(task, queue)
+			if err != nil {
+				return err
+			}
+			defer release()
+			return next(ctx, task)
+		}
+	}
+}
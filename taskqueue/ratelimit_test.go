@@ -0,0 +1,85 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Intent: A token bucket should admit its burst, then ask for a retry after
+// the time until the next token.
+func TestLimiterRateLimitRefillsTokens(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := NewLimiter(
+		WithTypeRateLimit("api.call", RateLimit{Rate: 2, Burst: 2}),
+		WithLimiterClock(func() time.Time { return now }),
+	)
+	task, _ := New(Definition{Type: "api.call"}, nil)
+
+	for i := range 2 {
+		if _, err := limiter.Acquire(task, "default"); err != nil {
+			t.Fatalf("Acquire %d: %v", i, err)
+		}
+	}
+	_, err := limiter.Acquire(task, "default")
+	if delay, ok := RetryLaterDelay(err); !ok || delay != 500*time.Millisecond {
+		t.Fatalf("delay = %s, %t; want 500ms, true (err %v)", delay, ok, err)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if _, err := limiter.Acquire(task, "default"); err != nil {
+		t.Fatalf("Acquire after refill: %v", err)
+	}
+}
+
+// Intent: Concurrency quotas should hold slots until release, and a rejected
+// task should not consume tokens from other limits.
+func TestLimiterConcurrencyQuota(t *testing.T) {
+	limiter := NewLimiter(
+		WithQueueConcurrency("partners", 1),
+		WithTypeRateLimit("api.call", RateLimit{Rate: 0.001, Burst: 2}),
+		WithQuotaRetryDelay(time.Second),
+	)
+	task, _ := New(Definition{Type: "api.call"}, nil)
+
+	release, err := limiter.Acquire(task, "partners")
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	_, err = limiter.Acquire(task, "partners")
+	if delay, ok := RetryLaterDelay(err); !ok || delay != time.Second {
+		t.Fatalf("delay = %s, %t; want 1s, true", delay, ok)
+	}
+	release()
+	release()
+	if _, err := limiter.Acquire(task, "partners"); err != nil {
+		t.Fatalf("Acquire after release: %v", err)
+	}
+}
+
+// Intent: The middleware should use the provider lane for queue limits and
+// surface a retry-later error that retry handling leaves alone.
+func TestLimitMiddlewareReturnsRetryLater(t *testing.T) {
+	limiter := NewLimiter(WithQueueConcurrency("default", 1))
+	release, _ := limiter.Acquire(Task{}, "default")
+	defer release()
+
+	called := false
+	processor := Chain(func(context.Context, Task) error {
+		called = true
+		return nil
+	}, Retry(NewRetryPolicy(FixedBackoff(time.Minute))), Limit(limiter))
+	ctx := ContextWithExecutionInfo(context.Background(), NewExecutionInfo(WithExecutionQueue("default")))
+
+	err := processor(ctx, Task{})
+	if !errors.Is(err, ErrRetryLater) || called {
+		t.Fatalf("error = %v, called = %t; want ErrRetryLater without processing", err, called)
+	}
+	if _, ok := RetryAfterDelay(err); ok {
+		t.Fatal("retry-later error was converted into a retry-after failure")
+	}
+	if class := ClassifyError(err); class != ErrorClassRetryLater || class.String() != "retry_later" {
+		t.Fatalf("class = %s, want retry_later", class)
+	}
+}
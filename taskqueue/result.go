@@ -0,0 +1,222 @@
+package taskqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+const (
+	// TaskIDHeader carries the producer-side task ID used to key results.
+	TaskIDHeader = "x-task-id"
+	// RawResultCodec marks a result payload stored as raw bytes, because the
+	// processor returned []byte for a task without a payload codec.
+	RawResultCodec = "raw"
+
+	defaultResultTTL = 24 * time.Hour
+)
+
+// TaskResult is the recorded outcome of a task.
+type TaskResult struct {
+	TaskID   string
+	TaskType TaskType
+	// Payload is the result value encoded with PayloadCodec.
+	Payload      []byte
+	PayloadCodec string
+	// Error is the final error message of a failed task.
+	Error       string
+	CompletedAt time.Time
+	ExpiresAt   time.Time
+}
+
+// Err returns an error wrapping ErrTaskFailed when the task failed.
+func (r TaskResult) Err() error {
+	if r.Error == "" {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrTaskFailed, r.Error)
+}
+
+// Decode decodes the result payload into target. It returns Err when the
+// task failed.
+func (r TaskResult) Decode(target any) error {
+	if err := r.Err(); err != nil {
+		return err
+	}
+	if target == nil {
+		return ErrNilDecodeTarget
+	}
+	if len(r.Payload) == 0 {
+		return nil
+	}
+	if r.PayloadCodec == RawResultCodec {
+		data, ok := target.(*[]byte)
+		if !ok {
+			return fmt.Errorf("%w: raw result needs *[]byte, got %T", ErrInvalidResultTarget, target)
+		}
+		*data = append([]byte(nil), r.Payload...)
+		return nil
+	}
+	codec, err := lookupPayloadCodec(r.PayloadCodec)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(r.Payload, target)
+}
+
+// ResultStore records task results for producers.
+type ResultStore interface {
+	// Put stores result, replacing any earlier result for its task ID.
+	Put(ctx context.Context, result TaskResult) error
+	// Result returns the stored result or ErrResultNotFound.
+	Result(ctx context.Context, taskID string) (TaskResult, error)
+	// Await blocks until the result is stored or ctx is done.
+	Await(ctx context.Context, taskID string) (TaskResult, error)
+}
+
+// ResultProcessorFunc processes a task and returns its result value.
+type ResultProcessorFunc func(context.Context, Task) (any, error)
+
+// ResultOption configures a result processor.
+type ResultOption func(*resultProcessor)
+
+// WithResultTTL sets how long results are kept. The default is 24 hours.
+func WithResultTTL(ttl time.Duration) ResultOption {
+	return func(p *resultProcessor) {
+		if ttl > 0 {
+			p.ttl = ttl
+		}
+	}
+}
+
+// WithResultClock sets the time source for result timestamps.
+func WithResultClock(now func() time.Time) ResultOption {
+	return func(p *resultProcessor) {
+		if now != nil {
+			p.now = now
+		}
+	}
+}
+
+type resultProcessor struct {
+	taskType TaskType
+	store    ResultStore
+	fn       ResultProcessorFunc
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// NewResultProcessor constructs a Processor that stores the value returned by
+// fn in store. The value is encoded with the task payload codec, or JSON when
+// the task has none; []byte values are stored as is, under RawResultCodec
+// when the task has no payload codec. Final failures, as
+// reported by IsFinalFailure, are stored too so awaiting producers return.
+//
+// Results are keyed by the TaskIDHeader set by EnqueueForResult, falling back
+// to the provider task ID from ExecutionInfo.
+func NewResultProcessor(taskType TaskType, store ResultStore, fn ResultProcessorFunc, opts ...ResultOption) Processor {
+	p := &resultProcessor{taskType: taskType, store: store, fn: fn, ttl: defaultResultTTL, now: time.Now}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(p)
+		}
+	}
+	return p
+}
+
+func (p *resultProcessor) TaskType() TaskType {
+	return p.taskType
+}
+
+func (p *resultProcessor) Process(ctx context.Context, task Task) error {
+	if p.fn == nil {
+		return ErrNilProcessor
+	}
+	if p.store == nil {
+		return ErrNilResultStore
+	}
+	value, err := p.fn(ctx, task)
+	if err != nil && !IsFinalFailure(ctx, err) {
+		return err
+	}
+
+	now := p.now()
+	result := TaskResult{
+		TaskID:      resultTaskID(ctx, task),
+		TaskType:    task.Type(),
+		CompletedAt: now,
+		ExpiresAt:   now.Add(p.ttl),
+	}
+	if result.TaskID == "" {
+		return fmt.Errorf("%w: task has no %s header or provider task ID", ErrSkipRetry, TaskIDHeader)
+	}
+	if err == nil {
+		result.Payload, result.PayloadCodec, err = encodeResult(task, value)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if putErr := p.store.Put(ctx, result); putErr != nil {
+		return fmt.Errorf("store task result: %w", putErr)
+	}
+	return err
+}
+
+func resultTaskID(ctx context.Context, task Task) string {
+	if id := task.headers[TaskIDHeader]; id != "" {
+		return id
+	}
+	if info, ok := ExecutionInfoFromContext(ctx); ok {
+		return info.TaskID()
+	}
+	return ""
+}
+
+func encodeResult(task Task, value any) ([]byte, string, error) {
+	if data, ok := value.([]byte); ok {
+		if task.payloadCodec == "" {
+			return data, RawResultCodec, nil
+		}
+		return data, task.payloadCodec, nil
+	}
+	if value == nil {
+		return nil, "", nil
+	}
+	codecName := task.payloadCodec
+	if codecName == "" {
+		codecName = JSONCodec
+	}
+	codec, err := lookupPayloadCodec(codecName)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrSkipRetry, err)
+	}
+	data, err := marshalPayload(codec, value)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: encode task result with %q: %w", ErrSkipRetry, codec.Name(), err)
+	}
+	return data, codec.Name(), nil
+}
+
+// EnqueueForResult assigns task a new TaskIDHeader, enqueues it, and returns
+// the ID to pass to ResultStore.Await. A task that already carries the header
+// keeps it.
+func EnqueueForResult(ctx context.Context, enqueuer Enqueuer, task Task, opts ...EnqueueOption) (string, error) {
+	if enqueuer == nil {
+		return "", ErrNilEnqueuer
+	}
+	id := task.headers[TaskIDHeader]
+	if id == "" {
+		var b [16]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", fmt.Errorf("generate task id: %w", err)
+		}
+		id = hex.EncodeToString(b[:])
+		task = task.WithHeaders(map[string]string{TaskIDHeader: id})
+	}
+	if err := enqueuer.Enqueue(ctx, task, opts...); err != nil {
+		return "", err
+	}
+	return id, nil
+}
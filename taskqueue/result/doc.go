@@ -0,0 +1,12 @@
+// Package result provides taskqueue.ResultStore implementations.
+//
+// Processors built with taskqueue.NewResultProcessor store the value they
+// return, encoded with the task payload codec, in a ResultStore. Producers
+// enqueue with taskqueue.EnqueueForResult and read the outcome with Result, or
+// block on Await until the task has completed or failed for good.
+//
+// Every result carries an expiry set by taskqueue.WithResultTTL. MemoryStore
+// drops expired results as it is used and on Purge; it is non-durable and
+// shared only within one process, so producers and workers must share the
+// instance.
+package result
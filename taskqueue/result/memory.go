@@ -0,0 +1,174 @@
+package result
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// Option configures a MemoryStore.
+type Option func(*MemoryStore)
+
+// WithClock sets the time source used to expire results.
+func WithClock(now func() time.Time) Option {
+	return func(s *MemoryStore) {
+		if now != nil {
+			s.now = now
+		}
+	}
+}
+
+// MemoryStore is an in-process taskqueue.ResultStore.
+type MemoryStore struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	results map[string]taskqueue.TaskResult
+	expiry  expiryHeap
+	waiters map[string][]chan taskqueue.TaskResult
+}
+
+var _ taskqueue.ResultStore = (*MemoryStore)(nil)
+
+// NewMemoryStore constructs an empty MemoryStore.
+func NewMemoryStore(opts ...Option) *MemoryStore {
+	s := &MemoryStore{
+		now:     time.Now,
+		results: make(map[string]taskqueue.TaskResult),
+		waiters: make(map[string][]chan taskqueue.TaskResult),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+	return s
+}
+
+// Put stores result and wakes producers awaiting it.
+func (s *MemoryStore) Put(_ context.Context, result taskqueue.TaskResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purgeLocked()
+	result.Payload = append([]byte(nil), result.Payload...)
+	for _, waiter := range s.waiters[result.TaskID] {
+		waiter <- result
+	}
+	delete(s.waiters, result.TaskID)
+	if !result.ExpiresAt.IsZero() && !result.ExpiresAt.After(s.now()) {
+		return nil
+	}
+	s.results[result.TaskID] = result
+	if !result.ExpiresAt.IsZero() {
+		heap.Push(&s.expiry, expiryEntry{taskID: result.TaskID, expiresAt: result.ExpiresAt})
+	}
+	return nil
+}
+
+// Result returns the stored result for taskID.
+func (s *MemoryStore) Result(_ context.Context, taskID string) (taskqueue.TaskResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purgeLocked()
+	result, ok := s.results[taskID]
+	if !ok {
+		return taskqueue.TaskResult{}, taskqueue.ErrResultNotFound
+	}
+	return result, nil
+}
+
+// Await returns the result for taskID once it has been stored.
+func (s *MemoryStore) Await(ctx context.Context, taskID string) (taskqueue.TaskResult, error) {
+	s.mu.Lock()
+	s.purgeLocked()
+	if result, ok := s.results[taskID]; ok {
+		s.mu.Unlock()
+		return result, nil
+	}
+	waiter := make(chan taskqueue.TaskResult, 1)
+	s.waiters[taskID] = append(s.waiters[taskID], waiter)
+	s.mu.Unlock()
+
+	select {
+	case result := <-waiter:
+		return result, nil
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	waiters := s.waiters[taskID]
+	for i, candidate := range waiters {
+		if candidate == waiter {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(s.waiters, taskID)
+	} else {
+		s.waiters[taskID] = waiters
+	}
+	select {
+	case result := <-waiter:
+		return result, nil
+	default:
+		return taskqueue.TaskResult{}, ctx.Err()
+	}
+}
+
+// Purge removes expired results and reports how many were removed.
+func (s *MemoryStore) Purge() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.purgeLocked()
+}
+
+// Len reports how many results are stored, including expired ones not yet
+// purged.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.results)
+}
+
+func (s *MemoryStore) purgeLocked() int {
+	now := s.now()
+	purged := 0
+	for len(s.expiry) > 0 && !s.expiry[0].expiresAt.After(now) {
+		entry, _ := heap.Pop(&s.expiry).(expiryEntry)
+		// A newer Put may have replaced the result with a later expiry.
+		if result, ok := s.results[entry.taskID]; ok && result.ExpiresAt.Equal(entry.expiresAt) {
+			delete(s.results, entry.taskID)
+			purged++
+		}
+	}
+	return purged
+}
+
+type expiryEntry struct {
+	taskID    string
+	expiresAt time.Time
+}
+
+type expiryHeap []expiryEntry
+
+func (h expiryHeap) Len() int { return len(h) }
+
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+
+func (h expiryHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *expiryHeap) Push(x any) {
+	entry, _ := x.(expiryEntry)
+	*h = append(*h, entry)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	*h = old[:n-1]
+	return entry
+}
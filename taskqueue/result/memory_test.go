@@ -0,0 +1,85 @@
+package result_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+	"github.com/go-jimu/components/taskqueue/memory"
+	"github.com/go-jimu/components/taskqueue/result"
+)
+
+// A producer awaiting a task enqueued through a provider should receive the
+// value returned by the result processor.
+func TestMemoryStore_AwaitReceivesProcessorResult(t *testing.T) {
+	store := result.NewMemoryStore()
+	router := taskqueue.NewRouter()
+	if err := router.Register(taskqueue.NewResultProcessor("math.square", store, func(_ context.Context, task taskqueue.Task) (any, error) {
+		var n int
+		if err := taskqueue.DecodeJSON(task, &n); err != nil {
+			return nil, err
+		}
+		return n * n, nil
+	})); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	queue, err := memory.New(router)
+	if err != nil {
+		t.Fatalf("memory.New: %v", err)
+	}
+	if err := queue.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { _ = queue.Shutdown(context.Background()) })
+
+	task, _ := taskqueue.NewJSONTask(taskqueue.Definition{Type: "math.square"}, 7)
+	id, err := taskqueue.EnqueueForResult(context.Background(), queue, task)
+	if err != nil {
+		t.Fatalf("EnqueueForResult: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	res, err := store.Await(ctx, id)
+	if err != nil {
+		t.Fatalf("Await: %v", err)
+	}
+	var squared int
+	if err := res.Decode(&squared); err != nil || squared != 49 {
+		t.Fatalf("Decode = %d, %v; want 49", squared, err)
+	}
+}
+
+// Results should disappear once their TTL has passed.
+func TestMemoryStore_ExpiresResults(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := result.NewMemoryStore(result.WithClock(func() time.Time { return now }))
+	ctx := context.Background()
+	_ = store.Put(ctx, taskqueue.TaskResult{TaskID: "short", ExpiresAt: now.Add(time.Minute)})
+	_ = store.Put(ctx, taskqueue.TaskResult{TaskID: "long", ExpiresAt: now.Add(time.Hour)})
+	_ = store.Put(ctx, taskqueue.TaskResult{TaskID: "short", ExpiresAt: now.Add(2 * time.Minute)})
+
+	now = now.Add(90 * time.Second)
+	if _, err := store.Result(ctx, "short"); err != nil {
+		t.Fatalf("replaced result expired early: %v", err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := store.Result(ctx, "short"); !errors.Is(err, taskqueue.ErrResultNotFound) {
+		t.Fatalf("Result error = %v, want ErrResultNotFound", err)
+	}
+	now = now.Add(time.Hour)
+	if purged := store.Purge(); purged != 1 || store.Len() != 0 {
+		t.Fatalf("purged = %d, len = %d; want 1, 0", purged, store.Len())
+	}
+}
+
+// Await should give up when its context ends.
+func TestMemoryStore_AwaitHonoursContext(t *testing.T) {
+	store := result.NewMemoryStore()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := store.Await(ctx, "missing"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Await error = %v, want deadline exceeded", err)
+	}
+}
@@ -0,0 +1,119 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Intent: A result processor should encode the returned value with the task
+// codec and key it by the producer task ID.
+func TestResultProcessorStoresEncodedResult(t *testing.T) {
+	store := &recordingResultStore{}
+	now := time.Unix(100, 0)
+	processor := NewResultProcessor("report.render", store, func(context.Context, Task) (any, error) {
+		return map[string]int{"pages": 3}, nil
+	}, WithResultTTL(time.Minute), WithResultClock(func() time.Time { return now }))
+
+	var enqueued Task
+	id, err := EnqueueForResult(context.Background(), EnqueueFunc(func(_ context.Context, task Task, _ ...EnqueueOption) error {
+		enqueued = task
+		return nil
+	}), mustJSONTask(t, "report.render"))
+	if err != nil || id == "" || enqueued.Headers()[TaskIDHeader] != id {
+		t.Fatalf("EnqueueForResult = %q, %v; header %q", id, err, enqueued.Headers()[TaskIDHeader])
+	}
+	if err := processor.Process(context.Background(), enqueued); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	result := store.results[0]
+	if result.TaskID != id || result.PayloadCodec != JSONCodec || !result.ExpiresAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("result = %+v", result)
+	}
+	var decoded map[string]int
+	if err := result.Decode(&decoded); err != nil || decoded["pages"] != 3 {
+		t.Fatalf("Decode = %v, %v", decoded, err)
+	}
+}
+
+// Intent: A []byte result of a task without a payload codec should be stored
+// raw and decode back into a byte slice.
+func TestResultProcessorStoresRawBytes(t *testing.T) {
+	store := &recordingResultStore{}
+	processor := NewResultProcessor("report.render", store, func(context.Context, Task) (any, error) {
+		return []byte("%PDF"), nil
+	})
+	task, err := New(Definition{Type: "report.render"}, nil, WithHeaders(map[string]string{TaskIDHeader: "task-1"}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := processor.Process(context.Background(), task); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	result := store.results[0]
+	if result.PayloadCodec != RawResultCodec {
+		t.Fatalf("PayloadCodec = %q, want %q", result.PayloadCodec, RawResultCodec)
+	}
+	var decoded []byte
+	if err := result.Decode(&decoded); err != nil || string(decoded) != "%PDF" {
+		t.Fatalf("Decode = %q, %v", decoded, err)
+	}
+	if err := result.Decode(new(string)); !errors.Is(err, ErrInvalidResultTarget) {
+		t.Fatalf("Decode into string = %v, want ErrInvalidResultTarget", err)
+	}
+}
+
+// Intent: Only final failures should be stored, so producers keep waiting
+// while the provider retries.
+func TestResultProcessorStoresFinalFailureOnly(t *testing.T) {
+	store := &recordingResultStore{}
+	failure := errors.New("renderer crashed")
+	processor := NewResultProcessor("report.render", store, func(context.Context, Task) (any, error) {
+		return nil, failure
+	})
+	task := mustJSONTask(t, "report.render")
+	ctx := func(retryCount int) context.Context {
+		return ContextWithExecutionInfo(context.Background(), NewExecutionInfo(
+			WithExecutionTaskID("task-1"), WithExecutionRetryCount(retryCount), WithExecutionMaxRetry(1)))
+	}
+
+	if err := processor.Process(ctx(0), task); !errors.Is(err, failure) || len(store.results) != 0 {
+		t.Fatalf("first attempt = %v, stored %d", err, len(store.results))
+	}
+	if err := processor.Process(ctx(1), task); !errors.Is(err, failure) || len(store.results) != 1 {
+		t.Fatalf("final attempt = %v, stored %d", err, len(store.results))
+	}
+	result := store.results[0]
+	if result.TaskID != "task-1" || !errors.Is(result.Err(), ErrTaskFailed) || !errors.Is(result.Decode(new(any)), ErrTaskFailed) {
+		t.Fatalf("result = %+v", result)
+	}
+}
+
+type recordingResultStore struct {
+	results []TaskResult
+}
+
+func (s *recordingResultStore) Put(_ context.Context, result TaskResult) error {
+	s.results = append(s.results, result)
+	return nil
+}
+
+func (s *recordingResultStore) Result(context.Context, string) (TaskResult, error) {
+	return TaskResult{}, ErrResultNotFound
+}
+
+func (s *recordingResultStore) Await(context.Context, string) (TaskResult, error) {
+	return TaskResult{}, ErrResultNotFound
+}
+
+func mustJSONTask(t *testing.T, taskType TaskType) Task {
+	t.Helper()
+	task, err := NewJSONTask(Definition{Type: taskType}, struct{}{})
+	if err != nil {
+		t.Fatalf("NewJSONTask: %v", err)
+	}
+	return task
+}
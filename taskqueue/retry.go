@@ -0,0 +1,271 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy decides whether a failed processing attempt is retried and how
+// long the provider waits before the next attempt.
+//
+// Providers pass the task, the execution metadata of the failed attempt, and
+// the processor error. ErrSkipRetry and the provider retry cap still take
+// precedence over a policy that asks for another attempt.
+type RetryPolicy interface {
+	NextRetry(task Task, info ExecutionInfo, err error) RetryDecision
+}
+
+// RetryPolicyFunc adapts a function to RetryPolicy.
+type RetryPolicyFunc func(task Task, info ExecutionInfo, err error) RetryDecision
+
+// NextRetry calls f.
+func (f RetryPolicyFunc) NextRetry(task Task, info ExecutionInfo, err error) RetryDecision {
+	return f(task, info, err)
+}
+
+// RetryDecision is the outcome of a RetryPolicy.
+type RetryDecision struct {
+	Retry bool
+	Delay time.Duration
+}
+
+// ErrorClass groups processor errors for retry decisions.
+type ErrorClass int
+
+const (
+	// ErrorClassRetryable is an ordinary processor failure.
+	ErrorClassRetryable ErrorClass = iota
+	// ErrorClassSkipRetry is an error wrapping ErrSkipRetry.
+	ErrorClassSkipRetry
+	// ErrorClassPanic is an error wrapping ErrPanic.
+	ErrorClassPanic
+	// ErrorClassDeadline is an error wrapping context.DeadlineExceeded, such
+	// as an attempt that ran past its timeout.
+	ErrorClassDeadline
+	// ErrorClassRetryLater is a RetryLaterError, such as a rate-limited task.
+	ErrorClassRetryLater
+)
+
+// String returns a stable lowercase name suitable for logs and metric labels.
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassRetryable:
+		return "retryable"
+	case ErrorClassSkipRetry:
+		return "skip_retry"
+	case ErrorClassPanic:
+		return "panic"
+	case ErrorClassDeadline:
+		return "deadline"
+	case ErrorClassRetryLater:
+		return "retry_later"
+	default:
+		return "unknown"
+	}
+}
+
+// ClassifyError returns the retry class of err. ErrSkipRetry wins over the
+// other classes.
+func ClassifyError(err error) ErrorClass {
+	switch {
+	case errors.Is(err, ErrSkipRetry):
+		return ErrorClassSkipRetry
+	case errors.Is(err, ErrRetryLater):
+		return ErrorClassRetryLater
+	case errors.Is(err, ErrPanic):
+		return ErrorClassPanic
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassDeadline
+	default:
+		return ErrorClassRetryable
+	}
+}
+
+// Backoff returns the delay before the next attempt after retryCount prior
+// retries.
+type Backoff func(retryCount int) time.Duration
+
+// FixedBackoff waits delay before every retry.
+func FixedBackoff(delay time.Duration) Backoff {
+	if delay < 0 {
+		delay = 0
+	}
+	return func(int) time.Duration {
+		return delay
+	}
+}
+
+// ExponentialBackoff waits base, then doubles the delay for each prior retry
+// up to maxDelay. A non-positive maxDelay leaves the delay uncapped.
+func ExponentialBackoff(base, maxDelay time.Duration) Backoff {
+	if base < 0 {
+		base = 0
+	}
+	return func(retryCount int) time.Duration {
+		delay := base
+		for i := 0; i < retryCount && delay > 0; i++ {
+			if delay > math.MaxInt64/2 || (maxDelay > 0 && delay >= maxDelay) {
+				break
+			}
+			delay *= 2
+		}
+		if maxDelay > 0 && delay > maxDelay {
+			return maxDelay
+		}
+		return delay
+	}
+}
+
+// JitterBackoff randomizes backoff delays downward by up to fraction of the
+// delay, so a fraction of 0.5 yields delays in [d/2, d]. Fraction is clamped
+// to [0, 1].
+func JitterBackoff(backoff Backoff, fraction float64) Backoff {
+	fraction = min(max(fraction, 0), 1)
+	return func(retryCount int) time.Duration {
+		if backoff == nil {
+			return 0
+		}
+		delay := backoff(retryCount)
+		spread := int64(float64(delay) * fraction)
+		if spread <= 0 {
+			return delay
+		}
+		return delay - time.Duration(rand.Int64N(spread+1))
+	}
+}
+
+// RetryPolicyOption configures the policy returned by NewRetryPolicy.
+type RetryPolicyOption func(*backoffPolicy)
+
+// WithNonRetryableClasses stops retries for errors in classes. Panics and
+// deadline errors are retried by default; ErrSkipRetry never is.
+func WithNonRetryableClasses(classes ...ErrorClass) RetryPolicyOption {
+	return func(p *backoffPolicy) {
+		for _, class := range classes {
+			p.skip[class] = true
+		}
+	}
+}
+
+type backoffPolicy struct {
+	backoff Backoff
+	skip    map[ErrorClass]bool
+}
+
+// NewRetryPolicy returns a policy that retries failures up to the execution
+// max retry, waiting backoff between attempts. A RetryAfterError delay from
+// the processor replaces the backoff delay. A nil backoff retries immediately.
+func NewRetryPolicy(backoff Backoff, opts ...RetryPolicyOption) RetryPolicy {
+	if backoff == nil {
+		backoff = FixedBackoff(0)
+	}
+	p := &backoffPolicy{backoff: backoff, skip: make(map[ErrorClass]bool)}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(p)
+		}
+	}
+	return p
+}
+
+func (p *backoffPolicy) NextRetry(_ Task, info ExecutionInfo, err error) RetryDecision {
+	if err == nil {
+		return RetryDecision{}
+	}
+	class := ClassifyError(err)
+	if class == ErrorClassSkipRetry || p.skip[class] {
+		return RetryDecision{}
+	}
+	retryCount, _ := info.RetryCount()
+	if maxRetry, ok := info.MaxRetry(); ok && retryCount >= maxRetry {
+		return RetryDecision{}
+	}
+	if delay, ok := RetryAfterDelay(err); ok {
+		return RetryDecision{Retry: true, Delay: delay}
+	}
+	return RetryDecision{Retry: true, Delay: max(p.backoff(retryCount), 0)}
+}
+
+// RetryAfterError carries a processor hint to retry the task after Delay.
+type RetryAfterError struct {
+	Delay time.Duration
+	Err   error
+}
+
+// RetryAfter wraps err with a hint to retry the task after delay.
+func RetryAfter(delay time.Duration, err error) error {
+	return &RetryAfterError{Delay: max(delay, 0), Err: err}
+}
+
+func (e *RetryAfterError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("retry task after %s", e.Delay)
+	}
+	return fmt.Sprintf("retry task after %s: %v", e.Delay, e.Err)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfterDelay returns the delay of the first RetryAfterError in err's
+// chain.
+func RetryAfterDelay(err error) (time.Duration, bool) {
+	var target *RetryAfterError
+	if !errors.As(err, &target) {
+		return 0, false
+	}
+	return target.Delay, true
+}
+
+// Retry applies policy to processor errors for providers that only understand
+// ErrSkipRetry and RetryAfterError. Errors the policy does not retry are
+// wrapped with ErrSkipRetry; retried errors carry the policy delay.
+// RetryLaterError passes through unchanged.
+func Retry(policy RetryPolicy) Middleware {
+	return func(next ProcessorFunc) ProcessorFunc {
+		return func(ctx context.Context, task Task) error {
+			if next == nil {
+				return ErrNilProcessor
+			}
+			err := next(ctx, task)
+			if err == nil || policy == nil || errors.Is(err, ErrRetryLater) {
+				return err
+			}
+			info, _ := ExecutionInfoFromContext(ctx)
+			decision := policy.NextRetry(task, info, err)
+			if !decision.Retry {
+				if errors.Is(err, ErrSkipRetry) {
+					return err
+				}
+				return fmt.Errorf("%w: %w", ErrSkipRetry, err)
+			}
+			return &RetryAfterError{Delay: decision.Delay, Err: err}
+		}
+	}
+}
+
+// IsFinalFailure reports whether a provider will stop retrying after err: it
+// wraps ErrSkipRetry, or the ExecutionInfo in ctx shows the retry budget is
+// spent. RetryLaterError is never final, and without retry metadata every
+// failure is. A custom provider RetryPolicy that stops earlier is not visible
+// here; install Retry inside middleware that relies on this.
+func IsFinalFailure(ctx context.Context, err error) bool {
+	if err == nil || errors.Is(err, ErrRetryLater) {
+		return false
+	}
+	if errors.Is(err, ErrSkipRetry) {
+		return true
+	}
+	info, ok := ExecutionInfoFromContext(ctx)
+	if !ok {
+		return true
+	}
+	retryCount, hasRetryCount := info.RetryCount()
+	maxRetry, hasMaxRetry := info.MaxRetry()
+	return !hasRetryCount || !hasMaxRetry || retryCount >= maxRetry
+}
@@ -0,0 +1,129 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Intent: Built-in backoffs should produce fixed, doubling-and-capped, and
+// bounded jittered delays so providers can share one retry vocabulary.
+func TestBackoffDelays(t *testing.T) {
+	fixed := FixedBackoff(time.Second)
+	if got := fixed(5); got != time.Second {
+		t.Fatalf("fixed delay = %s, want 1s", got)
+	}
+
+	exponential := ExponentialBackoff(100*time.Millisecond, time.Second)
+	for retryCount, want := range []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second,
+	} {
+		if got := exponential(retryCount); got != want {
+			t.Fatalf("exponential delay(%d) = %s, want %s", retryCount, got, want)
+		}
+	}
+	if got := ExponentialBackoff(time.Hour, 0)(200); got <= 0 {
+		t.Fatalf("uncapped exponential delay overflowed to %s", got)
+	}
+
+	jittered := JitterBackoff(fixed, 0.5)
+	for i := 0; i < 100; i++ {
+		if got := jittered(0); got < 500*time.Millisecond || got > time.Second {
+			t.Fatalf("jittered delay = %s, want within [500ms, 1s]", got)
+		}
+	}
+}
+
+// Intent: ErrSkipRetry, panics, and deadline errors should be classified
+// distinctly, with ErrSkipRetry taking precedence over the other classes.
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{err: errors.New("smtp unavailable"), want: ErrorClassRetryable},
+		{err: fmt.Errorf("wrapped: %w", ErrSkipRetry), want: ErrorClassSkipRetry},
+		{err: panicAsError("boom"), want: ErrorClassPanic},
+		{err: context.DeadlineExceeded, want: ErrorClassDeadline},
+		{err: fmt.Errorf("%w: %w", ErrSkipRetry, context.DeadlineExceeded), want: ErrorClassSkipRetry},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Fatalf("ClassifyError(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+// Intent: The default policy should stop at the execution max retry, honour
+// non-retryable classes, and prefer a processor retry-after hint over backoff.
+func TestNewRetryPolicyDecisions(t *testing.T) {
+	policy := NewRetryPolicy(ExponentialBackoff(time.Second, time.Minute), WithNonRetryableClasses(ErrorClassPanic))
+	attempt := func(retryCount int) ExecutionInfo {
+		return NewExecutionInfo(WithExecutionRetryCount(retryCount), WithExecutionMaxRetry(3))
+	}
+	failure := errors.New("smtp unavailable")
+
+	tests := []struct {
+		name string
+		info ExecutionInfo
+		err  error
+		want RetryDecision
+	}{
+		{name: "backoff", info: attempt(2), err: failure, want: RetryDecision{Retry: true, Delay: 4 * time.Second}},
+		{name: "exhausted", info: attempt(3), err: failure},
+		{name: "skip retry", info: attempt(0), err: ErrSkipRetry},
+		{name: "non-retryable class", info: attempt(0), err: panicAsError("boom")},
+		{name: "deadline", info: attempt(0), err: context.DeadlineExceeded, want: RetryDecision{Retry: true, Delay: time.Second}},
+		{name: "retry after", info: attempt(1), err: RetryAfter(time.Hour, failure), want: RetryDecision{Retry: true, Delay: time.Hour}},
+		{name: "no max retry", info: NewExecutionInfo(), err: failure, want: RetryDecision{Retry: true, Delay: time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.NextRetry(Task{}, tt.info, tt.err); got != tt.want {
+				t.Fatalf("decision = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Intent: RetryAfter should stay transparent to errors.Is on the wrapped cause
+// while exposing its delay through RetryAfterDelay.
+func TestRetryAfterWrapsCause(t *testing.T) {
+	cause := errors.New("rate limited")
+	err := fmt.Errorf("call upstream: %w", RetryAfter(30*time.Second, cause))
+
+	if !errors.Is(err, cause) {
+		t.Fatalf("errors.Is(%v, cause) = false", err)
+	}
+	delay, ok := RetryAfterDelay(err)
+	if !ok || delay != 30*time.Second {
+		t.Fatalf("delay = %s, %t; want 30s, true", delay, ok)
+	}
+	if _, ok := RetryAfterDelay(cause); ok {
+		t.Fatal("plain error reported a retry-after delay")
+	}
+}
+
+// Intent: Retry middleware should translate policy decisions into ErrSkipRetry
+// and RetryAfterError for providers without native RetryPolicy support.
+func TestRetryMiddlewareAnnotatesErrors(t *testing.T) {
+	failure := errors.New("smtp unavailable")
+	processor := Chain(func(context.Context, Task) error { return failure }, Retry(NewRetryPolicy(FixedBackoff(time.Minute))))
+
+	retryCtx := ContextWithExecutionInfo(context.Background(), NewExecutionInfo(WithExecutionRetryCount(0), WithExecutionMaxRetry(1)))
+	err := processor(retryCtx, Task{})
+	if delay, ok := RetryAfterDelay(err); !ok || delay != time.Minute {
+		t.Fatalf("retry delay = %s, %t; want 1m, true", delay, ok)
+	}
+	if !errors.Is(err, failure) {
+		t.Fatalf("error = %v, want wrapped failure", err)
+	}
+
+	lastCtx := ContextWithExecutionInfo(context.Background(), NewExecutionInfo(WithExecutionRetryCount(1), WithExecutionMaxRetry(1)))
+	err = processor(lastCtx, Task{})
+	if !errors.Is(err, ErrSkipRetry) || !errors.Is(err, failure) {
+		t.Fatalf("error = %v, want ErrSkipRetry wrapping failure", err)
+	}
+}
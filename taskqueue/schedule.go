@@ -1,9 +1,6 @@
 package taskqueue
 
-import (
-	"strings"
-	"time"
-)
+import "time"
 
 // ScheduleKind identifies the provider-neutral form of a periodic schedule.
 type ScheduleKind string
@@ -39,11 +36,11 @@ func WithLocation(location string) ScheduleOption {
 	}
 }
 
-// CronSchedule constructs a validated standard five-field cron schedule shape.
+// CronSchedule constructs a validated standard five-field cron schedule.
 //
-// Validation is intentionally limited to the portable five-field shape and
-// optional IANA location. Provider adapters remain responsible for cron dialect
-// details such as value ranges and named fields.
+// The spec is parsed with ParseCron, so field ranges, steps, lists, and named
+// months and days are checked at construction time. Seconds fields and
+// descriptors such as @daily are rejected.
 func CronSchedule(spec string, opts ...ScheduleOption) (Schedule, error) {
 	cfg := newScheduleConfig(opts...)
 	schedule := Schedule{
@@ -129,14 +126,35 @@ func (s Schedule) Validate() error {
 }
 
 func validateCronSpec(spec string) error {
-	fields := strings.Fields(spec)
-	if len(fields) == 0 {
-		return ErrEmptySchedule
+	_, err := ParseCron(spec)
+	return err
+}
+
+// Next returns the first fire time strictly after after.
+//
+// Cron schedules are evaluated in Location when set and in after's location
+// otherwise; the result is expressed in that evaluation location. Interval
+// schedules fire every Interval after after. Next returns the zero time when a
+// cron schedule has no fire time within five years.
+func (s Schedule) Next(after time.Time) (time.Time, error) {
+	if err := s.Validate(); err != nil {
+		return time.Time{}, err
 	}
-	if len(fields) != 5 {
-		return ErrInvalidSchedule
+	if s.kind == ScheduleKindInterval {
+		return after.Add(s.interval), nil
 	}
-	return nil
+	expr, err := ParseCron(s.spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if s.location != "" {
+		loc, err := time.LoadLocation(s.location)
+		if err != nil {
+			return time.Time{}, ErrInvalidScheduleLocation
+		}
+		after = after.In(loc)
+	}
+	return expr.Next(after), nil
 }
 
 func validateScheduleLocation(location string) error {
@@ -0,0 +1,19 @@
+// Package scheduler provides an in-process periodic task scheduler.
+//
+// Scheduler implements taskqueue.PeriodicTaskScheduler and taskqueue.Runner.
+// It computes fire times with taskqueue.Schedule.Next and, on each fire,
+// enqueues the registered PeriodicTask envelope with its stored enqueue policy
+// through any taskqueue.Enqueuer. Cron schedules are evaluated in their
+// configured location, so daylight saving transitions follow
+// taskqueue.CronExpression.Next semantics.
+//
+// The scheduler owns no distributed leadership: every running instance fires
+// every registered task. Use taskqueue.WithUnique in the periodic policy, or
+// run a single instance, when replicas must not enqueue duplicates.
+//
+// Fire times that pass while the scheduler cannot run, for example because the
+// process was suspended, are handled by the MissedFirePolicy. Registration is
+// allowed both before Start and while running; a task registered while running
+// first fires at its next fire time after registration. Time is read through
+// a Clock so tests can drive schedules deterministically.
+package scheduler
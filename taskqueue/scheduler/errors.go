@@ -0,0 +1,9 @@
+package scheduler
+
+import "errors"
+
+var (
+	ErrNilEnqueuer    = errors.New("scheduler enqueuer is nil")
+	ErrClosed         = errors.New("scheduler is closed")
+	ErrAlreadyStarted = errors.New("scheduler is already started")
+)
@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Clock supplies the current time and timers to a Scheduler.
+type Clock interface {
+	Now() time.Time
+	After(time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// MissedFirePolicy decides what happens to fire times that passed before the
+// scheduler observed them.
+type MissedFirePolicy int
+
+const (
+	// CoalesceMissedFires enqueues once for all fire times that are due when
+	// the scheduler wakes, then resumes from the next future fire time.
+	CoalesceMissedFires MissedFirePolicy = iota
+	// SkipMissedFires enqueues only when exactly one fire time is due and
+	// drops the fire when later fire times have also passed.
+	SkipMissedFires
+)
+
+// Option configures a Scheduler during construction.
+type Option func(*Scheduler)
+
+// WithClock sets the time source and timer factory.
+func WithClock(clock Clock) Option {
+	return func(s *Scheduler) {
+		if clock != nil {
+			s.clock = clock
+		}
+	}
+}
+
+// WithMissedFirePolicy sets how missed fire times are handled. The default is
+// CoalesceMissedFires.
+func WithMissedFirePolicy(policy MissedFirePolicy) Option {
+	return func(s *Scheduler) {
+		s.missed = policy
+	}
+}
+
+// WithLogger sets the logger for enqueue failures and skipped fires.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Scheduler) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// Scheduler enqueues registered periodic tasks on their schedules.
+type Scheduler struct {
+	enqueuer taskqueue.Enqueuer
+	clock    Clock
+	missed   MissedFirePolicy
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	entries  map[string]*entry
+	started  bool
+	closed   bool
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopped  chan struct{}
+	rootCtx  context.Context
+	cancel   context.CancelFunc
+}
+
+var (
+	_ taskqueue.PeriodicTaskScheduler = (*Scheduler)(nil)
+	_ taskqueue.Runner                = (*Scheduler)(nil)
+)
+
+type entry struct {
+	periodic taskqueue.PeriodicTask
+	next     time.Time
+}
+
+type fire struct {
+	periodic taskqueue.PeriodicTask
+	at       time.Time
+}
+
+// New creates a scheduler that enqueues periodic tasks through enqueuer.
+func New(enqueuer taskqueue.Enqueuer, opts ...Option) (*Scheduler, error) {
+	if enqueuer == nil {
+		return nil, ErrNilEnqueuer
+	}
+	rootCtx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		enqueuer: enqueuer,
+		clock:    systemClock{},
+		logger:   slog.Default(),
+		entries:  make(map[string]*entry),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		rootCtx:  rootCtx,
+		cancel:   cancel,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+	return s, nil
+}
+
+// RegisterPeriodicTask registers periodic under its unique name.
+func (s *Scheduler) RegisterPeriodicTask(periodic taskqueue.PeriodicTask) error {
+	if err := periodic.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if _, ok := s.entries[periodic.Name()]; ok {
+		return taskqueue.ErrDuplicatePeriodicTask
+	}
+	e := &entry{periodic: periodic}
+	if s.started {
+		next, err := periodic.Schedule().Next(s.clock.Now())
+		if err != nil {
+			return err
+		}
+		e.next = next
+	}
+	s.entries[periodic.Name()] = e
+	s.signal()
+	return nil
+}
+
+// Start computes the first fire time of every registered task and begins
+// firing them in a background goroutine.
+func (s *Scheduler) Start(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if s.started {
+		return ErrAlreadyStarted
+	}
+	now := s.clock.Now()
+	for _, e := range s.entries {
+		next, err := e.periodic.Schedule().Next(now)
+		if err != nil {
+			return err
+		}
+		e.next = next
+	}
+	s.started = true
+	go s.loop()
+	return nil
+}
+
+// Shutdown stops firing and waits for an in-progress fire round to finish
+// until ctx is done, then cancels outstanding enqueue calls.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	started := s.started
+	s.mu.Unlock()
+	defer s.cancel()
+	if !started {
+		return nil
+	}
+
+	s.stopOnce.Do(func() { close(s.stop) })
+	select {
+	case <-s.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run starts the scheduler, blocks until ctx is done, and then shuts down.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if err := s.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return s.Shutdown(context.WithoutCancel(ctx))
+}
+
+func (s *Scheduler) loop() {
+	defer close(s.stopped)
+	for {
+		for _, f := range s.due() {
+			s.enqueue(f)
+		}
+		var timeout <-chan time.Time
+		if wait, ok := s.untilNext(); ok {
+			timeout = s.clock.After(wait)
+		}
+		select {
+		case <-s.stop:
+			return
+		case <-s.wake:
+		case <-timeout:
+		}
+	}
+}
+
+// due advances every entry whose fire time has passed and returns the fires
+// to enqueue according to the missed fire policy, ordered by task name.
+func (s *Scheduler) due() []fire {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var fires []fire
+	for _, name := range names {
+		e := s.entries[name]
+		if e.next.IsZero() || e.next.After(now) {
+			continue
+		}
+		last := e.next
+		missed := 0
+		for {
+			next, err := e.periodic.Schedule().Next(e.next)
+			if err != nil || next.IsZero() {
+				e.next = time.Time{}
+				break
+			}
+			e.next = next
+			if next.After(now) {
+				break
+			}
+			last = next
+			missed++
+		}
+		if missed > 0 && s.missed == SkipMissedFires {
+			s.logger.Warn("taskqueue periodic fire skipped",
+				slog.String("periodic_task", name), slog.Int("missed", missed+1))
+			continue
+		}
+		fires = append(fires, fire{periodic: e.periodic, at: last})
+	}
+	return fires
+}
+
+func (s *Scheduler) untilNext() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var earliest time.Time
+	for _, e := range s.entries {
+		if e.next.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || e.next.Before(earliest) {
+			earliest = e.next
+		}
+	}
+	if earliest.IsZero() {
+		return 0, false
+	}
+	return earliest.Sub(s.clock.Now()), true
+}
+
+func (s *Scheduler) enqueue(f fire) {
+	err := s.enqueuer.Enqueue(s.rootCtx, f.periodic.Task(), taskqueue.WithEnqueueOptions(f.periodic.EnqueuePolicy()))
+	if err != nil {
+		s.logger.Error("taskqueue periodic enqueue failed",
+			slog.String("periodic_task", f.periodic.Name()),
+			slog.String("task_type", string(f.periodic.Task().Type())),
+			slog.Time("fire_time", f.at),
+			slog.Any("error", err))
+	}
+}
+
+func (s *Scheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,277 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+	"github.com/go-jimu/components/taskqueue/scheduler"
+)
+
+const waitTimeout = 2 * time.Second
+
+// Periodic tasks should be enqueued at each fire time with their stored
+// enqueue policy.
+func TestScheduler_EnqueuesOnScheduleWithPolicy(t *testing.T) {
+	clock := newFakeClock(time.Date(2026, 5, 29, 10, 7, 0, 0, time.UTC))
+	enqueuer := &recordingEnqueuer{}
+	s := newScheduler(t, enqueuer, scheduler.WithClock(clock))
+	mustRegister(t, s, "reports.quarter_hour", "*/15 * * * *", taskqueue.WithMaxRetry(1), taskqueue.WithUnique(time.Minute))
+	start(t, s, clock)
+
+	clock.Advance(8 * time.Minute)
+	clock.awaitWaiter(t)
+	clock.Advance(15 * time.Minute)
+	clock.awaitWaiter(t)
+
+	calls := enqueuer.calls()
+	if len(calls) != 2 {
+		t.Fatalf("enqueued %d tasks, want 2", len(calls))
+	}
+	for _, call := range calls {
+		if call.task.Type() != "reports.quarter_hour" {
+			t.Fatalf("task type = %q, want reports.quarter_hour", call.task.Type())
+		}
+		if maxRetry, ok := call.opts.MaxRetry(); !ok || maxRetry != 1 {
+			t.Fatalf("max retry = %d, %t; want 1, true", maxRetry, ok)
+		}
+		if call.opts.UniqueTTL() != time.Minute {
+			t.Fatalf("unique ttl = %s, want 1m", call.opts.UniqueTTL())
+		}
+	}
+}
+
+// Periodic task names should be unique per scheduler.
+func TestScheduler_RejectsDuplicateName(t *testing.T) {
+	s := newScheduler(t, &recordingEnqueuer{})
+	mustRegister(t, s, "reports.hourly", "0 * * * *")
+
+	periodic := newPeriodic(t, "reports.hourly", "30 * * * *")
+	if err := s.RegisterPeriodicTask(periodic); !errors.Is(err, taskqueue.ErrDuplicatePeriodicTask) {
+		t.Fatalf("error = %v, want ErrDuplicatePeriodicTask", err)
+	}
+}
+
+// Fire times missed while the scheduler could not run should be coalesced into
+// a single enqueue by default, or dropped with SkipMissedFires.
+func TestScheduler_MissedFirePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy scheduler.MissedFirePolicy
+		want   int
+	}{
+		{name: "coalesce", policy: scheduler.CoalesceMissedFires, want: 1},
+		{name: "skip", policy: scheduler.SkipMissedFires, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock(time.Date(2026, 5, 29, 10, 30, 0, 0, time.UTC))
+			enqueuer := &recordingEnqueuer{}
+			s := newScheduler(t, enqueuer, scheduler.WithClock(clock), scheduler.WithMissedFirePolicy(tt.policy))
+			mustRegister(t, s, "reports.hourly", "0 * * * *")
+			start(t, s, clock)
+
+			clock.Advance(3 * time.Hour)
+			clock.awaitWaiter(t)
+
+			if got := len(enqueuer.calls()); got != tt.want {
+				t.Fatalf("enqueued %d tasks, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// Tasks registered while running should first fire at their next fire time
+// after registration.
+func TestScheduler_RegisterWhileRunning(t *testing.T) {
+	clock := newFakeClock(time.Date(2026, 5, 29, 10, 30, 0, 0, time.UTC))
+	enqueuer := &recordingEnqueuer{}
+	s := newScheduler(t, enqueuer, scheduler.WithClock(clock))
+	mustRegister(t, s, "reports.daily", "0 0 * * *")
+	start(t, s, clock)
+
+	mustRegister(t, s, "reports.hourly", "0 * * * *")
+	clock.awaitWaiterUntil(t, time.Date(2026, 5, 29, 11, 0, 0, 0, time.UTC))
+	clock.Advance(30 * time.Minute)
+	clock.awaitWaiter(t)
+
+	if got := len(enqueuer.calls()); got != 1 {
+		t.Fatalf("enqueued %d tasks, want 1", got)
+	}
+}
+
+// Run should block until its context is cancelled and then stop cleanly.
+func TestScheduler_RunStopsOnContextCancel(t *testing.T) {
+	s := newScheduler(t, &recordingEnqueuer{})
+	mustRegister(t, s, "reports.hourly", "0 * * * *")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(waitTimeout):
+		t.Fatal("Run did not return")
+	}
+	if err := s.RegisterPeriodicTask(newPeriodic(t, "reports.daily", "0 0 * * *")); !errors.Is(err, scheduler.ErrClosed) {
+		t.Fatalf("register after shutdown error = %v, want ErrClosed", err)
+	}
+}
+
+func newScheduler(t *testing.T, enqueuer taskqueue.Enqueuer, opts ...scheduler.Option) *scheduler.Scheduler {
+	t.Helper()
+	s, err := scheduler.New(enqueuer, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return s
+}
+
+func start(t *testing.T, s *scheduler.Scheduler, clock *fakeClock) {
+	t.Helper()
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	})
+	clock.awaitWaiter(t)
+}
+
+func newPeriodic(t *testing.T, name, spec string, opts ...taskqueue.EnqueueOption) taskqueue.PeriodicTask {
+	t.Helper()
+	schedule, err := taskqueue.CronSchedule(spec)
+	if err != nil {
+		t.Fatalf("CronSchedule: %v", err)
+	}
+	task, err := taskqueue.New(taskqueue.Definition{Type: taskqueue.TaskType(name)}, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	periodic, err := taskqueue.NewPeriodicTask(name, schedule, task, opts...)
+	if err != nil {
+		t.Fatalf("NewPeriodicTask: %v", err)
+	}
+	return periodic
+}
+
+func mustRegister(t *testing.T, s *scheduler.Scheduler, name, spec string, opts ...taskqueue.EnqueueOption) {
+	t.Helper()
+	if err := s.RegisterPeriodicTask(newPeriodic(t, name, spec, opts...)); err != nil {
+		t.Fatalf("RegisterPeriodicTask: %v", err)
+	}
+}
+
+type enqueueCall struct {
+	task taskqueue.Task
+	opts taskqueue.EnqueueOptions
+}
+
+type recordingEnqueuer struct {
+	mu       sync.Mutex
+	recorded []enqueueCall
+}
+
+func (e *recordingEnqueuer) Enqueue(_ context.Context, task taskqueue.Task, opts ...taskqueue.EnqueueOption) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recorded = append(e.recorded, enqueueCall{task: task, opts: taskqueue.NewEnqueueOptions(opts...)})
+	return nil
+}
+
+func (e *recordingEnqueuer) calls() []enqueueCall {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]enqueueCall(nil), e.recorded...)
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	epoch    int
+	ch       chan time.Time
+}
+
+// fakeClock fires timers only when advanced. The scheduler requests a new
+// timer after every fire round, so a timer requested since the last Advance
+// means it has finished reacting to that Advance.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	epoch   int
+	waiters []fakeWaiter
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	deadline := c.now.Add(d)
+	if !deadline.After(c.now) {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{deadline: deadline, epoch: c.epoch, ch: ch})
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.epoch++
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.deadline.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+func (c *fakeClock) awaitWaiter(t *testing.T) {
+	t.Helper()
+	c.awaitWaiterUntil(t, time.Time{})
+}
+
+// awaitWaiterUntil waits for a timer requested since the last Advance,
+// optionally one with the given deadline.
+func (c *fakeClock) awaitWaiterUntil(t *testing.T, deadline time.Time) {
+	t.Helper()
+	timeout := time.Now().Add(waitTimeout)
+	for time.Now().Before(timeout) {
+		c.mu.Lock()
+		for _, w := range c.waiters {
+			if w.epoch == c.epoch && (deadline.IsZero() || w.deadline.Equal(deadline)) {
+				c.mu.Unlock()
+				return
+			}
+		}
+		c.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("scheduler did not wait on the clock")
+}
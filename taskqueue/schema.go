@@ -3,6 +3,8 @@ package taskqueue
 import (
 	"reflect"
 	"sync"
+
+	"google.golang.org/protobuf/proto"
 )
 
 // PayloadResolver allocates an empty payload target for a task type.
@@ -32,6 +34,22 @@ type schemaEntry struct {
 	def         Definition
 	factory     func() any
 	payloadType reflect.Type
+	codec       string
+	version     int
+	upcasters   map[int]upcaster
+}
+
+// SchemaOption configures one SchemaRegistry registration.
+type SchemaOption func(*schemaEntry)
+
+// WithSchemaCodec sets the payload codec used when the registry encodes tasks
+// for the registered type without an explicit codec name.
+func WithSchemaCodec(codecName string) SchemaOption {
+	return func(entry *schemaEntry) {
+		if codecName = normalizePayloadCodec(codecName); codecName != "" {
+			entry.codec = codecName
+		}
+	}
 }
 
 // SchemaRegistry maps semantic task types to Go payload schemas.
@@ -39,18 +57,25 @@ type SchemaRegistry struct {
 	mu           sync.RWMutex
 	byType       map[TaskType]schemaEntry
 	byPayloadTyp map[reflect.Type]Definition
+	decodeOpts   []DecodeOption
 }
 
-// NewSchemaRegistry creates an empty schema registry.
-func NewSchemaRegistry() *SchemaRegistry {
+// NewSchemaRegistry creates an empty schema registry. opts apply to every
+// task the registry decodes, such as WithDecryptionKeyring for consumers of
+// encrypted tasks.
+func NewSchemaRegistry(opts ...DecodeOption) *SchemaRegistry {
 	return &SchemaRegistry{
 		byType:       make(map[TaskType]schemaEntry),
 		byPayloadTyp: make(map[reflect.Type]Definition),
+		decodeOpts:   opts,
 	}
 }
 
 // Register associates a task definition with a factory for its payload schema.
-func (r *SchemaRegistry) Register(def Definition, factory func() any) error {
+// The default codec is ProtoCodec for protobuf messages and JSONCodec
+// otherwise; use WithSchemaCodec to override it. Use WithSchemaVersion and
+// Upcast to keep decoding tasks encoded with earlier payload schemas.
+func (r *SchemaRegistry) Register(def Definition, factory func() any, opts ...SchemaOption) error {
 	if def.Type == "" {
 		return ErrEmptyType
 	}
@@ -66,6 +91,26 @@ func (r *SchemaRegistry) Register(def Definition, factory func() any) error {
 	if err != nil {
 		return err
 	}
+	entry := schemaEntry{
+		def:         def,
+		factory:     factory,
+		payloadType: payloadType,
+		codec:       JSONCodec,
+	}
+	if _, ok := payload.(proto.Message); ok {
+		entry.codec = ProtoCodec
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&entry)
+		}
+	}
+	if _, err := lookupPayloadCodec(entry.codec); err != nil {
+		return err
+	}
+	if err := entry.validateUpcasters(); err != nil {
+		return err
+	}
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -76,11 +121,7 @@ func (r *SchemaRegistry) Register(def Definition, factory func() any) error {
 	if existing, ok := r.byType[def.Type]; ok {
 		delete(r.byPayloadTyp, existing.payloadType)
 	}
-	r.byType[def.Type] = schemaEntry{
-		def:         def,
-		factory:     factory,
-		payloadType: payloadType,
-	}
+	r.byType[def.Type] = entry
 	r.byPayloadTyp[payloadType] = def
 	return nil
 }
@@ -117,13 +158,28 @@ func (r *SchemaRegistry) DefinitionOf(payload any) (Definition, error) {
 	return def, nil
 }
 
+// CodecOf returns the default payload codec registered for taskType.
+func (r *SchemaRegistry) CodecOf(taskType TaskType) (string, error) {
+	r.mu.RLock()
+	entry, ok := r.byType[taskType]
+	r.mu.RUnlock()
+	if !ok {
+		return "", ErrUnknownType
+	}
+	return entry.codec, nil
+}
+
 // NewTask creates an encoded task using the definition registered for payload.
+// Versioned registrations set SchemaVersionHeader.
 func (r *SchemaRegistry) NewTask(codecName string, payload any, opts ...Option) (Task, error) {
 	def, err := r.DefinitionOf(payload)
 	if err != nil {
 		return Task{}, err
 	}
-	return NewEncodedTask(def, codecName, payload, opts...)
+	r.mu.RLock()
+	entry := r.byType[def.Type]
+	r.mu.RUnlock()
+	return NewEncodedTask(def, codecName, payload, entry.versionOptions(opts)...)
 }
 
 // NewJSONTask creates a JSON task using the definition registered for payload.
@@ -142,15 +198,17 @@ func (r *SchemaRegistry) Decode(task Task) (any, error) {
 }
 
 // DecodeWithCodec resolves task.Type() and decodes task payload with codecName.
+// Payloads of an older SchemaVersionHeader are decoded into the schema of
+// that version and upcast to the registered schema; versions that cannot be
+// upcast return an UnsupportedSchemaVersionError.
 func (r *SchemaRegistry) DecodeWithCodec(task Task, codecName string) (any, error) {
-	payload, err := r.Resolve(task.Type())
-	if err != nil {
-		return nil, err
-	}
-	if err := DecodePayloadWithCodec(task, codecName, payload); err != nil {
-		return nil, err
+	r.mu.RLock()
+	entry, ok := r.byType[task.Type()]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownType
 	}
-	return payload, nil
+	return entry.decode(task, codecName, r.decodeOpts)
 }
 
 // DecodeJSON resolves task.Type() and decodes a JSON task payload into that schema.
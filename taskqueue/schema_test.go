@@ -1,6 +1,7 @@
 package taskqueue
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -231,3 +232,154 @@ func TestPayloadResolverFunc_RejectsNilAndInvalidOutputs(t *testing.T) {
 		t.Fatalf("payload = %#v, want nil", payload)
 	}
 }
+
+type reviewTaskPayloadV1 struct {
+	Document string `json:"document"`
+}
+
+type reviewTaskPayloadV2 struct {
+	ID string `json:"id"`
+}
+
+func registerVersionedReview(t *testing.T, registry *SchemaRegistry, def Definition) {
+	t.Helper()
+	err := registry.Register(def, func() any { return &reviewTaskPayload{} },
+		WithSchemaVersion(3),
+		Upcast(1, func(old *reviewTaskPayloadV1) (*reviewTaskPayloadV2, error) {
+			return &reviewTaskPayloadV2{ID: old.Document}, nil
+		}),
+		Upcast(2, func(old *reviewTaskPayloadV2) (*reviewTaskPayload, error) {
+			return &reviewTaskPayload{ID: old.ID, Limit: 10}, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+}
+
+// Versioned registrations should stamp the schema version on encode and
+// upcast tasks still in flight from earlier versions on decode.
+func TestSchemaRegistry_UpcastsOlderPayloadVersions(t *testing.T) {
+	registry := NewSchemaRegistry()
+	def := Definition{Type: "document.review"}
+	registerVersionedReview(t, registry, def)
+
+	current, err := registry.NewJSONTask(&reviewTaskPayload{ID: "doc-3", Limit: 1})
+	if err != nil {
+		t.Fatalf("NewJSONTask: %v", err)
+	}
+	if version := current.Headers()[SchemaVersionHeader]; version != "3" {
+		t.Fatalf("schema version header = %q, want 3", version)
+	}
+	v1, err := NewJSONTask(def, reviewTaskPayloadV1{Document: "doc-1"})
+	if err != nil {
+		t.Fatalf("NewJSONTask v1: %v", err)
+	}
+	v2, err := NewJSONTask(def, reviewTaskPayloadV2{ID: "doc-2"}, WithHeader(SchemaVersionHeader, "2"))
+	if err != nil {
+		t.Fatalf("NewJSONTask v2: %v", err)
+	}
+
+	for _, tc := range []struct {
+		task Task
+		want reviewTaskPayload
+	}{
+		{current, reviewTaskPayload{ID: "doc-3", Limit: 1}},
+		{v1, reviewTaskPayload{ID: "doc-1", Limit: 10}},
+		{v2, reviewTaskPayload{ID: "doc-2", Limit: 10}},
+	} {
+		decoded, err := registry.Decode(tc.task)
+		if err != nil {
+			t.Fatalf("Decode %s: %v", tc.want.ID, err)
+		}
+		if payload, ok := decoded.(*reviewTaskPayload); !ok || *payload != tc.want {
+			t.Fatalf("decoded = %#v, want %#v", decoded, tc.want)
+		}
+	}
+
+	var handled *reviewTaskPayload
+	processor, err := Handle(registry, func(_ context.Context, _ Task, payload *reviewTaskPayload) error {
+		handled = payload
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if err := processor.Process(context.Background(), v1); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if handled == nil || handled.ID != "doc-1" {
+		t.Fatalf("handled payload = %#v", handled)
+	}
+}
+
+// Malformed versions should be reported with the task version and skip
+// retries in typed handlers.
+func TestSchemaRegistry_RejectsUnsupportedPayloadVersions(t *testing.T) {
+	registry := NewSchemaRegistry()
+	def := Definition{Type: "document.review"}
+	registerVersionedReview(t, registry, def)
+	processor, err := Handle(registry, func(context.Context, Task, *reviewTaskPayload) error { return nil })
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+
+	for _, version := range []string{"0", "three"} {
+		task, err := NewJSONTask(def, reviewTaskPayload{}, WithHeader(SchemaVersionHeader, version))
+		if err != nil {
+			t.Fatalf("NewJSONTask: %v", err)
+		}
+		_, err = registry.Decode(task)
+		var unsupported *UnsupportedSchemaVersionError
+		if !errors.As(err, &unsupported) || unsupported.Version != version || unsupported.Current != 3 || unsupported.Newer() {
+			t.Fatalf("Decode version %s error = %v, want UnsupportedSchemaVersionError", version, err)
+		}
+		if err := processor.Process(context.Background(), task); !errors.Is(err, ErrUnsupportedSchemaVersion) || !errors.Is(err, ErrSkipRetry) {
+			t.Fatalf("Process version %s error = %v, want unsupported version and ErrSkipRetry", version, err)
+		}
+	}
+}
+
+// Versions newer than the registered one come from producers deployed ahead
+// of the worker and should stay retryable in typed handlers, so an upgraded
+// worker processes them instead of the task being dead-lettered.
+func TestSchemaRegistry_RetriesNewerPayloadVersions(t *testing.T) {
+	registry := NewSchemaRegistry()
+	def := Definition{Type: "document.review"}
+	registerVersionedReview(t, registry, def)
+	processor, err := Handle(registry, func(context.Context, Task, *reviewTaskPayload) error { return nil })
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	task, err := NewJSONTask(def, reviewTaskPayload{}, WithHeader(SchemaVersionHeader, "4"))
+	if err != nil {
+		t.Fatalf("NewJSONTask: %v", err)
+	}
+
+	_, err = registry.Decode(task)
+	var unsupported *UnsupportedSchemaVersionError
+	if !errors.As(err, &unsupported) || !unsupported.Newer() {
+		t.Fatalf("Decode error = %v, want newer UnsupportedSchemaVersionError", err)
+	}
+	err = processor.Process(context.Background(), task)
+	if !errors.Is(err, ErrUnsupportedSchemaVersion) || errors.Is(err, ErrSkipRetry) {
+		t.Fatalf("Process error = %v, want retryable unsupported version", err)
+	}
+}
+
+// Upcaster chains should be checked at registration so gaps and mismatched
+// schemas do not surface only when old tasks arrive.
+func TestSchemaRegistry_RejectsBrokenUpcasterChains(t *testing.T) {
+	toV2 := Upcast(1, func(old *reviewTaskPayloadV1) (*reviewTaskPayloadV2, error) { return &reviewTaskPayloadV2{}, nil })
+	for name, opts := range map[string][]SchemaOption{
+		"gap":          {WithSchemaVersion(3), toV2},
+		"wrong schema": {WithSchemaVersion(2), Upcast(1, func(*reviewTaskPayloadV1) (*reviewTaskPayloadV2, error) { return nil, nil })},
+		"not older":    {WithSchemaVersion(2), Upcast(2, func(*reviewTaskPayloadV2) (*reviewTaskPayload, error) { return nil, nil })},
+		"unversioned":  {toV2},
+	} {
+		err := NewSchemaRegistry().Register(Definition{Type: "document.review"}, func() any { return &reviewTaskPayload{} }, opts...)
+		if !errors.Is(err, ErrInvalidSchemaVersion) {
+			t.Fatalf("%s error = %v, want ErrInvalidSchemaVersion", name, err)
+		}
+	}
+}
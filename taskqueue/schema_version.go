@@ -0,0 +1,176 @@
+package taskqueue
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// SchemaVersionHeader carries the payload schema version of tasks encoded by
+// a SchemaRegistry registration with WithSchemaVersion.
+const SchemaVersionHeader = "task.schema_version"
+
+// UnsupportedSchemaVersionError reports a task payload version that the
+// registry cannot decode: newer than the registered version, older than every
+// registered upcaster, or malformed.
+type UnsupportedSchemaVersionError struct {
+	TaskType TaskType
+	// Version is the SchemaVersionHeader value of the task.
+	Version string
+	// Current is the registered payload version.
+	Current int
+}
+
+// Error implements error.
+func (e *UnsupportedSchemaVersionError) Error() string {
+	return fmt.Sprintf("%s: %s version %q, current version %d", ErrUnsupportedSchemaVersion, e.TaskType, e.Version, e.Current)
+}
+
+// Is reports whether target is ErrUnsupportedSchemaVersion.
+func (e *UnsupportedSchemaVersionError) Is(target error) bool {
+	return target == ErrUnsupportedSchemaVersion
+}
+
+// Newer reports whether the task version is newer than the registered
+// version, as for tasks enqueued by producers that are deployed ahead of the
+// worker. Typed handlers retry such tasks instead of skipping retries.
+func (e *UnsupportedSchemaVersionError) Newer() bool {
+	version, err := strconv.Atoi(e.Version)
+	return err == nil && version > e.Current
+}
+
+type upcaster struct {
+	fromType reflect.Type
+	toType   reflect.Type
+	factory  func() any
+	upcast   func(any) (any, error)
+}
+
+// WithSchemaVersion sets the version of the registered payload schema.
+// Tasks encoded by the registry carry it in SchemaVersionHeader; tasks
+// without the header are treated as version 1. Versions start at 1.
+func WithSchemaVersion(version int) SchemaOption {
+	return func(entry *schemaEntry) {
+		if version > 0 {
+			entry.version = version
+		}
+	}
+}
+
+// Upcast registers fn to migrate payloads of version from, decoded into a
+// *From, to the schema of version from+1. Chained upcasters bring any
+// supported older version up to the registered schema on decode; the chain
+// from each upcaster to the registered version must be complete.
+func Upcast[From, To any](from int, fn func(*From) (*To, error)) SchemaOption {
+	return func(entry *schemaEntry) {
+		if fn == nil {
+			return
+		}
+		if entry.upcasters == nil {
+			entry.upcasters = make(map[int]upcaster)
+		}
+		entry.upcasters[from] = upcaster{
+			fromType: reflect.TypeFor[From](),
+			toType:   reflect.TypeFor[To](),
+			factory:  func() any { return new(From) },
+			upcast: func(payload any) (any, error) {
+				next, err := fn(payload.(*From))
+				if err == nil && next == nil {
+					err = ErrNilPayload
+				}
+				return next, err
+			},
+		}
+	}
+}
+
+// currentVersion returns the registered version, 1 for unversioned schemas.
+func (e schemaEntry) currentVersion() int {
+	return max(e.version, 1)
+}
+
+func (e schemaEntry) validateUpcasters() error {
+	current := e.currentVersion()
+	for from, u := range e.upcasters {
+		if from < 1 || from >= current {
+			return fmt.Errorf("%w: upcaster from version %d with current version %d", ErrInvalidSchemaVersion, from, current)
+		}
+		want := e.payloadType
+		if from+1 < current {
+			next, ok := e.upcasters[from+1]
+			if !ok {
+				return fmt.Errorf("%w: missing upcaster from version %d", ErrInvalidSchemaVersion, from+1)
+			}
+			want = next.fromType
+		}
+		if u.toType != want {
+			return fmt.Errorf("%w: upcaster from version %d returns %s, want %s", ErrInvalidSchemaVersion, from, u.toType, want)
+		}
+	}
+	return nil
+}
+
+// versionOptions appends the schema version header to opts for versioned
+// registrations.
+func (e schemaEntry) versionOptions(opts []Option) []Option {
+	if e.version == 0 {
+		return opts
+	}
+	return append(opts[:len(opts):len(opts)], WithHeader(SchemaVersionHeader, strconv.Itoa(e.version)))
+}
+
+// decode decodes task into the registered schema, upcasting older versions.
+func (e schemaEntry) decode(task Task, codecName string, opts []DecodeOption) (any, error) {
+	current := e.currentVersion()
+	version := 1
+	if raw, ok := task.headers[SchemaVersionHeader]; ok {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > current {
+			return nil, e.unsupported(raw)
+		}
+		version = parsed
+	}
+
+	var payload any
+	if version == current {
+		payload = e.factory()
+		if err := validatePayloadTarget(payload); err != nil {
+			return nil, err
+		}
+	} else {
+		u, ok := e.upcasters[version]
+		if !ok {
+			return nil, e.unsupported(strconv.Itoa(version))
+		}
+		payload = u.factory()
+	}
+	if err := DecodePayloadWithCodec(task, codecName, payload, opts...); err != nil {
+		return nil, err
+	}
+	for from := version; from < current; from++ {
+		next, err := e.upcasters[from].upcast(payload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: upcast %s payload from version %d: %w", ErrSkipRetry, e.def.Type, from, err)
+		}
+		payload = next
+	}
+	return payload, nil
+}
+
+func (e schemaEntry) unsupported(version string) error {
+	return &UnsupportedSchemaVersionError{TaskType: e.def.Type, Version: version, Current: e.currentVersion()}
+}
+
+// skipRetry marks err as non-retryable unless it already is or it reports a
+// newer schema version, which an upgraded worker can still decode.
+func skipRetry(err error) error {
+	if err == nil || errors.Is(err, ErrSkipRetry) {
+		return err
+	}
+	var unsupported *UnsupportedSchemaVersionError
+	if errors.As(err, &unsupported) && unsupported.Newer() {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrSkipRetry, err)
+}
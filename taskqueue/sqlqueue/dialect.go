@@ -0,0 +1,150 @@
+package sqlqueue
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-jimu/components/internal/sqlutil"
+)
+
+// Dialect selects placeholder syntax and column types for a database.
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectMySQL    Dialect = "mysql"
+	DialectSQLite   Dialect = "sqlite"
+)
+
+// DefaultTable is the task table name used when WithTable is not supplied.
+const DefaultTable = "taskqueue_tasks"
+
+// Schema returns the DDL statements that create the task table and its
+// unique-lock companion table named table + "_unique".
+func Schema(dialect Dialect, table string) ([]string, error) {
+	if err := validateTable(table); err != nil {
+		return nil, err
+	}
+	index := strings.ReplaceAll(table, ".", "_") + "_due_idx"
+	switch dialect {
+	case DialectPostgres:
+		return []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(64) PRIMARY KEY,
+	task_type VARCHAR(255) NOT NULL,
+	queue VARCHAR(255) NOT NULL,
+	payload BYTEA,
+	payload_codec VARCHAR(64) NOT NULL,
+	task_key VARCHAR(255) NOT NULL,
+	headers TEXT NOT NULL,
+	status VARCHAR(32) NOT NULL,
+	retry_count INTEGER NOT NULL,
+	max_retry INTEGER NOT NULL,
+	timeout_ns BIGINT NOT NULL,
+	deadline BIGINT NOT NULL,
+	unique_key VARCHAR(64) NOT NULL,
+	process_at BIGINT NOT NULL,
+	locked_until BIGINT NOT NULL,
+	claimed_by VARCHAR(255) NOT NULL,
+	last_error TEXT NOT NULL,
+	attempts TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL
+)`, table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (status, process_at)`, index, table),
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s_unique (
+	unique_key VARCHAR(64) PRIMARY KEY,
+	task_id VARCHAR(64) NOT NULL,
+	expires_at BIGINT NOT NULL
+)`, table),
+		}, nil
+	case DialectMySQL:
+		return []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(64) PRIMARY KEY,
+	task_type VARCHAR(255) NOT NULL,
+	queue VARCHAR(255) NOT NULL,
+	payload LONGBLOB,
+	payload_codec VARCHAR(64) NOT NULL,
+	task_key VARCHAR(255) NOT NULL,
+	headers TEXT NOT NULL,
+	status VARCHAR(32) NOT NULL,
+	retry_count INT NOT NULL,
+	max_retry INT NOT NULL,
+	timeout_ns BIGINT NOT NULL,
+	deadline BIGINT NOT NULL,
+	unique_key VARCHAR(64) NOT NULL,
+	process_at BIGINT NOT NULL,
+	locked_until BIGINT NOT NULL,
+	claimed_by VARCHAR(255) NOT NULL,
+	last_error TEXT NOT NULL,
+	attempts TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL,
+	INDEX %s (status, process_at)
+)`, table, index),
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s_unique (
+	unique_key VARCHAR(64) PRIMARY KEY,
+	task_id VARCHAR(64) NOT NULL,
+	expires_at BIGINT NOT NULL
+)`, table),
+		}, nil
+	case DialectSQLite:
+		return []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id TEXT PRIMARY KEY,
+	task_type TEXT NOT NULL,
+	queue TEXT NOT NULL,
+	payload BLOB,
+	payload_codec TEXT NOT NULL,
+	task_key TEXT NOT NULL,
+	headers TEXT NOT NULL,
+	status TEXT NOT NULL,
+	retry_count INTEGER NOT NULL,
+	max_retry INTEGER NOT NULL,
+	timeout_ns INTEGER NOT NULL,
+	deadline INTEGER NOT NULL,
+	unique_key TEXT NOT NULL,
+	process_at INTEGER NOT NULL,
+	locked_until INTEGER NOT NULL,
+	claimed_by TEXT NOT NULL,
+	last_error TEXT NOT NULL,
+	attempts TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL
+)`, table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (status, process_at)`, index, table),
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s_unique (
+	unique_key TEXT PRIMARY KEY,
+	task_id TEXT NOT NULL,
+	expires_at INTEGER NOT NULL
+)`, table),
+		}, nil
+	default:
+		return nil, ErrUnknownDialect
+	}
+}
+
+func validateTable(table string) error {
+	if !sqlutil.ValidTable(table) {
+		return ErrInvalidTable
+	}
+	return nil
+}
+
+// rebind rewrites ? placeholders into the dialect's placeholder syntax.
+func (d Dialect) rebind(query string) string {
+	if d != DialectPostgres {
+		return query
+	}
+	return sqlutil.Rebind(query)
+}
+
+func (d Dialect) valid() bool {
+	switch d {
+	case DialectPostgres, DialectMySQL, DialectSQLite:
+		return true
+	default:
+		return false
+	}
+}
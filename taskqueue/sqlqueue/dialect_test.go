@@ -0,0 +1,44 @@
+package sqlqueue
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// PostgreSQL needs numbered placeholders while MySQL and SQLite keep ? so the
+// same query text can be shared across dialects.
+func TestDialect_Rebind(t *testing.T) {
+	query := "UPDATE t SET a = ? WHERE id = ? AND b = ?"
+	if got := DialectPostgres.rebind(query); got != "UPDATE t SET a = $1 WHERE id = $2 AND b = $3" {
+		t.Fatalf("postgres = %q", got)
+	}
+	for _, dialect := range []Dialect{DialectMySQL, DialectSQLite} {
+		if got := dialect.rebind(query); got != query {
+			t.Fatalf("%s = %q", dialect, got)
+		}
+	}
+}
+
+// Schema should produce DDL for every supported dialect and reject unsafe
+// table names before they are interpolated into SQL.
+func TestSchema(t *testing.T) {
+	for _, dialect := range []Dialect{DialectPostgres, DialectMySQL, DialectSQLite} {
+		statements, err := Schema(dialect, "jobs.tasks")
+		if err != nil {
+			t.Fatalf("%s: %v", dialect, err)
+		}
+		if !strings.Contains(statements[0], "CREATE TABLE IF NOT EXISTS jobs.tasks (") {
+			t.Fatalf("%s statement = %s", dialect, statements[0])
+		}
+		if !strings.Contains(statements[len(statements)-1], "jobs.tasks_unique") {
+			t.Fatalf("%s unique statement = %s", dialect, statements[len(statements)-1])
+		}
+	}
+	if _, err := Schema(DialectSQLite, "tasks--"); !errors.Is(err, ErrInvalidTable) {
+		t.Fatalf("invalid table error = %v", err)
+	}
+	if _, err := Schema("oracle", "tasks"); !errors.Is(err, ErrUnknownDialect) {
+		t.Fatalf("unknown dialect error = %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+// Package sqlqueue provides a durable taskqueue provider backed by database/sql.
+//
+// Queue persists task envelopes (type, queue, payload, payload codec, key, and
+// headers) together with their enqueue policy in one table. Workers claim due
+// rows with a lease: a claim sets the row to StatusProcessing with a
+// locked_until timestamp and the worker ID, and another worker may reclaim the
+// row once the lease has expired. Claims are conditional updates, so no
+// database-specific locking syntax is required and the same schema works on
+// PostgreSQL, MySQL, and SQLite. Due rows are claimed in process_at order
+// across all served queues; taskqueue.QueuePriority is not applied.
+//
+// Enqueue policy is honoured as follows:
+//
+//   - WithDelay and WithProcessAt set the first process_at timestamp.
+//   - WithMaxRetry overrides the default retry count; failed attempts are
+//     rescheduled after the configured retry backoff.
+//   - WithTimeout bounds each attempt and extends the claim lease when it is
+//     longer than the configured lease.
+//   - WithDeadline bounds every attempt and archives the task once passed.
+//   - WithUnique takes a row in the companion unique-lock table keyed by
+//     TaskType, Queue, and Key (payload bytes when the key is empty). A second
+//     enqueue returns taskqueue.ErrDuplicateTask until the lock expires or the
+//     first task finishes.
+//
+// Successful tasks are deleted. Tasks that exhaust their retries, fail with
+// taskqueue.ErrSkipRetry, or pass their deadline are kept as StatusArchived
+// with the last error. Errors wrapping taskqueue.RetryLaterError, such as those
+// from WithLimiter, reschedule the task without counting a retry.
+//
+// While a processor runs, the worker renews its lease every third of the
+// lease, and cancels the processing context if another worker has taken the
+// row over. A row reclaimed after its lease expired, because its worker
+// crashed or stalled, counts as a retry; once the retries are spent it is
+// archived with ErrLeaseExpired instead of being processed again. A stalled
+// worker may still finish after its row was reclaimed, so processors must be
+// idempotent.
+//
+// Timestamps are stored as Unix nanoseconds in integer columns to keep
+// comparisons portable across databases. Create the tables with Migrate or
+// apply the statements returned by Schema through a migration tool.
+package sqlqueue
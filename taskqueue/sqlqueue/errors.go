@@ -0,0 +1,13 @@
+package sqlqueue
+
+import "errors"
+
+var (
+	ErrNilDB          = errors.New("sql queue database is nil")
+	ErrNilRouter      = errors.New("sql queue router is nil")
+	ErrUnknownDialect = errors.New("sql queue dialect is unknown")
+	ErrInvalidTable   = errors.New("sql queue table name is invalid")
+	ErrClosed         = errors.New("sql queue is closed")
+	ErrAlreadyStarted = errors.New("sql queue is already started")
+	ErrLeaseExpired   = errors.New("sql queue task lease expired before it finished")
+)
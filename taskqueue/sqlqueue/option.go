@@ -0,0 +1,150 @@
+package sqlqueue
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+const (
+	// DefaultQueue is the execution queue reported for tasks without a
+	// Definition.Queue.
+	DefaultQueue = "default"
+
+	defaultConcurrency  = 10
+	defaultMaxRetry     = 3
+	defaultRetryBackoff = 10 * time.Second
+	defaultPollInterval = time.Second
+	defaultLease        = 30 * time.Second
+)
+
+// Option configures a Queue during construction.
+type Option func(*Queue)
+
+// WithTable sets the task table name. The unique-lock table is named
+// table + "_unique".
+func WithTable(table string) Option {
+	return func(q *Queue) {
+		if table != "" {
+			q.table = table
+		}
+	}
+}
+
+// WithQueues restricts workers to claiming tasks whose Definition.Queue is one
+// of queues; use "" for tasks without a queue. By default workers claim tasks
+// from every queue.
+func WithQueues(queues ...string) Option {
+	return func(q *Queue) {
+		q.queues = append(q.queues, queues...)
+	}
+}
+
+// WithConcurrency sets the maximum number of tasks one worker runs at once.
+func WithConcurrency(concurrency int) Option {
+	return func(q *Queue) {
+		if concurrency > 0 {
+			q.concurrency = concurrency
+		}
+	}
+}
+
+// WithPollInterval sets how long an idle worker waits before claiming again.
+func WithPollInterval(interval time.Duration) Option {
+	return func(q *Queue) {
+		if interval > 0 {
+			q.pollInterval = interval
+		}
+	}
+}
+
+// WithLease sets how long a claimed task stays locked to this worker.
+func WithLease(lease time.Duration) Option {
+	return func(q *Queue) {
+		if lease > 0 {
+			q.lease = lease
+		}
+	}
+}
+
+// WithWorkerID sets the claimed_by value recorded for claimed tasks.
+func WithWorkerID(id string) Option {
+	return func(q *Queue) {
+		if id != "" {
+			q.workerID = id
+		}
+	}
+}
+
+// WithDefaultMaxRetry sets the retry count for tasks enqueued without
+// taskqueue.WithMaxRetry.
+func WithDefaultMaxRetry(maxRetry int) Option {
+	return func(q *Queue) {
+		if maxRetry >= 0 {
+			q.defaultMaxRetry = maxRetry
+		}
+	}
+}
+
+// WithRetryBackoff sets a fixed delay before a failed attempt is retried. It
+// is ignored when WithRetryPolicy is set.
+func WithRetryBackoff(backoff time.Duration) Option {
+	return func(q *Queue) {
+		if backoff >= 0 {
+			q.retryBackoff = backoff
+		}
+	}
+}
+
+// WithRetryPolicy sets the policy that decides whether and when failed
+// attempts are retried. ErrSkipRetry and the task max retry still stop retries.
+func WithRetryPolicy(policy taskqueue.RetryPolicy) Option {
+	return func(q *Queue) {
+		if policy != nil {
+			q.retryPolicy = policy
+		}
+	}
+}
+
+// WithDeadLetterSink sends tasks to sink when they are archived after
+// exhausting their retries or failing with taskqueue.ErrSkipRetry.
+func WithDeadLetterSink(sink taskqueue.DeadLetterSink) Option {
+	return func(q *Queue) {
+		q.deadLetters = sink
+	}
+}
+
+// WithLimiter enforces limiter before router dispatch. Tasks over a limit are
+// rescheduled without counting a retry.
+func WithLimiter(limiter *taskqueue.Limiter) Option {
+	return func(q *Queue) {
+		q.limiter = limiter
+	}
+}
+
+// WithMiddleware wraps router dispatch with middleware in declaration order.
+func WithMiddleware(middleware ...taskqueue.Middleware) Option {
+	return func(q *Queue) {
+		q.middleware = append(q.middleware, middleware...)
+	}
+}
+
+// WithLogger sets the logger for worker runtime diagnostics such as claim and
+// status update failures.
+func WithLogger(logger *slog.Logger) Option {
+	return func(q *Queue) {
+		if logger != nil {
+			q.logger = logger
+		}
+	}
+}
+
+// WithClock sets the time source used for scheduling and lease decisions.
+func WithClock(now func() time.Time) Option {
+	return func(q *Queue) {
+		if now != nil {
+			q.now = now
+		}
+	}
+}
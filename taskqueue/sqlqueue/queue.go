@@ -0,0 +1,748 @@
+package sqlqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// Status is the persisted lifecycle state of a task row.
+type Status string
+
+const (
+	StatusPending    Status = "pending"
+	StatusProcessing Status = "processing"
+	StatusArchived   Status = "archived"
+)
+
+const taskColumns = "id, task_type, queue, payload, payload_codec, task_key, headers, " +
+	"retry_count, max_retry, timeout_ns, deadline, unique_key, attempts"
+
+// Queue is a durable task queue provider backed by database/sql.
+type Queue struct {
+	db      *sql.DB
+	dialect Dialect
+	process taskqueue.ProcessorFunc
+
+	mu       sync.Mutex
+	started  bool
+	closed   bool
+	active   int
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopped  chan struct{}
+	inflight sync.WaitGroup
+	rootCtx  context.Context
+	cancel   context.CancelFunc
+
+	table           string
+	queues          []string
+	concurrency     int
+	pollInterval    time.Duration
+	lease           time.Duration
+	workerID        string
+	defaultMaxRetry int
+	retryBackoff    time.Duration
+	retryPolicy     taskqueue.RetryPolicy
+	deadLetters     taskqueue.DeadLetterSink
+	limiter         *taskqueue.Limiter
+	middleware      []taskqueue.Middleware
+	logger          *slog.Logger
+	now             func() time.Time
+}
+
+var (
+	_ taskqueue.Enqueuer = (*Queue)(nil)
+	_ taskqueue.Worker   = (*Queue)(nil)
+	_ taskqueue.Runner   = (*Queue)(nil)
+)
+
+type claimedTask struct {
+	id         string
+	reclaimed  bool
+	lease      time.Duration
+	task       taskqueue.Task
+	retryCount int
+	maxRetry   int
+	timeout    time.Duration
+	deadline   time.Time
+	uniqueKey  string
+	attempts   []taskqueue.Attempt
+}
+
+// New creates a SQL-backed queue that dispatches claimed tasks through router.
+//
+// Producer-only services can pass an empty router and never call Start.
+func New(db *sql.DB, dialect Dialect, router *taskqueue.Router, opts ...Option) (*Queue, error) {
+	if db == nil {
+		return nil, ErrNilDB
+	}
+	if !dialect.valid() {
+		return nil, ErrUnknownDialect
+	}
+	if router == nil {
+		return nil, ErrNilRouter
+	}
+	workerID, err := generateID()
+	if err != nil {
+		return nil, fmt.Errorf("generate worker id: %w", err)
+	}
+	rootCtx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		db:              db,
+		dialect:         dialect,
+		wake:            make(chan struct{}, 1),
+		stop:            make(chan struct{}),
+		stopped:         make(chan struct{}),
+		rootCtx:         rootCtx,
+		cancel:          cancel,
+		table:           DefaultTable,
+		concurrency:     defaultConcurrency,
+		pollInterval:    defaultPollInterval,
+		lease:           defaultLease,
+		workerID:        workerID,
+		defaultMaxRetry: defaultMaxRetry,
+		retryBackoff:    defaultRetryBackoff,
+		logger:          slog.Default(),
+		now:             time.Now,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(q)
+		}
+	}
+	if err := validateTable(q.table); err != nil {
+		cancel()
+		return nil, err
+	}
+	if q.retryPolicy == nil {
+		q.retryPolicy = taskqueue.NewRetryPolicy(taskqueue.FixedBackoff(q.retryBackoff))
+	}
+	middleware := append([]taskqueue.Middleware{taskqueue.Recover(), taskqueue.Limit(q.limiter)}, q.middleware...)
+	q.process = taskqueue.Chain(router.Process, middleware...)
+	return q, nil
+}
+
+// Migrate creates the task and unique-lock tables when they do not exist.
+func (q *Queue) Migrate(ctx context.Context) error {
+	statements, err := Schema(q.dialect, q.table)
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if _, err := q.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("migrate task queue table %s: %w", q.table, err)
+		}
+	}
+	return nil
+}
+
+// Enqueue validates policy and inserts task as a pending row.
+func (q *Queue) Enqueue(ctx context.Context, task taskqueue.Task, opts ...taskqueue.EnqueueOption) error {
+	if task.Type() == "" {
+		return taskqueue.ErrEmptyType
+	}
+	policy := taskqueue.NewEnqueueOptions(opts...)
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	id, err := generateID()
+	if err != nil {
+		return fmt.Errorf("generate task id: %w", err)
+	}
+	headers, err := encodeHeaders(task.Headers())
+	if err != nil {
+		return fmt.Errorf("encode task headers: %w", err)
+	}
+
+	now := q.now()
+	processAt := now
+	if delay := policy.Delay(); delay > 0 {
+		processAt = now.Add(delay)
+	} else if at := policy.ProcessAt(); !at.IsZero() {
+		processAt = at
+	}
+	maxRetry, ok := policy.MaxRetry()
+	if !ok {
+		maxRetry = q.defaultMaxRetry
+	}
+	key := ""
+	if policy.UniqueTTL() > 0 {
+		key = uniqueKey(task)
+	}
+
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin enqueue transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if key != "" {
+		if err := q.lockUnique(ctx, tx, key, id, now, now.Add(policy.UniqueTTL())); err != nil {
+			_ = tx.Rollback()
+			return q.uniqueLockError(ctx, key, now, err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, q.dialect.rebind("INSERT INTO "+q.table+" ("+taskColumns+
+		", status, process_at, locked_until, claimed_by, last_error, created_at, updated_at)"+
+		" VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		id, string(task.Type()), task.Queue(), task.Payload(), task.PayloadCodec(), task.Key(), headers,
+		0, maxRetry, int64(policy.Timeout()), unixNano(policy.Deadline()), key, "",
+		string(StatusPending), processAt.UnixNano(), int64(0), "", "", now.UnixNano(), now.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("insert task %s: %w", id, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit enqueue transaction: %w", err)
+	}
+	return nil
+}
+
+func (q *Queue) lockUnique(ctx context.Context, tx *sql.Tx, key, taskID string, now, expiresAt time.Time) error {
+	if _, err := tx.ExecContext(ctx, q.dialect.rebind("DELETE FROM "+q.table+"_unique WHERE unique_key = ? AND expires_at <= ?"),
+		key, now.UnixNano()); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, q.dialect.rebind("INSERT INTO "+q.table+"_unique (unique_key, task_id, expires_at) VALUES (?, ?, ?)"),
+		key, taskID, expiresAt.UnixNano())
+	return err
+}
+
+// uniqueLockError reports ErrDuplicateTask when a live lock explains the
+// failed insert, and the original database error otherwise.
+func (q *Queue) uniqueLockError(ctx context.Context, key string, now time.Time, cause error) error {
+	var held int
+	err := q.db.QueryRowContext(ctx, q.dialect.rebind("SELECT 1 FROM "+q.table+"_unique WHERE unique_key = ? AND expires_at > ?"),
+		key, now.UnixNano()).Scan(&held)
+	if err == nil {
+		return taskqueue.ErrDuplicateTask
+	}
+	return fmt.Errorf("lock unique task: %w", cause)
+}
+
+// Start begins claiming and processing due tasks in a background goroutine.
+func (q *Queue) Start(context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	if q.started {
+		return ErrAlreadyStarted
+	}
+	q.started = true
+	go q.loop()
+	return nil
+}
+
+// Shutdown stops claiming new tasks and waits for in-flight tasks to finish
+// until ctx is done. When ctx is done first, in-flight processor contexts are
+// canceled, ctx.Err() is returned, and unfinished rows become claimable again
+// once their lease expires.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	started := q.started
+	q.mu.Unlock()
+	defer q.cancel()
+	if !started {
+		return nil
+	}
+
+	q.stopOnce.Do(func() { close(q.stop) })
+	<-q.stopped
+
+	done := make(chan struct{})
+	go func() {
+		q.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run starts the queue, blocks until ctx is done, and then drains in-flight
+// tasks before returning.
+func (q *Queue) Run(ctx context.Context) error {
+	if err := q.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return q.Shutdown(context.WithoutCancel(ctx))
+}
+
+func (q *Queue) loop() {
+	defer close(q.stopped)
+	timer := time.NewTimer(q.pollInterval)
+	defer timer.Stop()
+	for {
+		busy := q.poll()
+		var timeout <-chan time.Time
+		if !busy {
+			timer.Reset(q.pollInterval)
+			timeout = timer.C
+		}
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		case <-timeout:
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+}
+
+// poll claims as many tasks as free capacity allows and reports whether the
+// worker is saturated, in which case the loop waits for a finished task
+// instead of the poll interval.
+func (q *Queue) poll() bool {
+	q.mu.Lock()
+	capacity := q.concurrency - q.active
+	q.mu.Unlock()
+	if capacity <= 0 {
+		return true
+	}
+
+	claimed, err := q.claim(q.rootCtx, capacity)
+	if err != nil {
+		q.logger.Error("taskqueue sql claim failed", slog.String("table", q.table), slog.Any("error", err))
+		return false
+	}
+	q.mu.Lock()
+	q.active += len(claimed)
+	q.mu.Unlock()
+	for _, task := range claimed {
+		q.inflight.Add(1)
+		go q.execute(task)
+	}
+	return len(claimed) == capacity
+}
+
+func (q *Queue) claim(ctx context.Context, limit int) ([]claimedTask, error) {
+	now := q.now()
+	query := "SELECT " + taskColumns + ", status FROM " + q.table +
+		" WHERE ((status = ? AND process_at <= ?) OR (status = ? AND locked_until <= ?))"
+	args := []any{string(StatusPending), now.UnixNano(), string(StatusProcessing), now.UnixNano()}
+	if len(q.queues) > 0 {
+		query += " AND queue IN (?" + strings.Repeat(", ?", len(q.queues)-1) + ")"
+		for _, queue := range q.queues {
+			args = append(args, queue)
+		}
+	}
+	query += " ORDER BY process_at, id LIMIT ?"
+	args = append(args, limit)
+
+	candidates, err := q.selectTasks(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	claimed := make([]claimedTask, 0, len(candidates))
+	for _, candidate := range candidates {
+		candidate.lease = max(q.lease, candidate.timeout)
+		// A reclaim means the previous worker crashed or hung, so it counts as
+		// a retry; otherwise such a task would be reclaimed forever.
+		increment, state, due := 0, "status = ? AND process_at <= ?", string(StatusPending)
+		if candidate.reclaimed {
+			increment, state, due = 1, "status = ? AND locked_until <= ?", string(StatusProcessing)
+		}
+		result, err := q.db.ExecContext(ctx, q.dialect.rebind("UPDATE "+q.table+
+			" SET retry_count = retry_count + ?, status = ?, locked_until = ?, claimed_by = ?, updated_at = ?"+
+			" WHERE id = ? AND "+state),
+			increment, string(StatusProcessing), now.Add(candidate.lease).UnixNano(), q.workerID, now.UnixNano(),
+			candidate.id, due, now.UnixNano(),
+		)
+		if err != nil {
+			return claimed, fmt.Errorf("claim task %s: %w", candidate.id, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return claimed, fmt.Errorf("claim task %s: %w", candidate.id, err)
+		}
+		if affected != 1 {
+			continue
+		}
+		candidate.retryCount += increment
+		claimed = append(claimed, candidate)
+	}
+	return claimed, nil
+}
+
+func (q *Queue) selectTasks(ctx context.Context, query string, args ...any) ([]claimedTask, error) {
+	rows, err := q.db.QueryContext(ctx, q.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("select due tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []claimedTask
+	for rows.Next() {
+		var (
+			claimed      claimedTask
+			taskType     string
+			queue        string
+			payload      []byte
+			payloadCodec string
+			key          string
+			headers      string
+			timeout      int64
+			deadline     int64
+			attempts     string
+			status       string
+		)
+		if err := rows.Scan(&claimed.id, &taskType, &queue, &payload, &payloadCodec, &key, &headers,
+			&claimed.retryCount, &claimed.maxRetry, &timeout, &deadline, &claimed.uniqueKey, &attempts, &status); err != nil {
+			return nil, fmt.Errorf("scan task: %w", err)
+		}
+		if claimed.attempts, err = decodeAttempts(attempts); err != nil {
+			return nil, fmt.Errorf("decode task %s attempts: %w", claimed.id, err)
+		}
+		decoded, err := decodeHeaders(headers)
+		if err != nil {
+			return nil, fmt.Errorf("decode task %s headers: %w", claimed.id, err)
+		}
+		claimed.task, err = taskqueue.New(
+			taskqueue.Definition{Type: taskqueue.TaskType(taskType), Queue: queue},
+			payload,
+			taskqueue.WithPayloadCodec(payloadCodec),
+			taskqueue.WithKey(key),
+			taskqueue.WithHeaders(decoded),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("restore task %s: %w", claimed.id, err)
+		}
+		claimed.reclaimed = Status(status) == StatusProcessing
+		claimed.timeout = time.Duration(timeout)
+		claimed.deadline = fromUnixNano(deadline)
+		tasks = append(tasks, claimed)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate due tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+func (q *Queue) execute(task claimedTask) {
+	defer q.inflight.Done()
+	startedAt := q.now()
+	err := q.attempt(task)
+	if err != nil && !errors.Is(err, taskqueue.ErrRetryLater) {
+		task.attempts = append(task.attempts, taskqueue.Attempt{
+			RetryCount: task.retryCount,
+			StartedAt:  startedAt,
+			FinishedAt: q.now(),
+			Error:      err.Error(),
+		})
+	}
+	if finishErr := q.finish(task, err); finishErr != nil {
+		q.logger.Error("taskqueue sql status update failed",
+			slog.String("task_id", task.id), slog.Any("error", finishErr))
+	}
+	q.mu.Lock()
+	q.active--
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) attempt(task claimedTask) error {
+	if task.reclaimed && task.retryCount > task.maxRetry {
+		return fmt.Errorf("%w: %w", taskqueue.ErrSkipRetry, ErrLeaseExpired)
+	}
+	if !task.deadline.IsZero() && !q.now().Before(task.deadline) {
+		return fmt.Errorf("%w: %w", taskqueue.ErrSkipRetry, context.DeadlineExceeded)
+	}
+
+	ctx, cancel := context.WithCancel(taskqueue.ContextWithExecutionInfo(q.rootCtx, task.executionInfo()))
+	defer cancel()
+	stop := q.heartbeat(ctx, task, cancel)
+	defer stop()
+	if task.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, task.timeout)
+		defer cancel()
+	}
+	if !task.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, task.deadline)
+		defer cancel()
+	}
+	return q.process(ctx, task.task)
+}
+
+// heartbeat renews the lease of task every third of the lease while it is
+// processed, so a long-running processor keeps its claim. When the lease is
+// lost to another worker, the processing context is canceled. The returned
+// function stops the heartbeat.
+func (q *Queue) heartbeat(ctx context.Context, task claimedTask, lost context.CancelFunc) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(max(task.lease/3, time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			now := q.now()
+			result, err := q.db.ExecContext(context.WithoutCancel(ctx), q.dialect.rebind("UPDATE "+q.table+
+				" SET locked_until = ?, updated_at = ? WHERE id = ? AND status = ? AND claimed_by = ?"),
+				now.Add(task.lease).UnixNano(), now.UnixNano(), task.id, string(StatusProcessing), q.workerID,
+			)
+			if err != nil {
+				q.logger.Error("taskqueue sql lease renewal failed", slog.String("task_id", task.id), slog.Any("error", err))
+				continue
+			}
+			if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+				q.logger.Warn("taskqueue sql lease lost", slog.String("task_id", task.id))
+				lost()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// finish records the attempt outcome. Updates are guarded by claimed_by so a
+// worker whose lease expired cannot overwrite another worker's claim. A
+// taskqueue.RetryLaterError reschedules the task without counting a retry.
+func (q *Queue) finish(task claimedTask, cause error) error {
+	ctx := context.WithoutCancel(q.rootCtx)
+	now := q.now()
+	if cause == nil {
+		if _, err := q.db.ExecContext(ctx, q.dialect.rebind("DELETE FROM "+q.table+" WHERE id = ? AND claimed_by = ?"),
+			task.id, q.workerID); err != nil {
+			return fmt.Errorf("delete completed task: %w", err)
+		}
+		return q.releaseUnique(ctx, task)
+	}
+	if delay, later := taskqueue.RetryLaterDelay(cause); later {
+		// Not a failure: keep retry_count, attempts, and last_error as they are.
+		if _, err := q.db.ExecContext(ctx, q.dialect.rebind("UPDATE "+q.table+
+			" SET status = ?, process_at = ?, locked_until = ?, claimed_by = ?, updated_at = ? WHERE id = ? AND claimed_by = ?"),
+			string(StatusPending), now.Add(delay).UnixNano(), int64(0), "", now.UnixNano(), task.id, q.workerID,
+		); err != nil {
+			return fmt.Errorf("reschedule deferred task: %w", err)
+		}
+		return nil
+	}
+	attempts, err := encodeAttempts(task.attempts)
+	if err != nil {
+		return fmt.Errorf("encode task attempts: %w", err)
+	}
+	if delay, retry := q.nextRetry(task, cause); retry {
+		_, err := q.db.ExecContext(ctx, q.dialect.rebind("UPDATE "+q.table+
+			" SET status = ?, retry_count = ?, process_at = ?, locked_until = ?, claimed_by = ?, last_error = ?, attempts = ?, updated_at = ?"+
+			" WHERE id = ? AND claimed_by = ?"),
+			string(StatusPending), task.retryCount+1, now.Add(delay).UnixNano(), int64(0), "", cause.Error(), attempts, now.UnixNano(),
+			task.id, q.workerID,
+		)
+		if err != nil {
+			return fmt.Errorf("reschedule failed task: %w", err)
+		}
+		return nil
+	}
+	result, err := q.db.ExecContext(ctx, q.dialect.rebind("UPDATE "+q.table+
+		" SET status = ?, locked_until = ?, last_error = ?, attempts = ?, updated_at = ? WHERE id = ? AND claimed_by = ?"),
+		string(StatusArchived), int64(0), cause.Error(), attempts, now.UnixNano(), task.id, q.workerID,
+	)
+	if err != nil {
+		return fmt.Errorf("archive failed task: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 1 {
+		q.deadLetter(ctx, task, cause)
+	}
+	return q.releaseUnique(ctx, task)
+}
+
+// deadLetter reports an archived task to the configured sink. Sink failures
+// are logged; the archived row remains the durable record.
+func (q *Queue) deadLetter(ctx context.Context, task claimedTask, cause error) {
+	if q.deadLetters == nil {
+		return
+	}
+	letter := taskqueue.DeadLetter{
+		Task:     task.task,
+		Info:     task.executionInfo(),
+		Err:      cause,
+		Attempts: task.attempts,
+	}
+	if err := q.deadLetters.Put(ctx, letter); err != nil {
+		q.logger.Error("taskqueue dead letter failed",
+			slog.String("task_id", task.id), slog.String("task_type", string(task.task.Type())), slog.Any("error", err))
+	}
+}
+
+func (q *Queue) nextRetry(task claimedTask, err error) (time.Duration, bool) {
+	if errors.Is(err, taskqueue.ErrSkipRetry) || task.retryCount >= task.maxRetry {
+		return 0, false
+	}
+	decision := q.retryPolicy.NextRetry(task.task, task.executionInfo(), err)
+	return max(decision.Delay, 0), decision.Retry
+}
+
+func (t claimedTask) executionInfo() taskqueue.ExecutionInfo {
+	return taskqueue.NewExecutionInfo(
+		taskqueue.WithExecutionTaskID(t.id),
+		taskqueue.WithExecutionQueue(queueName(t.task.Queue())),
+		taskqueue.WithExecutionRetryCount(t.retryCount),
+		taskqueue.WithExecutionMaxRetry(t.maxRetry),
+	)
+}
+
+func (q *Queue) releaseUnique(ctx context.Context, task claimedTask) error {
+	if task.uniqueKey == "" {
+		return nil
+	}
+	if _, err := q.db.ExecContext(ctx, q.dialect.rebind("DELETE FROM "+q.table+"_unique WHERE unique_key = ? AND task_id = ?"),
+		task.uniqueKey, task.id); err != nil {
+		return fmt.Errorf("release unique lock: %w", err)
+	}
+	return nil
+}
+
+func queueName(queue string) string {
+	if queue == "" {
+		return DefaultQueue
+	}
+	return queue
+}
+
+// uniqueKey hashes the task identity so it fits a fixed-width key column. An
+// empty queue hashes as DefaultQueue, the queue the task runs on.
+func uniqueKey(task taskqueue.Task) string {
+	hash := sha256.New()
+	hash.Write([]byte(task.Type()))
+	hash.Write([]byte{0})
+	hash.Write([]byte(queueName(task.Queue())))
+	hash.Write([]byte{0})
+	if task.Key() != "" {
+		hash.Write([]byte(task.Key()))
+	} else {
+		hash.Write(task.Payload())
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// attemptRecord is the persisted JSON form of taskqueue.Attempt.
+type attemptRecord struct {
+	RetryCount int    `json:"retry_count"`
+	StartedAt  int64  `json:"started_at"`
+	FinishedAt int64  `json:"finished_at"`
+	Error      string `json:"error"`
+}
+
+func encodeAttempts(attempts []taskqueue.Attempt) (string, error) {
+	if len(attempts) == 0 {
+		return "", nil
+	}
+	records := make([]attemptRecord, len(attempts))
+	for i, attempt := range attempts {
+		records[i] = attemptRecord{
+			RetryCount: attempt.RetryCount,
+			StartedAt:  unixNano(attempt.StartedAt),
+			FinishedAt: unixNano(attempt.FinishedAt),
+			Error:      attempt.Error,
+		}
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func decodeAttempts(data string) ([]taskqueue.Attempt, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var records []attemptRecord
+	if err := json.Unmarshal([]byte(data), &records); err != nil {
+		return nil, err
+	}
+	attempts := make([]taskqueue.Attempt, len(records))
+	for i, record := range records {
+		attempts[i] = taskqueue.Attempt{
+			RetryCount: record.RetryCount,
+			StartedAt:  fromUnixNano(record.StartedAt),
+			FinishedAt: fromUnixNano(record.FinishedAt),
+			Error:      record.Error,
+		}
+	}
+	return attempts, nil
+}
+
+func encodeHeaders(headers map[string]string) (string, error) {
+	if len(headers) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(headers)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func decodeHeaders(data string) (map[string]string, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(data), &headers); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+func generateID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
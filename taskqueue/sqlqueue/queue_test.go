@@ -0,0 +1,491 @@
+package sqlqueue_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+	"github.com/go-jimu/components/taskqueue/sqlqueue"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const waitTimeout = 2 * time.Second
+
+// Enqueue should persist the full task envelope and enqueue policy so another
+// process can restore and execute the task.
+func TestQueue_EnqueuePersistsEnvelopeAndPolicy(t *testing.T) {
+	db := openDB(t)
+	now := time.Date(2026, 5, 28, 10, 0, 0, 0, time.UTC)
+	queue := newQueue(t, db, taskqueue.NewRouter(), sqlqueue.WithClock(func() time.Time { return now }))
+	task, err := taskqueue.NewJSONTask(
+		taskqueue.Definition{Type: "email.welcome", Queue: "mailers"},
+		map[string]string{"user_id": "user-1"},
+		taskqueue.WithKey("user-1"),
+		taskqueue.WithHeader("trace-id", "trace-1"),
+	)
+	if err != nil {
+		t.Fatalf("NewJSONTask: %v", err)
+	}
+
+	if err := queue.Enqueue(context.Background(), task, taskqueue.WithDelay(time.Minute), taskqueue.WithMaxRetry(7), taskqueue.WithTimeout(time.Second)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	var (
+		taskType, queueName, codec, key, headers, status string
+		payload                                          []byte
+		maxRetry                                         int
+		timeout, processAt                               int64
+	)
+	err = db.QueryRow(`SELECT task_type, queue, payload, payload_codec, task_key, headers, status, max_retry, timeout_ns, process_at FROM taskqueue_tasks`).
+		Scan(&taskType, &queueName, &payload, &codec, &key, &headers, &status, &maxRetry, &timeout, &processAt)
+	if err != nil {
+		t.Fatalf("select task: %v", err)
+	}
+	if taskType != "email.welcome" || queueName != "mailers" || key != "user-1" || codec != taskqueue.JSONCodec {
+		t.Fatalf("envelope = %q %q %q %q", taskType, queueName, key, codec)
+	}
+	if string(payload) != `{"user_id":"user-1"}` {
+		t.Fatalf("payload = %s", payload)
+	}
+	if headers != `{"trace-id":"trace-1"}` {
+		t.Fatalf("headers = %s", headers)
+	}
+	if status != string(sqlqueue.StatusPending) || maxRetry != 7 || time.Duration(timeout) != time.Second {
+		t.Fatalf("policy = %q %d %v", status, maxRetry, time.Duration(timeout))
+	}
+	if processAt != now.Add(time.Minute).UnixNano() {
+		t.Fatalf("process at = %v", time.Unix(0, processAt).UTC())
+	}
+}
+
+// Workers should claim due rows, dispatch the restored task with execution
+// metadata, and delete the row after success.
+func TestQueue_ProcessesAndDeletesCompletedTask(t *testing.T) {
+	db := openDB(t)
+	router := taskqueue.NewRouter()
+	seen := make(chan taskqueue.Task, 1)
+	infos := make(chan taskqueue.ExecutionInfo, 1)
+	mustRegister(t, router, func(ctx context.Context, task taskqueue.Task) error {
+		info, _ := taskqueue.ExecutionInfoFromContext(ctx)
+		infos <- info
+		seen <- task
+		return nil
+	})
+	queue := newQueue(t, db, router, sqlqueue.WithPollInterval(10*time.Millisecond))
+	task := newTask(t, "user-1")
+	if err := queue.Enqueue(context.Background(), task, taskqueue.WithMaxRetry(2)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	startQueue(t, queue)
+
+	got := receive(t, seen)
+	if got.Type() != task.Type() || got.Key() != "user-1" || string(got.Payload()) != string(task.Payload()) {
+		t.Fatalf("restored task = %s %s %s", got.Type(), got.Key(), got.Payload())
+	}
+	info := receive(t, infos)
+	if info.TaskID() == "" || info.Queue() != "mailers" {
+		t.Fatalf("execution info = %q %q", info.TaskID(), info.Queue())
+	}
+	if maxRetry, _ := info.MaxRetry(); maxRetry != 2 {
+		t.Fatalf("max retry = %d, want 2", maxRetry)
+	}
+	waitFor(t, func() bool { return countRows(t, db, "") == 0 })
+}
+
+// Failed attempts should be rescheduled until max retry is exhausted and then
+// archived with the last error for operational visibility.
+func TestQueue_RetriesThenArchivesFailedTask(t *testing.T) {
+	db := openDB(t)
+	router := taskqueue.NewRouter()
+	attempts := make(chan int, 3)
+	mustRegister(t, router, func(ctx context.Context, _ taskqueue.Task) error {
+		info, _ := taskqueue.ExecutionInfoFromContext(ctx)
+		retryCount, _ := info.RetryCount()
+		attempts <- retryCount
+		return errors.New("smtp unavailable")
+	})
+	queue := newQueue(t, db, router, sqlqueue.WithPollInterval(5*time.Millisecond), sqlqueue.WithRetryBackoff(0))
+	if err := queue.Enqueue(context.Background(), newTask(t, "user-1"), taskqueue.WithMaxRetry(2)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	startQueue(t, queue)
+
+	for want := 0; want <= 2; want++ {
+		if got := receive(t, attempts); got != want {
+			t.Fatalf("retry count = %d, want %d", got, want)
+		}
+	}
+	waitFor(t, func() bool { return countRows(t, db, sqlqueue.StatusArchived) == 1 })
+	var lastError string
+	if err := db.QueryRow(`SELECT last_error FROM taskqueue_tasks`).Scan(&lastError); err != nil {
+		t.Fatalf("select last error: %v", err)
+	}
+	if lastError != "smtp unavailable" {
+		t.Fatalf("last error = %q", lastError)
+	}
+}
+
+// Archived tasks should be reported to the dead letter sink with the attempt
+// history persisted across retries.
+func TestQueue_SendsArchivedTaskToDeadLetterSink(t *testing.T) {
+	db := openDB(t)
+	router := taskqueue.NewRouter()
+	mustRegister(t, router, func(context.Context, taskqueue.Task) error {
+		return errors.New("smtp unavailable")
+	})
+	letters := make(chan taskqueue.DeadLetter, 1)
+	sink := taskqueue.DeadLetterSinkFunc(func(_ context.Context, letter taskqueue.DeadLetter) error {
+		letters <- letter
+		return nil
+	})
+	queue := newQueue(t, db, router,
+		sqlqueue.WithPollInterval(5*time.Millisecond),
+		sqlqueue.WithRetryBackoff(0),
+		sqlqueue.WithDeadLetterSink(sink),
+	)
+	if err := queue.Enqueue(context.Background(), newTask(t, "user-1"), taskqueue.WithMaxRetry(1)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	startQueue(t, queue)
+
+	letter := receive(t, letters)
+	if letter.Task.Key() != "user-1" || letter.Err.Error() != "smtp unavailable" {
+		t.Fatalf("letter = %#v", letter)
+	}
+	if len(letter.Attempts) != 2 || letter.Attempts[0].RetryCount != 0 || letter.Attempts[1].RetryCount != 1 {
+		t.Fatalf("attempts = %#v, want retry counts 0 and 1", letter.Attempts)
+	}
+	if countRows(t, db, sqlqueue.StatusArchived) != 1 {
+		t.Fatal("dead-lettered task was not archived")
+	}
+}
+
+// The retry policy delay should set the next process_at of a failed task.
+func TestQueue_RetryPolicySchedulesNextAttempt(t *testing.T) {
+	db := openDB(t)
+	router := taskqueue.NewRouter()
+	attempted := make(chan struct{}, 1)
+	mustRegister(t, router, func(context.Context, taskqueue.Task) error {
+		attempted <- struct{}{}
+		return errors.New("smtp unavailable")
+	})
+	now := time.Now()
+	queue := newQueue(t, db, router,
+		sqlqueue.WithPollInterval(5*time.Millisecond),
+		sqlqueue.WithClock(func() time.Time { return now }),
+		sqlqueue.WithRetryPolicy(taskqueue.NewRetryPolicy(taskqueue.ExponentialBackoff(time.Minute, time.Hour))),
+	)
+	if err := queue.Enqueue(context.Background(), newTask(t, "user-1"), taskqueue.WithMaxRetry(2)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	startQueue(t, queue)
+
+	receive(t, attempted)
+	waitFor(t, func() bool {
+		var retryCount int
+		var processAt int64
+		if err := db.QueryRow(`SELECT retry_count, process_at FROM taskqueue_tasks`).Scan(&retryCount, &processAt); err != nil {
+			t.Fatalf("select retry: %v", err)
+		}
+		return retryCount == 1 && processAt == now.Add(time.Minute).UnixNano()
+	})
+}
+
+// A retry-later error should reschedule the task without touching its retry
+// count, attempt history, or last error.
+func TestQueue_RetryLaterKeepsRetryBudget(t *testing.T) {
+	db := openDB(t)
+	router := taskqueue.NewRouter()
+	attempted := make(chan struct{}, 1)
+	mustRegister(t, router, func(context.Context, taskqueue.Task) error {
+		attempted <- struct{}{}
+		return taskqueue.RetryLater(time.Minute, "partner quota")
+	})
+	now := time.Now()
+	queue := newQueue(t, db, router,
+		sqlqueue.WithPollInterval(5*time.Millisecond),
+		sqlqueue.WithClock(func() time.Time { return now }),
+	)
+	if err := queue.Enqueue(context.Background(), newTask(t, "user-1"), taskqueue.WithMaxRetry(0)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	startQueue(t, queue)
+
+	receive(t, attempted)
+	waitFor(t, func() bool {
+		var status, lastError, attempts string
+		var retryCount int
+		var processAt int64
+		if err := db.QueryRow(`SELECT status, retry_count, process_at, last_error, attempts FROM taskqueue_tasks`).
+			Scan(&status, &retryCount, &processAt, &lastError, &attempts); err != nil {
+			t.Fatalf("select task: %v", err)
+		}
+		return status == string(sqlqueue.StatusPending) && retryCount == 0 && lastError == "" &&
+			attempts == "" && processAt == now.Add(time.Minute).UnixNano()
+	})
+}
+
+// Unique enqueue should reject duplicates while the lock is live and accept the
+// task again once the uniqueness window has expired.
+func TestQueue_UniqueLockRejectsDuplicatesUntilExpiry(t *testing.T) {
+	db := openDB(t)
+	now := time.Date(2026, 5, 28, 10, 0, 0, 0, time.UTC)
+	queue := newQueue(t, db, taskqueue.NewRouter(), sqlqueue.WithClock(func() time.Time { return now }))
+	task := newTask(t, "user-1")
+
+	if err := queue.Enqueue(context.Background(), task, taskqueue.WithUnique(time.Minute)); err != nil {
+		t.Fatalf("first Enqueue: %v", err)
+	}
+	if err := queue.Enqueue(context.Background(), task, taskqueue.WithUnique(time.Minute)); !errors.Is(err, taskqueue.ErrDuplicateTask) {
+		t.Fatalf("duplicate error = %v, want ErrDuplicateTask", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := queue.Enqueue(context.Background(), task, taskqueue.WithUnique(time.Minute)); err != nil {
+		t.Fatalf("Enqueue after expiry: %v", err)
+	}
+	if got := countRows(t, db, sqlqueue.StatusPending); got != 2 {
+		t.Fatalf("pending rows = %d, want 2", got)
+	}
+}
+
+// A task without a queue runs on DefaultQueue, so it must collide with the
+// same task enqueued on DefaultQueue explicitly, as it does in memory.
+func TestQueue_UniqueLockTreatsEmptyQueueAsDefault(t *testing.T) {
+	queue := newQueue(t, openDB(t), taskqueue.NewRouter())
+	for i, name := range []string{"", sqlqueue.DefaultQueue} {
+		task, err := taskqueue.NewJSONTask(taskqueue.Definition{Type: "email.welcome", Queue: name}, struct{}{}, taskqueue.WithKey("user-1"))
+		if err != nil {
+			t.Fatalf("NewJSONTask: %v", err)
+		}
+		err = queue.Enqueue(context.Background(), task, taskqueue.WithUnique(time.Minute))
+		if i == 0 && err != nil {
+			t.Fatalf("first Enqueue: %v", err)
+		}
+		if i == 1 && !errors.Is(err, taskqueue.ErrDuplicateTask) {
+			t.Fatalf("Enqueue on %q error = %v, want ErrDuplicateTask", name, err)
+		}
+	}
+}
+
+// Rows held under a live lease must not be claimed, while rows whose lease has
+// expired are reclaimed from crashed workers.
+func TestQueue_ReclaimsOnlyExpiredLeases(t *testing.T) {
+	db := openDB(t)
+	router := taskqueue.NewRouter()
+	seen := make(chan string, 2)
+	mustRegister(t, router, func(_ context.Context, task taskqueue.Task) error {
+		seen <- task.Key()
+		return nil
+	})
+	queue := newQueue(t, db, router, sqlqueue.WithPollInterval(5*time.Millisecond))
+	for _, key := range []string{"expired", "live"} {
+		if err := queue.Enqueue(context.Background(), newTask(t, key)); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	now := time.Now()
+	lease := map[string]time.Time{"expired": now.Add(-time.Minute), "live": now.Add(time.Hour)}
+	for key, lockedUntil := range lease {
+		if _, err := db.Exec(`UPDATE taskqueue_tasks SET status = ?, locked_until = ?, claimed_by = ? WHERE task_key = ?`,
+			string(sqlqueue.StatusProcessing), lockedUntil.UnixNano(), "crashed-worker", key); err != nil {
+			t.Fatalf("lease task: %v", err)
+		}
+	}
+	startQueue(t, queue)
+
+	if got := receive(t, seen); got != "expired" {
+		t.Fatalf("processed %q, want expired", got)
+	}
+	expectNone(t, seen)
+}
+
+// A lease-expired reclaim should count as a retry, and a row whose retries
+// are spent should be dead-lettered instead of being processed again.
+func TestQueue_ReclaimCountsAsRetry(t *testing.T) {
+	db := openDB(t)
+	router := taskqueue.NewRouter()
+	seen := make(chan int, 2)
+	mustRegister(t, router, func(ctx context.Context, _ taskqueue.Task) error {
+		info, _ := taskqueue.ExecutionInfoFromContext(ctx)
+		retryCount, _ := info.RetryCount()
+		seen <- retryCount
+		return nil
+	})
+	letters := make(chan taskqueue.DeadLetter, 1)
+	sink := taskqueue.DeadLetterSinkFunc(func(_ context.Context, letter taskqueue.DeadLetter) error {
+		letters <- letter
+		return nil
+	})
+	queue := newQueue(t, db, router, sqlqueue.WithPollInterval(5*time.Millisecond), sqlqueue.WithDeadLetterSink(sink))
+	for key, retryCount := range map[string]int{"crashed-once": 0, "crashed-twice": 1} {
+		if err := queue.Enqueue(context.Background(), newTask(t, key), taskqueue.WithMaxRetry(1)); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		if _, err := db.Exec(`UPDATE taskqueue_tasks SET status = ?, retry_count = ?, locked_until = ?, claimed_by = ? WHERE task_key = ?`,
+			string(sqlqueue.StatusProcessing), retryCount, time.Now().Add(-time.Minute).UnixNano(), "crashed-worker", key); err != nil {
+			t.Fatalf("lease task: %v", err)
+		}
+	}
+	startQueue(t, queue)
+
+	if got := receive(t, seen); got != 1 {
+		t.Fatalf("reclaimed task ran with retry count %d, want 1", got)
+	}
+	letter := receive(t, letters)
+	if letter.Task.Key() != "crashed-twice" || !errors.Is(letter.Err, sqlqueue.ErrLeaseExpired) {
+		t.Fatalf("letter = %s: %v, want crashed-twice with ErrLeaseExpired", letter.Task.Key(), letter.Err)
+	}
+	expectNone(t, seen)
+}
+
+// A processor running longer than the lease should keep its claim, so a
+// second worker does not process the same task.
+func TestQueue_HeartbeatRenewsLease(t *testing.T) {
+	db := openDB(t)
+	router := taskqueue.NewRouter()
+	seen := make(chan string, 2)
+	mustRegister(t, router, func(_ context.Context, task taskqueue.Task) error {
+		seen <- task.Key()
+		time.Sleep(300 * time.Millisecond)
+		return nil
+	})
+	opts := []sqlqueue.Option{sqlqueue.WithPollInterval(5 * time.Millisecond), sqlqueue.WithLease(60 * time.Millisecond)}
+	first := newQueue(t, db, router, append(opts, sqlqueue.WithWorkerID("worker-1"))...)
+	if err := first.Enqueue(context.Background(), newTask(t, "slow")); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	startQueue(t, first)
+	receive(t, seen)
+	startQueue(t, newQueue(t, db, router, append(opts, sqlqueue.WithWorkerID("worker-2"))...))
+
+	select {
+	case key := <-seen:
+		t.Fatalf("task %q was processed twice", key)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// Invalid configuration should be rejected before any SQL is executed.
+func TestNew_ValidationErrors(t *testing.T) {
+	db := openDB(t)
+	router := taskqueue.NewRouter()
+	if _, err := sqlqueue.New(nil, sqlqueue.DialectSQLite, router); !errors.Is(err, sqlqueue.ErrNilDB) {
+		t.Fatalf("nil db error = %v", err)
+	}
+	if _, err := sqlqueue.New(db, "oracle", router); !errors.Is(err, sqlqueue.ErrUnknownDialect) {
+		t.Fatalf("dialect error = %v", err)
+	}
+	if _, err := sqlqueue.New(db, sqlqueue.DialectSQLite, nil); !errors.Is(err, sqlqueue.ErrNilRouter) {
+		t.Fatalf("nil router error = %v", err)
+	}
+	if _, err := sqlqueue.New(db, sqlqueue.DialectSQLite, router, sqlqueue.WithTable("tasks; DROP TABLE x")); !errors.Is(err, sqlqueue.ErrInvalidTable) {
+		t.Fatalf("table error = %v", err)
+	}
+}
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "tasks.db")+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func newQueue(t *testing.T, db *sql.DB, router *taskqueue.Router, opts ...sqlqueue.Option) *sqlqueue.Queue {
+	t.Helper()
+	queue, err := sqlqueue.New(db, sqlqueue.DialectSQLite, router, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := queue.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	return queue
+}
+
+func startQueue(t *testing.T, queue *sqlqueue.Queue) {
+	t.Helper()
+	if err := queue.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+		defer cancel()
+		if err := queue.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	})
+}
+
+func mustRegister(t *testing.T, router *taskqueue.Router, fn taskqueue.ProcessorFunc) {
+	t.Helper()
+	if err := router.Register(taskqueue.NewProcessor("email.welcome", fn)); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+}
+
+func newTask(t *testing.T, key string) taskqueue.Task {
+	t.Helper()
+	task, err := taskqueue.NewJSONTask(taskqueue.Definition{Type: "email.welcome", Queue: "mailers"}, struct{}{}, taskqueue.WithKey(key))
+	if err != nil {
+		t.Fatalf("NewJSONTask: %v", err)
+	}
+	return task
+}
+
+func countRows(t *testing.T, db *sql.DB, status sqlqueue.Status) int {
+	t.Helper()
+	query := `SELECT COUNT(*) FROM taskqueue_tasks`
+	args := []any{}
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, string(status))
+	}
+	var count int
+	if err := db.QueryRow(query, args...).Scan(&count); err != nil {
+		t.Fatalf("count rows: %v", err)
+	}
+	return count
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for condition")
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case value := <-ch:
+		return value
+	case <-time.After(waitTimeout):
+		t.Fatal("timed out waiting for value")
+		var zero T
+		return zero
+	}
+}
+
+func expectNone[T any](t *testing.T, ch <-chan T) {
+	t.Helper()
+	select {
+	case value := <-ch:
+		t.Fatalf("unexpected value %v", value)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
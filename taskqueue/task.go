@@ -53,9 +53,13 @@ func New(def Definition, payload []byte, opts ...Option) (Task, error) {
 			opt(&cfg)
 		}
 	}
+	sealed, err := sealPayload(def.Type, payload, &cfg)
+	if err != nil {
+		return Task{}, err
+	}
 	return Task{
 		def:          def,
-		payload:      append([]byte(nil), payload...),
+		payload:      append([]byte(nil), sealed...),
 		payloadCodec: cfg.payloadCodec,
 		key:          cfg.key,
 		headers:      cloneHeaders(cfg.headers),
@@ -94,12 +98,12 @@ func NewProtoTask(def Definition, payload any, opts ...Option) (Task, error) {
 }
 
 // DecodePayload decodes task payload into target using task.PayloadCodec().
-func DecodePayload(task Task, target any) error {
-	return DecodePayloadWithCodec(task, task.PayloadCodec(), target)
+func DecodePayload(task Task, target any, opts ...DecodeOption) error {
+	return DecodePayloadWithCodec(task, task.PayloadCodec(), target, opts...)
 }
 
 // DecodePayloadWithCodec decodes task payload into target using codecName.
-func DecodePayloadWithCodec(task Task, codecName string, target any) error {
+func DecodePayloadWithCodec(task Task, codecName string, target any, opts ...DecodeOption) error {
 	if target == nil {
 		return ErrNilDecodeTarget
 	}
@@ -107,23 +111,27 @@ func DecodePayloadWithCodec(task Task, codecName string, target any) error {
 	if err != nil {
 		return err
 	}
-	if len(task.payload) == 0 {
+	payload, err := openPayload(task, newDecodeConfig(opts))
+	if err != nil {
+		return err
+	}
+	if len(payload) == 0 {
 		return nil
 	}
-	if err := codec.Unmarshal(task.payload, target); err != nil {
+	if err := codec.Unmarshal(payload, target); err != nil {
 		return fmt.Errorf("%w: %w", ErrSkipRetry, err)
 	}
 	return nil
 }
 
 // DecodeJSON decodes a JSON task payload into target.
-func DecodeJSON(task Task, target any) error {
-	return DecodePayloadWithCodec(task, JSONCodec, target)
+func DecodeJSON(task Task, target any, opts ...DecodeOption) error {
+	return DecodePayloadWithCodec(task, JSONCodec, target, opts...)
 }
 
 // DecodeProto decodes a protobuf task payload into target.
-func DecodeProto(task Task, target any) error {
-	return DecodePayloadWithCodec(task, ProtoCodec, target)
+func DecodeProto(task Task, target any, opts ...DecodeOption) error {
+	return DecodePayloadWithCodec(task, ProtoCodec, target, opts...)
 }
 
 // Type returns the semantic task contract type.
@@ -141,7 +149,9 @@ func (t Task) Definition() Definition {
 	return t.def
 }
 
-// Payload returns a copy of the task payload bytes.
+// Payload returns a copy of the task payload bytes as stored, compressed or
+// encrypted when the task was built with WithCompression or WithEncryption.
+// DecodePayload reverses those transformations.
 func (t Task) Payload() []byte {
 	return append([]byte(nil), t.payload...)
 }
@@ -151,7 +161,8 @@ func (t Task) PayloadCodec() string {
 	return t.payloadCodec
 }
 
-// Key returns the task idempotency or ordering key.
+// Key returns the task idempotency or ordering key. OrderByKey and provider
+// key ordering serialize processing of tasks that share it.
 func (t Task) Key() string {
 	return t.key
 }
@@ -161,6 +172,30 @@ func (t Task) Headers() map[string]string {
 	return cloneHeaders(t.headers)
 }
 
+// WithQueue returns a copy of t assigned to queue.
+func (t Task) WithQueue(queue string) Task {
+	t.def.Queue = queue
+	return t
+}
+
+// WithHeaders returns a copy of t with headers merged over its existing
+// headers. It lets adapters and middleware add metadata such as trace context
+// without rebuilding the envelope.
+func (t Task) WithHeaders(headers map[string]string) Task {
+	if len(headers) == 0 {
+		return t
+	}
+	merged := make(map[string]string, len(t.headers)+len(headers))
+	for key, value := range t.headers {
+		merged[key] = value
+	}
+	for key, value := range headers {
+		merged[key] = value
+	}
+	t.headers = merged
+	return t
+}
+
 func cloneHeaders(headers map[string]string) map[string]string {
 	if len(headers) == 0 {
 		return nil
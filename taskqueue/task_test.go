@@ -68,6 +68,27 @@ func TestNewJSONTask_PreservesDefinitionAndCopiesHeaders(t *testing.T) {
 	}
 }
 
+// Task.WithHeaders should merge metadata into a copy so middleware can add
+// headers without mutating the caller's envelope.
+func TestTask_WithHeadersMergesIntoCopy(t *testing.T) {
+	task, err := New(Definition{Type: "document.review.v1"}, nil, WithKey("doc-1"), WithHeader("source", "scheduler"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	traced := task.WithHeaders(map[string]string{"traceparent": "00-abc", "source": "relay"})
+
+	if got := traced.Headers(); !reflect.DeepEqual(got, map[string]string{"source": "relay", "traceparent": "00-abc"}) {
+		t.Fatalf("headers = %#v", got)
+	}
+	if got := task.Headers(); !reflect.DeepEqual(got, map[string]string{"source": "scheduler"}) {
+		t.Fatalf("original headers = %#v", got)
+	}
+	if traced.Key() != "doc-1" || traced.Type() != task.Type() {
+		t.Fatalf("traced task lost envelope fields: %#v", traced)
+	}
+}
+
 // DecodePayload should use the codec carried by the task envelope so provider
 // adapters can decode persisted payload bytes without treating JSON as schema.
 func TestDecodePayload_UsesEnvelopePayloadCodec(t *testing.T) {
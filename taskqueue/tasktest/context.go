@@ -0,0 +1,17 @@
+package tasktest
+
+import (
+	"context"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// ExecutionContext returns ctx carrying the taskqueue.ExecutionInfo built
+// from opts, as a provider passes it to a processor. A nil ctx means
+// context.Background.
+func ExecutionContext(ctx context.Context, opts ...taskqueue.ExecutionInfoOption) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return taskqueue.ContextWithExecutionInfo(ctx, taskqueue.NewExecutionInfo(opts...))
+}
@@ -0,0 +1,15 @@
+// Package tasktest provides test doubles for code built on taskqueue.
+//
+// Recorder is an Enqueuer that records tasks and their enqueue policy for
+// assertions in producer tests. Runner is a synchronous provider: it keeps
+// enqueued and periodic tasks on a controllable Clock and processes the due
+// ones through a Router when the test calls RunDue or Advance, with
+// taskqueue.ExecutionInfo, retries, deadlines, and RetryLaterError handled the
+// way in-process providers handle them. Periodic fire times missed in one
+// clock move follow the scheduler.MissedFirePolicy, as in scheduler.Scheduler.
+// ExecutionContext builds the context a
+// provider would pass to a processor under test.
+//
+// Clock also satisfies scheduler.Clock and can drive the memory provider
+// through memory.WithClock(clock.Now).
+package tasktest
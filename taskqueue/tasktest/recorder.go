@@ -0,0 +1,154 @@
+package tasktest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// Enqueued is a task recorded by a Recorder.
+type Enqueued struct {
+	Task    taskqueue.Task
+	Options taskqueue.EnqueueOptions
+}
+
+// Recorder is a taskqueue.Enqueuer that records every accepted task.
+type Recorder struct {
+	mu       sync.Mutex
+	enqueued []Enqueued
+	err      error
+}
+
+var _ taskqueue.Enqueuer = (*Recorder)(nil)
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Enqueue validates task and its policy the way providers do and records
+// them. It returns the error set by FailWith without recording.
+func (r *Recorder) Enqueue(_ context.Context, task taskqueue.Task, opts ...taskqueue.EnqueueOption) error {
+	if task.Type() == "" {
+		return taskqueue.ErrEmptyType
+	}
+	policy := taskqueue.NewEnqueueOptions(opts...)
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.enqueued = append(r.enqueued, Enqueued{Task: task, Options: policy})
+	return nil
+}
+
+// FailWith makes later Enqueue calls return err. A nil err accepts tasks
+// again.
+func (r *Recorder) FailWith(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// Enqueued returns the recorded tasks in enqueue order.
+func (r *Recorder) Enqueued() []Enqueued {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Enqueued(nil), r.enqueued...)
+}
+
+// OfType returns the recorded tasks of taskType in enqueue order.
+func (r *Recorder) OfType(taskType taskqueue.TaskType) []Enqueued {
+	var matched []Enqueued
+	for _, enqueued := range r.Enqueued() {
+		if enqueued.Task.Type() == taskType {
+			matched = append(matched, enqueued)
+		}
+	}
+	return matched
+}
+
+// Reset forgets the recorded tasks.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enqueued = nil
+}
+
+// AssertEnqueued fails t unless a task of taskType was recorded, and returns
+// the latest one.
+func (r *Recorder) AssertEnqueued(t testing.TB, taskType taskqueue.TaskType) Enqueued {
+	t.Helper()
+	matched := r.OfType(taskType)
+	if len(matched) == 0 {
+		t.Fatalf("no %s task enqueued; enqueued %v", taskType, r.types())
+		return Enqueued{}
+	}
+	return matched[len(matched)-1]
+}
+
+// AssertNotEnqueued fails t if a task of taskType was recorded.
+func (r *Recorder) AssertNotEnqueued(t testing.TB, taskType taskqueue.TaskType) {
+	t.Helper()
+	if matched := r.OfType(taskType); len(matched) > 0 {
+		t.Fatalf("%d %s tasks enqueued, want none", len(matched), taskType)
+	}
+}
+
+// AssertCount fails t unless exactly n tasks of taskType were recorded.
+func (r *Recorder) AssertCount(t testing.TB, taskType taskqueue.TaskType, n int) {
+	t.Helper()
+	if matched := r.OfType(taskType); len(matched) != n {
+		t.Fatalf("%d %s tasks enqueued, want %d", len(matched), taskType, n)
+	}
+}
+
+func (r *Recorder) types() []taskqueue.TaskType {
+	var types []taskqueue.TaskType
+	for _, enqueued := range r.Enqueued() {
+		types = append(types, enqueued.Task.Type())
+	}
+	return types
+}
+
+// AssertOptions fails t unless e was enqueued with exactly the policy built
+// from want.
+func AssertOptions(t testing.TB, e Enqueued, want ...taskqueue.EnqueueOption) {
+	t.Helper()
+	if policy := taskqueue.NewEnqueueOptions(want...); !reflect.DeepEqual(e.Options, policy) {
+		t.Fatalf("%s enqueue options = %s, want %s", e.Task.Type(), describeOptions(e.Options), describeOptions(policy))
+	}
+}
+
+// DecodePayload decodes the payload of task through registry and fails t
+// unless it decodes into a *T.
+func DecodePayload[T any](t testing.TB, registry *taskqueue.SchemaRegistry, task taskqueue.Task) *T {
+	t.Helper()
+	decoded, err := registry.Decode(task)
+	if err != nil {
+		t.Fatalf("decode %s payload: %v", task.Type(), err)
+		return nil
+	}
+	payload, ok := decoded.(*T)
+	if !ok {
+		t.Fatalf("%s payload decodes to %T, want %T", task.Type(), decoded, payload)
+		return nil
+	}
+	return payload
+}
+
+func describeOptions(o taskqueue.EnqueueOptions) string {
+	maxRetry := "default"
+	if n, ok := o.MaxRetry(); ok {
+		maxRetry = fmt.Sprint(n)
+	}
+	return fmt.Sprintf("{delay=%s process_at=%s max_retry=%s timeout=%s deadline=%s unique=%s}",
+		o.Delay(), o.ProcessAt(), maxRetry, o.Timeout(), o.Deadline(), o.UniqueTTL())
+}
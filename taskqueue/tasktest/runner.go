@@ -0,0 +1,366 @@
+package tasktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+	"github.com/go-jimu/components/taskqueue/scheduler"
+)
+
+const (
+	// DefaultQueue is the execution queue reported for tasks without a
+	// Definition.Queue.
+	DefaultQueue = "default"
+
+	defaultMaxRetry     = 3
+	defaultRetryBackoff = time.Second
+)
+
+// Outcome is the result of one processing attempt by a Runner.
+type Outcome struct {
+	Task taskqueue.Task
+	Info taskqueue.ExecutionInfo
+	Err  error
+	// Final reports a failure that is not retried.
+	Final bool
+}
+
+// RunnerOption configures a Runner.
+type RunnerOption func(*Runner)
+
+// WithClock sets the clock that decides when tasks are due. By default a
+// Runner uses a Clock set to the Unix epoch.
+func WithClock(clock *Clock) RunnerOption {
+	return func(r *Runner) {
+		if clock != nil {
+			r.clock = clock
+		}
+	}
+}
+
+// WithMiddleware wraps router dispatch with middleware in declaration order.
+func WithMiddleware(middleware ...taskqueue.Middleware) RunnerOption {
+	return func(r *Runner) {
+		r.middleware = append(r.middleware, middleware...)
+	}
+}
+
+// WithMissedFirePolicy sets how periodic fire times passed in one clock move
+// are handled, as scheduler.WithMissedFirePolicy does for a Scheduler. The
+// default is scheduler.CoalesceMissedFires.
+func WithMissedFirePolicy(policy scheduler.MissedFirePolicy) RunnerOption {
+	return func(r *Runner) {
+		r.missed = policy
+	}
+}
+
+// WithRetryPolicy sets the policy that decides whether and when failed
+// attempts are retried. The default retries up to the task max retry, three
+// unless set at enqueue, one second apart.
+func WithRetryPolicy(policy taskqueue.RetryPolicy) RunnerOption {
+	return func(r *Runner) {
+		if policy != nil {
+			r.retryPolicy = policy
+		}
+	}
+}
+
+type job struct {
+	id        string
+	seq       int
+	task      taskqueue.Task
+	queue     string
+	processAt time.Time
+	deadline  time.Time
+	timeout   time.Duration
+	maxRetry  int
+	retried   int
+}
+
+type periodicJob struct {
+	periodic taskqueue.PeriodicTask
+	next     time.Time
+}
+
+// Runner is a synchronous taskqueue provider for tests. It implements
+// taskqueue.Enqueuer and taskqueue.PeriodicTaskRegistrar; nothing runs until
+// RunDue or Advance is called.
+type Runner struct {
+	mu          sync.Mutex
+	clock       *Clock
+	process     taskqueue.ProcessorFunc
+	middleware  []taskqueue.Middleware
+	retryPolicy taskqueue.RetryPolicy
+	missed      scheduler.MissedFirePolicy
+	jobs        []*job
+	periodic    map[string]*periodicJob
+	outcomes    []Outcome
+	seq         int
+}
+
+var (
+	_ taskqueue.Enqueuer              = (*Runner)(nil)
+	_ taskqueue.PeriodicTaskRegistrar = (*Runner)(nil)
+)
+
+// NewRunner returns a Runner that processes tasks through router.
+func NewRunner(router *taskqueue.Router, opts ...RunnerOption) *Runner {
+	r := &Runner{
+		clock:       NewClock(time.Unix(0, 0).UTC()),
+		retryPolicy: taskqueue.NewRetryPolicy(taskqueue.FixedBackoff(defaultRetryBackoff)),
+		periodic:    make(map[string]*periodicJob),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(r)
+		}
+	}
+	process := taskqueue.ProcessorFunc(func(context.Context, taskqueue.Task) error { return taskqueue.ErrUnhandledType })
+	if router != nil {
+		process = router.Process
+	}
+	r.process = taskqueue.Chain(process, append([]taskqueue.Middleware{taskqueue.Recover()}, r.middleware...)...)
+	return r
+}
+
+// Clock returns the clock of the runner.
+func (r *Runner) Clock() *Clock {
+	return r.clock
+}
+
+// Enqueue validates policy and stores task until it is due on the clock.
+// WithUnique is not enforced.
+func (r *Runner) Enqueue(_ context.Context, task taskqueue.Task, opts ...taskqueue.EnqueueOption) error {
+	if task.Type() == "" {
+		return taskqueue.ErrEmptyType
+	}
+	policy := taskqueue.NewEnqueueOptions(opts...)
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	now := r.clock.Now()
+	processAt := now
+	if delay := policy.Delay(); delay > 0 {
+		processAt = now.Add(delay)
+	} else if at := policy.ProcessAt(); !at.IsZero() {
+		processAt = at
+	}
+	maxRetry, ok := policy.MaxRetry()
+	if !ok {
+		maxRetry = defaultMaxRetry
+	}
+	queue := task.Queue()
+	if queue == "" {
+		queue = DefaultQueue
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	r.jobs = append(r.jobs, &job{
+		id:        fmt.Sprintf("task-%d", r.seq),
+		seq:       r.seq,
+		task:      task,
+		queue:     queue,
+		processAt: processAt,
+		deadline:  policy.Deadline(),
+		timeout:   policy.Timeout(),
+		maxRetry:  maxRetry,
+	})
+	return nil
+}
+
+// RegisterPeriodicTask registers periodic to be enqueued at each fire time
+// the clock passes. Fire times passed in one clock move follow the
+// WithMissedFirePolicy policy.
+func (r *Runner) RegisterPeriodicTask(periodic taskqueue.PeriodicTask) error {
+	if err := periodic.Validate(); err != nil {
+		return err
+	}
+	next, err := periodic.Schedule().Next(r.clock.Now())
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.periodic[periodic.Name()]; ok {
+		return taskqueue.ErrDuplicatePeriodicTask
+	}
+	r.periodic[periodic.Name()] = &periodicJob{periodic: periodic, next: next}
+	return nil
+}
+
+// Advance moves the clock forward by d and runs the tasks that are due.
+func (r *Runner) Advance(ctx context.Context, d time.Duration) []Outcome {
+	r.clock.Advance(d)
+	return r.RunDue(ctx)
+}
+
+// RunDue enqueues periodic tasks whose fire time has passed, then processes
+// due tasks one at a time in due order, including retries and tasks enqueued
+// by processors that are already due, until none is left. It returns the
+// outcome of each attempt.
+func (r *Runner) RunDue(ctx context.Context) []Outcome {
+	if err := r.firePeriodic(ctx); err != nil {
+		return []Outcome{{Err: err, Final: true}}
+	}
+	var outcomes []Outcome
+	for {
+		j := r.nextDue()
+		if j == nil {
+			break
+		}
+		outcomes = append(outcomes, r.run(ctx, j))
+	}
+	r.mu.Lock()
+	r.outcomes = append(r.outcomes, outcomes...)
+	r.mu.Unlock()
+	return outcomes
+}
+
+// Outcomes returns every attempt outcome so far.
+func (r *Runner) Outcomes() []Outcome {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Outcome(nil), r.outcomes...)
+}
+
+// Pending returns the tasks waiting to be processed, including scheduled
+// retries, in due order.
+func (r *Runner) Pending() []taskqueue.Task {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sortLocked()
+	tasks := make([]taskqueue.Task, len(r.jobs))
+	for i, j := range r.jobs {
+		tasks[i] = j.task
+	}
+	return tasks
+}
+
+func (r *Runner) firePeriodic(ctx context.Context) error {
+	now := r.clock.Now()
+	r.mu.Lock()
+	names := make([]string, 0, len(r.periodic))
+	for name := range r.periodic {
+		names = append(names, name)
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		r.mu.Lock()
+		p := r.periodic[name]
+		r.mu.Unlock()
+		if p.next.IsZero() || p.next.After(now) {
+			continue
+		}
+		periodic := p.periodic
+		missed := 0
+		for {
+			next, err := periodic.Schedule().Next(p.next)
+			if err != nil {
+				return fmt.Errorf("schedule periodic task %s: %w", name, err)
+			}
+			p.next = next
+			if next.IsZero() || next.After(now) {
+				break
+			}
+			missed++
+		}
+		if missed > 0 && r.missed == scheduler.SkipMissedFires {
+			continue
+		}
+		if err := r.Enqueue(ctx, periodic.Task(), taskqueue.WithEnqueueOptions(periodic.EnqueuePolicy())); err != nil {
+			return fmt.Errorf("enqueue periodic task %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// nextDue removes and returns the earliest due job.
+func (r *Runner) nextDue() *job {
+	now := r.clock.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sortLocked()
+	if len(r.jobs) == 0 || r.jobs[0].processAt.After(now) {
+		return nil
+	}
+	j := r.jobs[0]
+	r.jobs = r.jobs[1:]
+	return j
+}
+
+func (r *Runner) sortLocked() {
+	sort.SliceStable(r.jobs, func(a, b int) bool {
+		if !r.jobs[a].processAt.Equal(r.jobs[b].processAt) {
+			return r.jobs[a].processAt.Before(r.jobs[b].processAt)
+		}
+		return r.jobs[a].seq < r.jobs[b].seq
+	})
+}
+
+func (r *Runner) run(ctx context.Context, j *job) Outcome {
+	info := taskqueue.NewExecutionInfo(
+		taskqueue.WithExecutionTaskID(j.id),
+		taskqueue.WithExecutionQueue(j.queue),
+		taskqueue.WithExecutionRetryCount(j.retried),
+		taskqueue.WithExecutionMaxRetry(j.maxRetry),
+	)
+	outcome := Outcome{Task: j.task, Info: info}
+	if !j.deadline.IsZero() && !r.clock.Now().Before(j.deadline) {
+		outcome.Err = fmt.Errorf("%w: %w", taskqueue.ErrSkipRetry, context.DeadlineExceeded)
+		outcome.Final = true
+		return outcome
+	}
+
+	attemptCtx := taskqueue.ContextWithExecutionInfo(ctx, info)
+	if !j.deadline.IsZero() {
+		// The deadline is on the runner clock, so the context gets the time
+		// left until it rather than the fake instant itself.
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithDeadline(attemptCtx, time.Now().Add(j.deadline.Sub(r.clock.Now())))
+		defer cancel()
+	}
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(attemptCtx, j.timeout)
+		defer cancel()
+	}
+	outcome.Err = r.process(attemptCtx, j.task)
+	if outcome.Err == nil {
+		return outcome
+	}
+
+	now := r.clock.Now()
+	if delay, ok := taskqueue.RetryLaterDelay(outcome.Err); ok {
+		// Wait at least one tick so RunDue does not spin on the same task.
+		j.processAt = now.Add(max(delay, time.Nanosecond))
+		r.reschedule(j)
+		return outcome
+	}
+	decision := taskqueue.RetryDecision{}
+	if !errors.Is(outcome.Err, taskqueue.ErrSkipRetry) && j.retried < j.maxRetry {
+		decision = r.retryPolicy.NextRetry(j.task, info, outcome.Err)
+	}
+	if !decision.Retry {
+		outcome.Final = true
+		return outcome
+	}
+	j.retried++
+	j.processAt = now.Add(decision.Delay)
+	r.reschedule(j)
+	return outcome
+}
+
+func (r *Runner) reschedule(j *job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs = append(r.jobs, j)
+}
@@ -0,0 +1,222 @@
+package tasktest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+	"github.com/go-jimu/components/taskqueue/scheduler"
+	"github.com/go-jimu/components/taskqueue/tasktest"
+)
+
+type welcomeEmail struct {
+	UserID string `json:"user_id"`
+}
+
+// failureRecorder captures assertion failures instead of stopping the test.
+type failureRecorder struct {
+	testing.TB
+	failures []string
+}
+
+func (f *failureRecorder) Helper() {}
+
+func (f *failureRecorder) Fatalf(format string, args ...any) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+// A producer test should be able to assert the enqueued type, the payload
+// decoded through the registry, and the enqueue policy.
+func TestRecorder_AssertsEnqueuedTasks(t *testing.T) {
+	registry := taskqueue.NewSchemaRegistry()
+	if err := registry.Register(taskqueue.Definition{Type: "email.welcome"}, func() any { return &welcomeEmail{} }); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	recorder := tasktest.NewRecorder()
+	if err := taskqueue.Enqueue(context.Background(), recorder, registry, &welcomeEmail{UserID: "user-1"},
+		taskqueue.WithDelay(time.Minute), taskqueue.WithMaxRetry(5)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	enqueued := recorder.AssertEnqueued(t, "email.welcome")
+	if payload := tasktest.DecodePayload[welcomeEmail](t, registry, enqueued.Task); payload.UserID != "user-1" {
+		t.Fatalf("payload = %#v", payload)
+	}
+	tasktest.AssertOptions(t, enqueued, taskqueue.WithDelay(time.Minute), taskqueue.WithMaxRetry(5))
+	recorder.AssertCount(t, "email.welcome", 1)
+	recorder.AssertNotEnqueued(t, "email.reminder")
+
+	failing := &failureRecorder{TB: t}
+	recorder.AssertEnqueued(failing, "email.reminder")
+	tasktest.AssertOptions(failing, enqueued, taskqueue.WithDelay(time.Hour))
+	if len(failing.failures) != 2 || !strings.Contains(failing.failures[1], "delay=1h0m0s") {
+		t.Fatalf("failures = %q", failing.failures)
+	}
+
+	recorder.FailWith(errors.New("broker down"))
+	if err := recorder.Enqueue(context.Background(), enqueued.Task); err == nil {
+		t.Fatal("Enqueue after FailWith error = nil")
+	}
+}
+
+// The runner should hold delayed tasks and retries until the clock passes
+// them, and report execution metadata for each attempt.
+func TestRunner_AdvancesDelaysAndRetries(t *testing.T) {
+	router := taskqueue.NewRouter()
+	attempts := 0
+	if err := router.Register(taskqueue.NewProcessor("email.welcome", func(context.Context, taskqueue.Task) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("smtp unavailable")
+		}
+		return nil
+	})); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	runner := tasktest.NewRunner(router, tasktest.WithRetryPolicy(taskqueue.NewRetryPolicy(taskqueue.FixedBackoff(time.Minute))))
+	task, _ := taskqueue.NewJSONTask(taskqueue.Definition{Type: "email.welcome", Queue: "mailers"}, welcomeEmail{})
+	ctx := context.Background()
+	if err := runner.Enqueue(ctx, task, taskqueue.WithDelay(time.Hour), taskqueue.WithMaxRetry(2)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	if outcomes := runner.RunDue(ctx); len(outcomes) != 0 {
+		t.Fatalf("outcomes before delay = %v", outcomes)
+	}
+	outcomes := runner.Advance(ctx, time.Hour)
+	if len(outcomes) != 1 || outcomes[0].Err == nil || outcomes[0].Final {
+		t.Fatalf("first outcomes = %+v, want one retryable failure", outcomes)
+	}
+	if queue := outcomes[0].Info.Queue(); queue != "mailers" {
+		t.Fatalf("queue = %q, want mailers", queue)
+	}
+	if len(runner.Pending()) != 1 {
+		t.Fatalf("pending = %d, want the retry", len(runner.Pending()))
+	}
+	outcomes = runner.Advance(ctx, time.Minute)
+	if retryCount, _ := outcomes[0].Info.RetryCount(); len(outcomes) != 1 || outcomes[0].Err != nil || retryCount != 1 {
+		t.Fatalf("retry outcomes = %+v, want success on retry 1", outcomes)
+	}
+	if len(runner.Outcomes()) != 2 {
+		t.Fatalf("all outcomes = %d, want 2", len(runner.Outcomes()))
+	}
+}
+
+// Periodic tasks should fire once per schedule tick that the clock passes,
+// and coalesce the ticks missed in one clock move like the scheduler does.
+func TestRunner_FiresPeriodicTasks(t *testing.T) {
+	router := taskqueue.NewRouter()
+	if err := router.Register(taskqueue.NewProcessor("report.build", func(context.Context, taskqueue.Task) error { return nil })); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	clock := tasktest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	runner := tasktest.NewRunner(router, tasktest.WithClock(clock))
+	task, _ := taskqueue.NewJSONTask(taskqueue.Definition{Type: "report.build"}, struct{}{})
+	schedule, _ := taskqueue.IntervalSchedule(time.Hour)
+	periodic, err := taskqueue.NewPeriodicTask("reports.hourly", schedule, task)
+	if err != nil {
+		t.Fatalf("NewPeriodicTask: %v", err)
+	}
+	if err := runner.RegisterPeriodicTask(periodic); err != nil {
+		t.Fatalf("RegisterPeriodicTask: %v", err)
+	}
+
+	for range 3 {
+		if outcomes := runner.Advance(context.Background(), time.Hour); len(outcomes) != 1 {
+			t.Fatalf("outcomes = %d, want 1 hourly fire", len(outcomes))
+		}
+	}
+	if outcomes := runner.Advance(context.Background(), 3*time.Hour); len(outcomes) != 1 {
+		t.Fatalf("outcomes = %d, want 3 missed fires coalesced into 1", len(outcomes))
+	}
+	if err := runner.RegisterPeriodicTask(periodic); !errors.Is(err, taskqueue.ErrDuplicatePeriodicTask) {
+		t.Fatalf("duplicate registration error = %v", err)
+	}
+}
+
+// SkipMissedFires should drop a fire when later fire times have also passed.
+func TestRunner_SkipsMissedPeriodicFires(t *testing.T) {
+	router := taskqueue.NewRouter()
+	if err := router.Register(taskqueue.NewProcessor("report.build", func(context.Context, taskqueue.Task) error { return nil })); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	runner := tasktest.NewRunner(router, tasktest.WithMissedFirePolicy(scheduler.SkipMissedFires))
+	task, _ := taskqueue.NewJSONTask(taskqueue.Definition{Type: "report.build"}, struct{}{})
+	schedule, _ := taskqueue.IntervalSchedule(time.Hour)
+	periodic, err := taskqueue.NewPeriodicTask("reports.hourly", schedule, task)
+	if err != nil {
+		t.Fatalf("NewPeriodicTask: %v", err)
+	}
+	if err := runner.RegisterPeriodicTask(periodic); err != nil {
+		t.Fatalf("RegisterPeriodicTask: %v", err)
+	}
+
+	if outcomes := runner.Advance(context.Background(), 3*time.Hour); len(outcomes) != 0 {
+		t.Fatalf("outcomes = %d, want missed fires skipped", len(outcomes))
+	}
+	if outcomes := runner.Advance(context.Background(), time.Hour); len(outcomes) != 1 {
+		t.Fatalf("outcomes = %d, want the next fire", len(outcomes))
+	}
+}
+
+// A task deadline should bound the attempt context with the time left on the
+// runner clock.
+func TestRunner_PutsDeadlineOnContext(t *testing.T) {
+	router := taskqueue.NewRouter()
+	left := make(chan time.Duration, 1)
+	if err := router.Register(taskqueue.NewProcessor("report.build", func(ctx context.Context, _ taskqueue.Task) error {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return errors.New("attempt context has no deadline")
+		}
+		left <- time.Until(deadline)
+		return nil
+	})); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	runner := tasktest.NewRunner(router)
+	task, _ := taskqueue.NewJSONTask(taskqueue.Definition{Type: "report.build"}, struct{}{})
+	if err := runner.Enqueue(context.Background(), task, taskqueue.WithDeadline(runner.Clock().Now().Add(time.Hour))); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	if outcomes := runner.RunDue(context.Background()); len(outcomes) != 1 || outcomes[0].Err != nil {
+		t.Fatalf("outcomes = %+v", outcomes)
+	}
+	if got := <-left; got <= 59*time.Minute || got > time.Hour {
+		t.Fatalf("time left = %s, want about 1h", got)
+	}
+}
+
+// The clock should fire timers when advanced past them.
+func TestClock_FiresTimersOnAdvance(t *testing.T) {
+	clock := tasktest.NewClock(time.Unix(0, 0))
+	timer := clock.After(time.Minute)
+	clock.Advance(30 * time.Second)
+	select {
+	case <-timer:
+		t.Fatal("timer fired early")
+	default:
+	}
+	clock.Advance(30 * time.Second)
+	select {
+	case at := <-timer:
+		if !at.Equal(time.Unix(60, 0)) {
+			t.Fatalf("timer time = %s", at)
+		}
+	default:
+		t.Fatal("timer did not fire")
+	}
+}
+
+// ExecutionContext should carry metadata for processors tested directly.
+func TestExecutionContext_CarriesExecutionInfo(t *testing.T) {
+	ctx := tasktest.ExecutionContext(context.Background(), taskqueue.WithExecutionRetryCount(3), taskqueue.WithExecutionMaxRetry(3))
+	if !taskqueue.IsFinalFailure(ctx, errors.New("boom")) {
+		t.Fatal("failure on the last retry is not final")
+	}
+}
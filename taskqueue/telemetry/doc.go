@@ -0,0 +1,13 @@
+// Package telemetry provides OpenTelemetry tracing and metrics for taskqueue.
+//
+// EnqueueTracing is taskqueue.EnqueueMiddleware that records a producer span
+// and injects the W3C trace context into Task.Headers; NewEnqueuer applies it
+// to any taskqueue.Enqueuer. Tracing is processor middleware
+// that extracts that context and records a consumer span for each attempt.
+// Metrics is processor middleware that records attempts, latency, failures by
+// taskqueue.ErrorClass, and retries.
+//
+// Spans and measurements carry the task type and queue. Providers default to
+// the OpenTelemetry globals and can be replaced with options, which keeps the
+// middleware testable with the SDK in-memory exporters and readers.
+package telemetry
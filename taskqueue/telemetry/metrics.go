@@ -0,0 +1,73 @@
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// Instrument names recorded by Metrics.
+const (
+	AttemptsMetric = "taskqueue.process.attempts"
+	DurationMetric = "taskqueue.process.duration"
+	FailuresMetric = "taskqueue.process.failures"
+	RetriesMetric  = "taskqueue.process.retries"
+)
+
+// Metrics records processing attempts, attempt latency in seconds, failures
+// labelled by taskqueue.ErrorClass, and attempts that are retries. It returns
+// an error when an instrument cannot be created.
+func Metrics(opts ...Option) (taskqueue.Middleware, error) {
+	cfg := newConfig(opts)
+	meter := cfg.meterProvider.Meter(ScopeName)
+
+	attempts, err := meter.Int64Counter(AttemptsMetric,
+		metric.WithDescription("Task processing attempts."), metric.WithUnit("{attempt}"))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := meter.Float64Histogram(DurationMetric,
+		metric.WithDescription("Task processing attempt duration."), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	failures, err := meter.Int64Counter(FailuresMetric,
+		metric.WithDescription("Failed task processing attempts by error class."), metric.WithUnit("{attempt}"))
+	if err != nil {
+		return nil, err
+	}
+	retries, err := meter.Int64Counter(RetriesMetric,
+		metric.WithDescription("Task processing attempts that retry an earlier failure."), metric.WithUnit("{attempt}"))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next taskqueue.ProcessorFunc) taskqueue.ProcessorFunc {
+		return func(ctx context.Context, task taskqueue.Task) error {
+			labels := metric.WithAttributeSet(attribute.NewSet(taskAttributes(task.Type(), executionQueue(ctx, task))...))
+			attempts.Add(ctx, 1, labels)
+			if info, ok := taskqueue.ExecutionInfoFromContext(ctx); ok {
+				if retryCount, ok := info.RetryCount(); ok && retryCount > 0 {
+					retries.Add(ctx, 1, labels)
+				}
+			}
+
+			startedAt := time.Now()
+			err := taskqueue.ErrNilProcessor
+			if next != nil {
+				err = next(ctx, task)
+			}
+			duration.Record(ctx, time.Since(startedAt).Seconds(), labels)
+			if err != nil {
+				attrs := append(taskAttributes(task.Type(), executionQueue(ctx, task)),
+					ErrorClassKey.String(taskqueue.ClassifyError(err).String()))
+				failures.Add(ctx, 1, metric.WithAttributes(attrs...))
+			}
+			return err
+		}
+	}, nil
+}
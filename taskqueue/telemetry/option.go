@@ -0,0 +1,63 @@
+package telemetry
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of tracers and meters created by
+// this package.
+const ScopeName = "github.com/go-jimu/components/taskqueue/telemetry"
+
+// Option configures tracing and metrics instrumentation.
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagator     propagation.TextMapPropagator
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+		propagator:     propagation.TraceContext{},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	return cfg
+}
+
+// WithTracerProvider sets the tracer provider. The default is the global one.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(cfg *config) {
+		if provider != nil {
+			cfg.tracerProvider = provider
+		}
+	}
+}
+
+// WithMeterProvider sets the meter provider. The default is the global one.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(cfg *config) {
+		if provider != nil {
+			cfg.meterProvider = provider
+		}
+	}
+}
+
+// WithPropagator sets the propagator used for task headers. The default is
+// W3C trace context.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(cfg *config) {
+		if propagator != nil {
+			cfg.propagator = propagator
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package telemetry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-jimu/components/taskqueue"
+	"github.com/go-jimu/components/taskqueue/telemetry"
+)
+
+// The processing span should continue the trace started at enqueue time,
+// carried through W3C traceparent headers on the task envelope.
+func TestTracing_PropagatesTraceContextFromEnqueueToProcess(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	var enqueued taskqueue.Task
+	inner := enqueuerFunc(func(_ context.Context, task taskqueue.Task, _ ...taskqueue.EnqueueOption) error {
+		enqueued = task
+		return nil
+	})
+	enqueuer := telemetry.NewEnqueuer(inner, telemetry.WithTracerProvider(provider))
+	if err := enqueuer.Enqueue(context.Background(), newTask(t)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if enqueued.Headers()["traceparent"] == "" {
+		t.Fatalf("headers = %#v, want traceparent", enqueued.Headers())
+	}
+
+	failure := errors.New("smtp unavailable")
+	processor := taskqueue.Chain(func(ctx context.Context, _ taskqueue.Task) error {
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			t.Fatal("processor context has no span")
+		}
+		return failure
+	}, telemetry.Tracing(telemetry.WithTracerProvider(provider)))
+	ctx := taskqueue.ContextWithExecutionInfo(context.Background(), taskqueue.NewExecutionInfo(
+		taskqueue.WithExecutionTaskID("task-1"), taskqueue.WithExecutionQueue("mailers"), taskqueue.WithExecutionRetryCount(2)))
+	if err := processor(ctx, enqueued); !errors.Is(err, failure) {
+		t.Fatalf("Process error = %v, want failure", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	producer, consumer := spans[0], spans[1]
+	if producer.SpanKind != trace.SpanKindProducer || consumer.SpanKind != trace.SpanKindConsumer {
+		t.Fatalf("span kinds = %s, %s", producer.SpanKind, consumer.SpanKind)
+	}
+	if consumer.SpanContext.TraceID() != producer.SpanContext.TraceID() || consumer.Parent.SpanID() != producer.SpanContext.SpanID() {
+		t.Fatal("processing span is not a child of the enqueue span")
+	}
+	if consumer.Name != "process email.welcome" {
+		t.Fatalf("span name = %q", consumer.Name)
+	}
+	want := map[attribute.Key]attribute.Value{
+		telemetry.TaskTypeKey:   attribute.StringValue("email.welcome"),
+		telemetry.QueueKey:      attribute.StringValue("mailers"),
+		telemetry.TaskIDKey:     attribute.StringValue("task-1"),
+		telemetry.RetryCountKey: attribute.IntValue(2),
+		telemetry.ErrorClassKey: attribute.StringValue("retryable"),
+	}
+	got := make(map[attribute.Key]attribute.Value)
+	for _, kv := range consumer.Attributes {
+		got[kv.Key] = kv.Value
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Fatalf("attribute %s = %v, want %v", key, got[key].Emit(), value.Emit())
+		}
+	}
+	if consumer.Status.Description != "smtp unavailable" {
+		t.Fatalf("status = %#v", consumer.Status)
+	}
+}
+
+// Metrics should count attempts, retries, and failures by error class and
+// record latency, all labelled by task type and queue.
+func TestMetrics_RecordsAttemptsFailuresAndRetries(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	middleware, err := telemetry.Metrics(telemetry.WithMeterProvider(provider))
+	if err != nil {
+		t.Fatalf("Metrics: %v", err)
+	}
+	results := []error{errors.New("smtp unavailable"), taskqueue.ErrSkipRetry, nil}
+	processor := taskqueue.Chain(func(ctx context.Context, _ taskqueue.Task) error {
+		info, _ := taskqueue.ExecutionInfoFromContext(ctx)
+		retryCount, _ := info.RetryCount()
+		return results[retryCount]
+	}, middleware)
+	for retryCount := range results {
+		ctx := taskqueue.ContextWithExecutionInfo(context.Background(), taskqueue.NewExecutionInfo(
+			taskqueue.WithExecutionRetryCount(retryCount)))
+		_ = processor(ctx, newTask(t))
+	}
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	metrics := make(map[string]metricdata.Aggregation)
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+
+	if got := sumTotal(t, metrics[telemetry.AttemptsMetric], nil); got != 3 {
+		t.Fatalf("attempts = %d, want 3", got)
+	}
+	if got := sumTotal(t, metrics[telemetry.RetriesMetric], nil); got != 2 {
+		t.Fatalf("retries = %d, want 2", got)
+	}
+	for class, want := range map[string]int64{"retryable": 1, "skip_retry": 1} {
+		filter := attribute.NewSet(telemetry.TaskTypeKey.String("email.welcome"), telemetry.QueueKey.String("mailers"),
+			telemetry.ErrorClassKey.String(class))
+		if got := sumTotal(t, metrics[telemetry.FailuresMetric], &filter); got != want {
+			t.Fatalf("%s failures = %d, want %d", class, got, want)
+		}
+	}
+	histogram, ok := metrics[telemetry.DurationMetric].(metricdata.Histogram[float64])
+	if !ok || len(histogram.DataPoints) != 1 || histogram.DataPoints[0].Count != 3 {
+		t.Fatalf("duration = %#v, want 3 observations in one series", metrics[telemetry.DurationMetric])
+	}
+	labels := histogram.DataPoints[0].Attributes
+	if v, _ := labels.Value(telemetry.TaskTypeKey); v.AsString() != "email.welcome" {
+		t.Fatalf("duration labels = %v", labels.ToSlice())
+	}
+}
+
+func sumTotal(t *testing.T, data metricdata.Aggregation, attrs *attribute.Set) int64 {
+	t.Helper()
+	sum, ok := data.(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("aggregation = %T, want Sum[int64]", data)
+	}
+	var total int64
+	for _, point := range sum.DataPoints {
+		if attrs == nil || point.Attributes.Equals(attrs) {
+			total += point.Value
+		}
+	}
+	return total
+}
+
+type enqueuerFunc func(context.Context, taskqueue.Task, ...taskqueue.EnqueueOption) error
+
+func (f enqueuerFunc) Enqueue(ctx context.Context, task taskqueue.Task, opts ...taskqueue.EnqueueOption) error {
+	return f(ctx, task, opts...)
+}
+
+func newTask(t *testing.T) taskqueue.Task {
+	t.Helper()
+	task, err := taskqueue.NewJSONTask(taskqueue.Definition{Type: "email.welcome", Queue: "mailers"}, struct{}{})
+	if err != nil {
+		t.Fatalf("NewJSONTask: %v", err)
+	}
+	return task
+}
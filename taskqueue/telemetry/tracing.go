@@ -0,0 +1,121 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// Attribute keys recorded on spans and measurements.
+const (
+	TaskTypeKey   = attribute.Key("taskqueue.task_type")
+	QueueKey      = attribute.Key("taskqueue.queue")
+	TaskIDKey     = attribute.Key("taskqueue.task_id")
+	RetryCountKey = attribute.Key("taskqueue.retry_count")
+	ErrorClassKey = attribute.Key("taskqueue.error_class")
+)
+
+// NewEnqueuer wraps next so every enqueue records a producer span and carries
+// its trace context to processors in the task headers.
+func NewEnqueuer(next taskqueue.Enqueuer, opts ...Option) taskqueue.Enqueuer {
+	return taskqueue.ChainEnqueuer(next, EnqueueTracing(opts...))
+}
+
+// EnqueueTracing records a producer span for each enqueue and injects its
+// trace context into the task headers.
+func EnqueueTracing(opts ...Option) taskqueue.EnqueueMiddleware {
+	cfg := newConfig(opts)
+	tracer := cfg.tracerProvider.Tracer(ScopeName)
+	return func(next taskqueue.EnqueueFunc) taskqueue.EnqueueFunc {
+		return func(ctx context.Context, task taskqueue.Task, opts ...taskqueue.EnqueueOption) error {
+			ctx, span := tracer.Start(ctx, "enqueue "+string(task.Type()),
+				trace.WithSpanKind(trace.SpanKindProducer),
+				trace.WithAttributes(taskAttributes(task.Type(), task.Queue())...),
+			)
+			defer span.End()
+
+			err := next(ctx, inject(ctx, task, cfg.propagator), opts...)
+			if err != nil {
+				recordError(span, err)
+			}
+			return err
+		}
+	}
+}
+
+// Inject returns a copy of task whose headers carry the trace context of ctx.
+func Inject(ctx context.Context, task taskqueue.Task, opts ...Option) taskqueue.Task {
+	return inject(ctx, task, newConfig(opts).propagator)
+}
+
+func inject(ctx context.Context, task taskqueue.Task, propagator propagation.TextMapPropagator) taskqueue.Task {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return task.WithHeaders(carrier)
+}
+
+// Extract returns ctx with the trace context carried in task headers.
+func Extract(ctx context.Context, task taskqueue.Task, opts ...Option) context.Context {
+	cfg := newConfig(opts)
+	return cfg.propagator.Extract(ctx, propagation.MapCarrier(task.Headers()))
+}
+
+// Tracing records a consumer span for each processing attempt, parented by
+// the trace context extracted from the task headers.
+func Tracing(opts ...Option) taskqueue.Middleware {
+	cfg := newConfig(opts)
+	tracer := cfg.tracerProvider.Tracer(ScopeName)
+	return func(next taskqueue.ProcessorFunc) taskqueue.ProcessorFunc {
+		return func(ctx context.Context, task taskqueue.Task) error {
+			ctx = cfg.propagator.Extract(ctx, propagation.MapCarrier(task.Headers()))
+			attrs := taskAttributes(task.Type(), executionQueue(ctx, task))
+			if info, ok := taskqueue.ExecutionInfoFromContext(ctx); ok {
+				if info.TaskID() != "" {
+					attrs = append(attrs, TaskIDKey.String(info.TaskID()))
+				}
+				if retryCount, ok := info.RetryCount(); ok {
+					attrs = append(attrs, RetryCountKey.Int(retryCount))
+				}
+			}
+			ctx, span := tracer.Start(ctx, "process "+string(task.Type()),
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(attrs...),
+			)
+			defer span.End()
+
+			if next == nil {
+				recordError(span, taskqueue.ErrNilProcessor)
+				return taskqueue.ErrNilProcessor
+			}
+			err := next(ctx, task)
+			if err != nil {
+				span.SetAttributes(ErrorClassKey.String(taskqueue.ClassifyError(err).String()))
+				recordError(span, err)
+			}
+			return err
+		}
+	}
+}
+
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+func taskAttributes(taskType taskqueue.TaskType, queue string) []attribute.KeyValue {
+	return []attribute.KeyValue{TaskTypeKey.String(string(taskType)), QueueKey.String(queue)}
+}
+
+// executionQueue prefers the provider lane from ExecutionInfo, which resolves
+// an empty Definition.Queue to the provider default.
+func executionQueue(ctx context.Context, task taskqueue.Task) string {
+	if info, ok := taskqueue.ExecutionInfoFromContext(ctx); ok && info.Queue() != "" {
+		return info.Queue()
+	}
+	return task.Queue()
+}
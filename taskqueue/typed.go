@@ -0,0 +1,84 @@
+package taskqueue
+
+import (
+	"context"
+	"fmt"
+)
+
+// Handle constructs a Processor for the task type registered for T in
+// registry. The processor decodes each task payload into a fresh *T from the
+// registered factory before calling fn.
+//
+// Producers using Enqueue or NewTypedTask with the same T and registry are
+// guaranteed to target this processor's task type. Payloads that cannot be
+// decoded fail with ErrSkipRetry.
+func Handle[T any](registry *SchemaRegistry, fn func(context.Context, Task, *T) error) (Processor, error) {
+	if registry == nil {
+		return nil, ErrNilSchemaRegistry
+	}
+	if fn == nil {
+		return nil, ErrNilProcessor
+	}
+	def, err := registry.DefinitionOf(new(T))
+	if err != nil {
+		return nil, err
+	}
+	return NewProcessor(def.Type, func(ctx context.Context, task Task) error {
+		payload, err := decodeTyped[T](registry, task)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, task, payload)
+	}), nil
+}
+
+// NewTypedTask encodes payload with the definition and codec registered for T.
+func NewTypedTask[T any](registry *SchemaRegistry, payload *T, opts ...Option) (Task, error) {
+	if registry == nil {
+		return Task{}, ErrNilSchemaRegistry
+	}
+	if payload == nil {
+		return Task{}, ErrNilPayload
+	}
+	def, err := registry.DefinitionOf(payload)
+	if err != nil {
+		return Task{}, err
+	}
+	codecName, err := registry.CodecOf(def.Type)
+	if err != nil {
+		return Task{}, err
+	}
+	return registry.NewTask(codecName, payload, opts...)
+}
+
+// Enqueue encodes payload with NewTypedTask and enqueues it. Use NewTypedTask
+// directly when the task needs a key or headers.
+func Enqueue[T any](ctx context.Context, enqueuer Enqueuer, registry *SchemaRegistry, payload *T, opts ...EnqueueOption) error {
+	if enqueuer == nil {
+		return ErrNilEnqueuer
+	}
+	task, err := NewTypedTask(registry, payload)
+	if err != nil {
+		return err
+	}
+	return enqueuer.Enqueue(ctx, task, opts...)
+}
+
+func decodeTyped[T any](registry *SchemaRegistry, task Task) (*T, error) {
+	codecName := task.PayloadCodec()
+	if codecName == "" {
+		var err error
+		if codecName, err = registry.CodecOf(task.Type()); err != nil {
+			return nil, skipRetry(err)
+		}
+	}
+	decoded, err := registry.DecodeWithCodec(task, codecName)
+	if err != nil {
+		return nil, skipRetry(err)
+	}
+	payload, ok := decoded.(*T)
+	if !ok {
+		return nil, fmt.Errorf("%w: %w: %s resolves to %T", ErrSkipRetry, ErrInvalidPayloadFactory, task.Type(), decoded)
+	}
+	return payload, nil
+}
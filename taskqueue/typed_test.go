@@ -0,0 +1,112 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	testdata "github.com/go-jimu/components/encoding/testdata"
+	"google.golang.org/protobuf/proto"
+)
+
+type enqueuerFunc func(context.Context, Task, ...EnqueueOption) error
+
+func (f enqueuerFunc) Enqueue(ctx context.Context, task Task, opts ...EnqueueOption) error {
+	return f(ctx, task, opts...)
+}
+
+// Typed producers and handlers should meet on the registered definition and
+// codec so payload types, not task type strings, bind both sides together.
+func TestHandleAndEnqueueRoundTripTypedPayload(t *testing.T) {
+	registry := NewSchemaRegistry()
+	def := Definition{Type: "document.review.v1", Queue: "reconcile"}
+	if err := registry.Register(def, func() any { return &reviewTaskPayload{} }, WithSchemaCodec(YAMLCodec)); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	var got *reviewTaskPayload
+	processor, err := Handle(registry, func(_ context.Context, _ Task, payload *reviewTaskPayload) error {
+		got = payload
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	router := NewRouter()
+	if err := router.Register(processor); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	var enqueued Task
+	enqueuer := enqueuerFunc(func(_ context.Context, task Task, opts ...EnqueueOption) error {
+		enqueued = task
+		if maxRetry, _ := NewEnqueueOptions(opts...).MaxRetry(); maxRetry != 2 {
+			t.Fatalf("max retry = %d, want 2", maxRetry)
+		}
+		return nil
+	})
+	if err := Enqueue(context.Background(), enqueuer, registry, &reviewTaskPayload{ID: "doc-1", Limit: 3}, WithMaxRetry(2)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if enqueued.Definition() != def || enqueued.PayloadCodec() != YAMLCodec {
+		t.Fatalf("enqueued definition = %#v, codec = %q", enqueued.Definition(), enqueued.PayloadCodec())
+	}
+	if err := router.Process(context.Background(), enqueued); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if got == nil || got.ID != "doc-1" || got.Limit != 3 {
+		t.Fatalf("handled payload = %#v", got)
+	}
+}
+
+// Protobuf payload types should default to the protobuf codec.
+func TestNewTypedTaskDefaultsProtoCodec(t *testing.T) {
+	registry := NewSchemaRegistry()
+	def := Definition{Type: "document.review.v1"}
+	if err := registry.Register(def, func() any { return &testdata.TestModel{} }); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	want := &testdata.TestModel{Id: 9, Name: "persisted"}
+
+	task, err := NewTypedTask(registry, want, WithKey("doc-9"))
+	if err != nil {
+		t.Fatalf("NewTypedTask: %v", err)
+	}
+	if task.PayloadCodec() != ProtoCodec || task.Key() != "doc-9" {
+		t.Fatalf("codec = %q, key = %q", task.PayloadCodec(), task.Key())
+	}
+	got, err := decodeTyped[testdata.TestModel](registry, task)
+	if err != nil {
+		t.Fatalf("decodeTyped: %v", err)
+	}
+	if !proto.Equal(want, got) {
+		t.Fatalf("decoded payload = %v, want %v", got, want)
+	}
+}
+
+// Unregistered payload types should fail at construction, and undecodable
+// payloads should not be retried.
+func TestHandleErrors(t *testing.T) {
+	registry := NewSchemaRegistry()
+	handler := func(context.Context, Task, *reviewTaskPayload) error { return nil }
+	if _, err := Handle(registry, handler); !errors.Is(err, ErrUnknownPayloadType) {
+		t.Fatalf("unregistered Handle error = %v, want ErrUnknownPayloadType", err)
+	}
+	if _, err := Handle[reviewTaskPayload](nil, handler); !errors.Is(err, ErrNilSchemaRegistry) {
+		t.Fatalf("nil registry error = %v, want ErrNilSchemaRegistry", err)
+	}
+	if err := registry.Register(Definition{Type: "document.review.v1"}, func() any { return &reviewTaskPayload{} }); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	processor, err := Handle(registry, handler)
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+
+	task, err := New(Definition{Type: "document.review.v1"}, []byte("{not json"), WithPayloadCodec(JSONCodec))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := processor.Process(context.Background(), task); !errors.Is(err, ErrSkipRetry) {
+		t.Fatalf("Process error = %v, want ErrSkipRetry", err)
+	}
+}
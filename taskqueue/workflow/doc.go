@@ -0,0 +1,25 @@
+// Package workflow orchestrates DAGs of taskqueue tasks.
+//
+// A Workflow lists Steps, each wrapping a taskqueue.Task and naming the steps
+// it depends on. Engine.Start records a Run in a Store and enqueues the steps
+// without dependencies through any taskqueue.Enqueuer. The Engine middleware,
+// installed in the worker's processing chain, records each step outcome in the
+// Store and enqueues the steps whose dependencies have completed, so
+// "run A, then B and C in parallel, then D" needs no coordinator process.
+//
+// Step results set with SetResult are kept in the Run and passed to dependent
+// steps as task headers, read back with Result.
+//
+// A step fails for good when its error wraps taskqueue.ErrSkipRetry or its
+// ExecutionInfo shows the retry budget is spent; install taskqueue.Retry
+// inside the Engine middleware when a custom retry policy stops earlier. The
+// FailurePolicy then decides what happens next: Abort stops the run, Compensate
+// enqueues the compensation tasks of completed steps in reverse completion
+// order, and Continue lets dependents run anyway.
+//
+// Delivery is at least once. A step task that arrives again after its step
+// has completed is not processed again; Resume re-enqueues steps that were
+// recorded as enqueued but never completed, for example after a crash.
+//
+// MemoryStore is a non-durable Store for tests and single-process services.
+package workflow
@@ -0,0 +1,273 @@
+package workflow
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// Headers carried by workflow step and compensation tasks.
+const (
+	RunIDHeader        = "x-workflow-run-id"
+	StepHeader         = "x-workflow-step"
+	CompensateHeader   = "x-workflow-compensate"
+	ResultHeaderPrefix = "x-workflow-result-"
+)
+
+type resultContextKey struct{}
+
+// Option configures an Engine.
+type Option func(*Engine)
+
+// WithClock sets the time source for run timestamps.
+func WithClock(now func() time.Time) Option {
+	return func(e *Engine) {
+		if now != nil {
+			e.now = now
+		}
+	}
+}
+
+// WithEnqueueOptions sets the enqueue policy for every step and compensation
+// task.
+func WithEnqueueOptions(opts ...taskqueue.EnqueueOption) Option {
+	return func(e *Engine) {
+		e.enqueueOpts = append(e.enqueueOpts, opts...)
+	}
+}
+
+// Engine starts workflow runs and advances them as steps complete.
+type Engine struct {
+	store       Store
+	enqueuer    taskqueue.Enqueuer
+	enqueueOpts []taskqueue.EnqueueOption
+	now         func() time.Time
+}
+
+// NewEngine constructs an Engine that records runs in store and enqueues
+// steps through enqueuer.
+func NewEngine(store Store, enqueuer taskqueue.Enqueuer, opts ...Option) (*Engine, error) {
+	if store == nil {
+		return nil, ErrNilStore
+	}
+	if enqueuer == nil {
+		return nil, ErrNilEnqueuer
+	}
+	e := &Engine{store: store, enqueuer: enqueuer, now: time.Now}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(e)
+		}
+	}
+	return e, nil
+}
+
+// Start validates w, records a new Run, and enqueues the steps without
+// dependencies. When an enqueue fails the run is kept with those steps
+// pending, and the error is returned with the run so it can be resumed.
+func (e *Engine) Start(ctx context.Context, w Workflow) (Run, error) {
+	if err := w.Validate(); err != nil {
+		return Run{}, err
+	}
+	id, err := generateID()
+	if err != nil {
+		return Run{}, fmt.Errorf("generate run id: %w", err)
+	}
+	run := newRun(id, w, e.now())
+	dispatches := run.advance()
+	if err := e.store.Create(ctx, run); err != nil {
+		return Run{}, fmt.Errorf("create workflow run: %w", err)
+	}
+	if err := e.enqueue(ctx, run.ID, dispatches); err != nil {
+		run, _ = e.store.Get(ctx, run.ID)
+		return run, err
+	}
+	return run, nil
+}
+
+// Get returns the run with id.
+func (e *Engine) Get(ctx context.Context, id string) (Run, error) {
+	return e.store.Get(ctx, id)
+}
+
+// Resume enqueues again the tasks of run id that are recorded as enqueued or
+// compensating, and any steps that have become ready. Step tasks that already
+// completed are not processed twice.
+func (e *Engine) Resume(ctx context.Context, id string) error {
+	var dispatches []dispatch
+	_, err := e.store.Update(ctx, id, func(run *Run) error {
+		dispatches = append(run.pendingDispatches(), run.advance()...)
+		run.UpdatedAt = e.now()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return e.enqueue(ctx, id, dispatches)
+}
+
+// Middleware records the outcome of workflow tasks and enqueues the steps
+// that become ready. Tasks without workflow headers pass through unchanged.
+// A step task whose step already finished, or whose run has stopped, is
+// acknowledged without processing.
+func (e *Engine) Middleware() taskqueue.Middleware {
+	return func(next taskqueue.ProcessorFunc) taskqueue.ProcessorFunc {
+		return func(ctx context.Context, task taskqueue.Task) error {
+			if next == nil {
+				return taskqueue.ErrNilProcessor
+			}
+			headers := task.Headers()
+			runID := headers[RunIDHeader]
+			switch {
+			case runID == "":
+				return next(ctx, task)
+			case headers[CompensateHeader] != "":
+				return e.processCompensation(ctx, runID, headers[CompensateHeader], task, next)
+			default:
+				return e.processStep(ctx, runID, headers[StepHeader], task, next)
+			}
+		}
+	}
+}
+
+func (e *Engine) processStep(ctx context.Context, runID, name string, task taskqueue.Task, next taskqueue.ProcessorFunc) error {
+	run, err := e.store.Get(ctx, runID)
+	if err != nil {
+		return err
+	}
+	step, ok := run.Step(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrStepNotFound, name)
+	}
+	if step.Status != StepEnqueued || run.Status != RunRunning {
+		// Duplicate delivery or a stopped run: skip the work, but make sure
+		// the run has moved on.
+		return e.update(ctx, runID, func(run *Run) {
+			if i := run.stepIndex(name); run.Steps[i].Status == StepEnqueued && run.Status != RunRunning {
+				run.Steps[i].Status = StepSkipped
+			}
+		})
+	}
+
+	var result string
+	err = next(context.WithValue(ctx, resultContextKey{}, &result), task)
+	if err != nil && !taskqueue.IsFinalFailure(ctx, err) {
+		return err
+	}
+	updateErr := e.update(ctx, runID, func(run *Run) {
+		i := run.stepIndex(name)
+		if run.Steps[i].Status != StepEnqueued {
+			return
+		}
+		if err != nil {
+			run.fail(i, err)
+		} else {
+			run.succeed(i, result)
+		}
+	})
+	return errors.Join(err, updateErr)
+}
+
+func (e *Engine) processCompensation(ctx context.Context, runID, name string, task taskqueue.Task, next taskqueue.ProcessorFunc) error {
+	run, err := e.store.Get(ctx, runID)
+	if err != nil {
+		return err
+	}
+	step, ok := run.Step(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrStepNotFound, name)
+	}
+	if step.Status != StepCompensating {
+		return nil
+	}
+
+	err = next(ctx, task)
+	if err != nil && !taskqueue.IsFinalFailure(ctx, err) {
+		return err
+	}
+	updateErr := e.update(ctx, runID, func(run *Run) {
+		i := run.stepIndex(name)
+		if run.Steps[i].Status != StepCompensating {
+			return
+		}
+		if err == nil {
+			run.Steps[i].Status = StepCompensated
+			return
+		}
+		run.Steps[i].Status = StepCompensationFailed
+		run.Steps[i].Error = err.Error()
+		run.Status = RunFailed
+		run.Error = "compensate step " + name + ": " + err.Error()
+	})
+	return errors.Join(err, updateErr)
+}
+
+// update applies change, advances the run, and enqueues the tasks that
+// became ready.
+func (e *Engine) update(ctx context.Context, runID string, change func(*Run)) error {
+	var dispatches []dispatch
+	_, err := e.store.Update(ctx, runID, func(run *Run) error {
+		change(run)
+		dispatches = run.advance()
+		run.UpdatedAt = e.now()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update workflow run %s: %w", runID, err)
+	}
+	return e.enqueue(ctx, runID, dispatches)
+}
+
+// enqueue sends dispatches and reverts the ones that could not be enqueued so
+// a provider retry or Resume picks them up again.
+func (e *Engine) enqueue(ctx context.Context, runID string, dispatches []dispatch) error {
+	var failed []dispatch
+	var errs []error
+	for _, d := range dispatches {
+		if err := e.enqueuer.Enqueue(ctx, d.task, e.enqueueOpts...); err != nil {
+			failed = append(failed, d)
+			errs = append(errs, fmt.Errorf("enqueue workflow step %s: %w", d.step, err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	_, err := e.store.Update(context.WithoutCancel(ctx), runID, func(run *Run) error {
+		for _, d := range failed {
+			run.revert(d)
+		}
+		run.UpdatedAt = e.now()
+		return nil
+	})
+	return errors.Join(append(errs, err)...)
+}
+
+// SetResult records the result of the step being processed. It is passed to
+// dependent steps and kept in the Run. Outside a workflow step it does
+// nothing.
+func SetResult(ctx context.Context, result string) {
+	if holder, ok := ctx.Value(resultContextKey{}).(*string); ok {
+		*holder = result
+	}
+}
+
+// Result returns the result of step carried by task. Step tasks carry the
+// results of their dependencies; compensation tasks carry the result of the
+// step they undo.
+func Result(task taskqueue.Task, step string) (string, bool) {
+	result, ok := task.Headers()[ResultHeaderPrefix+step]
+	return result, ok
+}
+
+func generateID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
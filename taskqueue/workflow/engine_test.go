@@ -0,0 +1,353 @@
+package workflow_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+	"github.com/go-jimu/components/taskqueue/memory"
+	"github.com/go-jimu/components/taskqueue/workflow"
+)
+
+// A diamond workflow should run B and C after A, D after both, and pass each
+// step's result to its dependents through headers.
+func TestEngine_RunsDiamondThroughMemoryProvider(t *testing.T) {
+	router := taskqueue.NewRouter()
+	// The engine enqueues through the queue, and the queue processes through
+	// the engine middleware.
+	var queue *memory.Queue
+	engine, err := workflow.NewEngine(workflow.NewMemoryStore(), taskqueue.EnqueueFunc(
+		func(ctx context.Context, task taskqueue.Task, opts ...taskqueue.EnqueueOption) error {
+			return queue.Enqueue(ctx, task, opts...)
+		}))
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	queue, err = memory.New(router, memory.WithMiddleware(engine.Middleware()))
+	if err != nil {
+		t.Fatalf("memory.New: %v", err)
+	}
+
+	var mu sync.Mutex
+	seen := make(map[string]map[string]string)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		mustRegister(t, router, taskqueue.TaskType(name), func(ctx context.Context, task taskqueue.Task) error {
+			inputs := make(map[string]string)
+			for _, dep := range []string{"a", "b", "c"} {
+				if result, ok := workflow.Result(task, dep); ok {
+					inputs[dep] = result
+				}
+			}
+			mu.Lock()
+			seen[name] = inputs
+			mu.Unlock()
+			workflow.SetResult(ctx, name+"-out")
+			return nil
+		})
+	}
+	if err := queue.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { _ = queue.Shutdown(context.Background()) })
+
+	run, err := engine.Start(context.Background(), workflow.Workflow{Name: "diamond", Steps: []workflow.Step{
+		{Name: "a", Task: newTask(t, "a")},
+		{Name: "b", Task: newTask(t, "b"), DependsOn: []string{"a"}},
+		{Name: "c", Task: newTask(t, "c"), DependsOn: []string{"a"}},
+		{Name: "d", Task: newTask(t, "d"), DependsOn: []string{"b", "c"}},
+	}})
+	if err != nil {
+		t.Fatalf("Start workflow: %v", err)
+	}
+
+	run = waitForRun(t, engine, run.ID)
+	if run.Status != workflow.RunSucceeded {
+		t.Fatalf("status = %s, error = %q", run.Status, run.Error)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]map[string]string{
+		"a": {},
+		"b": {"a": "a-out"},
+		"c": {"a": "a-out"},
+		"d": {"b": "b-out", "c": "c-out"},
+	}
+	if !reflect.DeepEqual(seen, want) {
+		t.Fatalf("inputs = %v, want %v", seen, want)
+	}
+	if d, _ := run.Step("d"); d.Result != "d-out" || d.Completed != 4 {
+		t.Fatalf("step d = %+v", d)
+	}
+}
+
+// Under Compensate, a failed step should skip the rest of the DAG and undo
+// completed steps in reverse completion order.
+func TestEngine_CompensatesCompletedStepsInReverseOrder(t *testing.T) {
+	h := newHarness(t)
+	h.fail["charge"] = errors.New("card declined")
+
+	run := h.start(workflow.Workflow{Name: "order", FailurePolicy: workflow.Compensate, Steps: []workflow.Step{
+		{Name: "reserve", Task: newTask(t, "reserve"), Compensation: newTask(t, "release")},
+		{Name: "charge", Task: newTask(t, "charge"), DependsOn: []string{"reserve"}},
+		{Name: "ship", Task: newTask(t, "ship"), DependsOn: []string{"charge"}},
+	}})
+	h.drain()
+
+	run = h.get(run.ID)
+	if run.Status != workflow.RunCompensated {
+		t.Fatalf("status = %s", run.Status)
+	}
+	if want := []string{"reserve", "charge", "release"}; !reflect.DeepEqual(h.processed, want) {
+		t.Fatalf("processed = %v, want %v", h.processed, want)
+	}
+	for name, want := range map[string]workflow.StepStatus{
+		"reserve": workflow.StepCompensated, "charge": workflow.StepFailed, "ship": workflow.StepSkipped,
+	} {
+		if step, _ := run.Step(name); step.Status != want {
+			t.Fatalf("%s status = %s, want %s", name, step.Status, want)
+		}
+	}
+	if !strings.Contains(run.Error, "card declined") {
+		t.Fatalf("run error = %q", run.Error)
+	}
+}
+
+// Abort should stop the run, while a step-level Continue lets dependents run.
+func TestEngine_FailurePolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    workflow.FailurePolicy
+		processed []string
+		status    workflow.StepStatus
+	}{
+		{name: "abort", policy: workflow.Abort, processed: []string{"lint"}, status: workflow.StepSkipped},
+		{name: "continue", policy: workflow.Continue, processed: []string{"lint", "deploy"}, status: workflow.StepSucceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.fail["lint"] = errors.New("style violations")
+			run := h.start(workflow.Workflow{Name: "pipeline", Steps: []workflow.Step{
+				{Name: "lint", Task: newTask(t, "lint"), FailurePolicy: tt.policy},
+				{Name: "deploy", Task: newTask(t, "deploy"), DependsOn: []string{"lint"}},
+			}})
+			h.drain()
+
+			run = h.get(run.ID)
+			if run.Status != workflow.RunFailed {
+				t.Fatalf("status = %s", run.Status)
+			}
+			if !reflect.DeepEqual(h.processed, tt.processed) {
+				t.Fatalf("processed = %v, want %v", h.processed, tt.processed)
+			}
+			if deploy, _ := run.Step("deploy"); deploy.Status != tt.status {
+				t.Fatalf("deploy status = %s, want %s", deploy.Status, tt.status)
+			}
+		})
+	}
+}
+
+// Retryable failures should be left to the provider; only the final attempt
+// decides the step outcome.
+func TestEngine_WaitsForFinalAttempt(t *testing.T) {
+	h := newHarness(t)
+	h.fail["fetch"] = errors.New("timeout")
+	run := h.start(workflow.Workflow{Name: "sync", Steps: []workflow.Step{{Name: "fetch", Task: newTask(t, "fetch")}}})
+	task := h.pop()
+
+	ctx := taskqueue.ContextWithExecutionInfo(context.Background(), taskqueue.NewExecutionInfo(
+		taskqueue.WithExecutionRetryCount(0), taskqueue.WithExecutionMaxRetry(1)))
+	if err := h.process(ctx, task); err == nil {
+		t.Fatal("first attempt succeeded, want failure")
+	}
+	if step, _ := h.get(run.ID).Step("fetch"); step.Status != workflow.StepEnqueued {
+		t.Fatalf("status after retryable failure = %s, want enqueued", step.Status)
+	}
+
+	delete(h.fail, "fetch")
+	if err := h.process(ctx, task); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if got := h.get(run.ID).Status; got != workflow.RunSucceeded {
+		t.Fatalf("status = %s, want succeeded", got)
+	}
+}
+
+// A step task delivered again after completion should not run the step twice,
+// and Resume should re-enqueue steps whose enqueue was lost.
+func TestEngine_DeduplicatesAndResumes(t *testing.T) {
+	h := newHarness(t)
+	run := h.start(workflow.Workflow{Name: "report", Steps: []workflow.Step{
+		{Name: "collect", Task: newTask(t, "collect")},
+		{Name: "render", Task: newTask(t, "render"), DependsOn: []string{"collect"}},
+	}})
+	collect := h.pop()
+	if err := h.process(context.Background(), collect); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if err := h.process(context.Background(), collect); err != nil {
+		t.Fatalf("duplicate: %v", err)
+	}
+	if want := []string{"collect"}; !reflect.DeepEqual(h.processed, want) {
+		t.Fatalf("processed = %v, want %v", h.processed, want)
+	}
+
+	h.queue = nil // the render task was lost
+	if err := h.engine.Resume(context.Background(), run.ID); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	h.drain()
+	if got := h.get(run.ID).Status; got != workflow.RunSucceeded {
+		t.Fatalf("status = %s, want succeeded", got)
+	}
+}
+
+// A failed enqueue should leave the step pending for Resume.
+func TestEngine_StartRevertsFailedEnqueue(t *testing.T) {
+	store := workflow.NewMemoryStore()
+	failing := taskqueue.EnqueueFunc(func(context.Context, taskqueue.Task, ...taskqueue.EnqueueOption) error {
+		return errors.New("broker down")
+	})
+	engine, err := workflow.NewEngine(store, failing)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	run, err := engine.Start(context.Background(), workflow.Workflow{Steps: []workflow.Step{{Name: "a", Task: newTask(t, "a")}}})
+	if err == nil || !strings.Contains(err.Error(), "broker down") {
+		t.Fatalf("Start error = %v, want broker down", err)
+	}
+	if step, _ := run.Step("a"); step.Status != workflow.StepPending {
+		t.Fatalf("status = %s, want pending", step.Status)
+	}
+}
+
+// Workflows should be rejected unless they form a valid DAG.
+func TestWorkflow_Validate(t *testing.T) {
+	task := newTask(t, "a")
+	tests := []struct {
+		name string
+		w    workflow.Workflow
+		want error
+	}{
+		{name: "empty", w: workflow.Workflow{}, want: workflow.ErrEmptyWorkflow},
+		{name: "unnamed", w: workflow.Workflow{Steps: []workflow.Step{{Task: task}}}, want: workflow.ErrEmptyStepName},
+		{name: "no task", w: workflow.Workflow{Steps: []workflow.Step{{Name: "a"}}}, want: workflow.ErrEmptyStepTask},
+		{name: "duplicate", w: workflow.Workflow{Steps: []workflow.Step{{Name: "a", Task: task}, {Name: "a", Task: task}}}, want: workflow.ErrDuplicateStep},
+		{name: "unknown", w: workflow.Workflow{Steps: []workflow.Step{{Name: "a", Task: task, DependsOn: []string{"b"}}}}, want: workflow.ErrUnknownDependency},
+		{name: "cycle", w: workflow.Workflow{Steps: []workflow.Step{
+			{Name: "a", Task: task, DependsOn: []string{"b"}},
+			{Name: "b", Task: task, DependsOn: []string{"a"}},
+		}}, want: workflow.ErrCyclicDependency},
+		{name: "policy", w: workflow.Workflow{FailurePolicy: 9, Steps: []workflow.Step{{Name: "a", Task: task}}}, want: workflow.ErrInvalidPolicy},
+	}
+	for _, tt := range tests {
+		if err := tt.w.Validate(); !errors.Is(err, tt.want) {
+			t.Fatalf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// harness runs workflow tasks synchronously through the engine middleware.
+type harness struct {
+	t         *testing.T
+	engine    *workflow.Engine
+	router    *taskqueue.Router
+	queue     []taskqueue.Task
+	processed []string
+	fail      map[string]error
+}
+
+func newHarness(t *testing.T) *harness {
+	h := &harness{t: t, router: taskqueue.NewRouter(), fail: make(map[string]error)}
+	engine, err := workflow.NewEngine(workflow.NewMemoryStore(), taskqueue.EnqueueFunc(func(_ context.Context, task taskqueue.Task, _ ...taskqueue.EnqueueOption) error {
+		h.queue = append(h.queue, task)
+		return nil
+	}), workflow.WithClock(func() time.Time { return time.Unix(0, 0) }))
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	h.engine = engine
+	for _, name := range []string{"reserve", "charge", "ship", "release", "lint", "deploy", "fetch", "collect", "render"} {
+		mustRegister(t, h.router, taskqueue.TaskType(name), func(context.Context, taskqueue.Task) error {
+			h.processed = append(h.processed, name)
+			return h.fail[name]
+		})
+	}
+	return h
+}
+
+func (h *harness) start(w workflow.Workflow) workflow.Run {
+	h.t.Helper()
+	run, err := h.engine.Start(context.Background(), w)
+	if err != nil {
+		h.t.Fatalf("Start: %v", err)
+	}
+	return run
+}
+
+func (h *harness) pop() taskqueue.Task {
+	h.t.Helper()
+	if len(h.queue) == 0 {
+		h.t.Fatal("no task enqueued")
+	}
+	task := h.queue[0]
+	h.queue = h.queue[1:]
+	return task
+}
+
+func (h *harness) process(ctx context.Context, task taskqueue.Task) error {
+	return taskqueue.Chain(h.router.Process, h.engine.Middleware())(ctx, task)
+}
+
+func (h *harness) drain() {
+	for len(h.queue) > 0 {
+		_ = h.process(context.Background(), h.pop())
+	}
+}
+
+func (h *harness) get(id string) workflow.Run {
+	h.t.Helper()
+	run, err := h.engine.Get(context.Background(), id)
+	if err != nil {
+		h.t.Fatalf("Get: %v", err)
+	}
+	return run
+}
+
+func waitForRun(t *testing.T, engine *workflow.Engine, id string) workflow.Run {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		run, err := engine.Get(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if run.Status.Done() {
+			return run
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for workflow run")
+	return workflow.Run{}
+}
+
+func mustRegister(t *testing.T, router *taskqueue.Router, taskType taskqueue.TaskType, fn taskqueue.ProcessorFunc) {
+	t.Helper()
+	if err := router.Register(taskqueue.NewProcessor(taskType, fn)); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+}
+
+func newTask(t *testing.T, taskType taskqueue.TaskType) taskqueue.Task {
+	t.Helper()
+	task, err := taskqueue.New(taskqueue.Definition{Type: taskType}, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return task
+}
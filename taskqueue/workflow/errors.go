@@ -0,0 +1,18 @@
+package workflow
+
+import "errors"
+
+var (
+	ErrNilStore          = errors.New("workflow store is nil")
+	ErrNilEnqueuer       = errors.New("workflow enqueuer is nil")
+	ErrEmptyWorkflow     = errors.New("workflow has no steps")
+	ErrEmptyStepName     = errors.New("workflow step name is empty")
+	ErrEmptyStepTask     = errors.New("workflow step task type is empty")
+	ErrDuplicateStep     = errors.New("workflow step is already defined")
+	ErrUnknownDependency = errors.New("workflow step depends on an unknown step")
+	ErrCyclicDependency  = errors.New("workflow steps have a cyclic dependency")
+	ErrInvalidPolicy     = errors.New("workflow failure policy is invalid")
+	ErrRunNotFound       = errors.New("workflow run is not found")
+	ErrRunExists         = errors.New("workflow run already exists")
+	ErrStepNotFound      = errors.New("workflow step is not found")
+)
@@ -0,0 +1,253 @@
+package workflow
+
+import (
+	"slices"
+	"time"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// RunStatus is the lifecycle state of a Run.
+type RunStatus string
+
+const (
+	RunRunning      RunStatus = "running"
+	RunSucceeded    RunStatus = "succeeded"
+	RunFailed       RunStatus = "failed"
+	RunCompensating RunStatus = "compensating"
+	RunCompensated  RunStatus = "compensated"
+)
+
+// Done reports whether s is terminal.
+func (s RunStatus) Done() bool {
+	return s == RunSucceeded || s == RunFailed || s == RunCompensated
+}
+
+// StepStatus is the lifecycle state of one step of a Run.
+type StepStatus string
+
+const (
+	StepPending            StepStatus = "pending"
+	StepEnqueued           StepStatus = "enqueued"
+	StepSucceeded          StepStatus = "succeeded"
+	StepFailed             StepStatus = "failed"
+	StepSkipped            StepStatus = "skipped"
+	StepCompensating       StepStatus = "compensating"
+	StepCompensated        StepStatus = "compensated"
+	StepCompensationFailed StepStatus = "compensation_failed"
+)
+
+// StepRun is a step definition with its progress in a Run.
+type StepRun struct {
+	Step
+	Status StepStatus
+	// Result is the value set with SetResult when the step succeeded.
+	Result string
+	// Error is the final error of a failed step or compensation.
+	Error string
+	// Completed orders successful steps by completion, starting at 1.
+	Completed int
+}
+
+// Run is the recorded progress of one started Workflow.
+type Run struct {
+	ID            string
+	Workflow      string
+	FailurePolicy FailurePolicy
+	Status        RunStatus
+	Steps         []StepRun
+	Error         string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Step returns the progress of the step called name.
+func (r Run) Step(name string) (StepRun, bool) {
+	if i := r.stepIndex(name); i >= 0 {
+		return r.Steps[i], true
+	}
+	return StepRun{}, false
+}
+
+// dispatch is a task to enqueue after a Run update has been stored.
+type dispatch struct {
+	step       string
+	compensate bool
+	task       taskqueue.Task
+}
+
+func newRun(id string, w Workflow, now time.Time) Run {
+	run := Run{
+		ID:            id,
+		Workflow:      w.Name,
+		FailurePolicy: w.FailurePolicy,
+		Status:        RunRunning,
+		Steps:         make([]StepRun, len(w.Steps)),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if run.FailurePolicy == Inherit {
+		run.FailurePolicy = Abort
+	}
+	for i, step := range w.Steps {
+		step.DependsOn = slices.Clone(step.DependsOn)
+		run.Steps[i] = StepRun{Step: step, Status: StepPending}
+	}
+	return run
+}
+
+func (r Run) clone() Run {
+	r.Steps = slices.Clone(r.Steps)
+	for i := range r.Steps {
+		r.Steps[i].DependsOn = slices.Clone(r.Steps[i].DependsOn)
+	}
+	return r
+}
+
+func (r *Run) stepIndex(name string) int {
+	return slices.IndexFunc(r.Steps, func(s StepRun) bool { return s.Name == name })
+}
+
+func (r *Run) succeed(i int, result string) {
+	completed := 0
+	for _, step := range r.Steps {
+		completed = max(completed, step.Completed)
+	}
+	r.Steps[i].Status = StepSucceeded
+	r.Steps[i].Result = result
+	r.Steps[i].Completed = completed + 1
+}
+
+func (r *Run) fail(i int, err error) {
+	step := &r.Steps[i]
+	step.Status = StepFailed
+	step.Error = err.Error()
+	if r.Status != RunRunning {
+		return
+	}
+	policy := step.FailurePolicy
+	if policy == Inherit {
+		policy = r.FailurePolicy
+	}
+	switch policy {
+	case Continue:
+		return
+	case Compensate:
+		r.Status = RunCompensating
+	default:
+		r.Status = RunFailed
+	}
+	r.Error = "step " + step.Name + ": " + step.Error
+	r.skipPending()
+}
+
+func (r *Run) skipPending() {
+	for i := range r.Steps {
+		if r.Steps[i].Status == StepPending {
+			r.Steps[i].Status = StepSkipped
+		}
+	}
+}
+
+// advance moves the run forward after a change and returns the tasks to
+// enqueue, already marked as enqueued or compensating.
+func (r *Run) advance() []dispatch {
+	var out []dispatch
+	switch r.Status {
+	case RunRunning:
+		for i := range r.Steps {
+			if r.Steps[i].Status == StepPending && r.dependenciesDone(r.Steps[i]) {
+				r.Steps[i].Status = StepEnqueued
+				out = append(out, r.stepDispatch(i))
+			}
+		}
+		if len(out) == 0 && !r.hasStatus(StepPending, StepEnqueued) {
+			r.Status = RunSucceeded
+			if r.hasStatus(StepFailed) {
+				r.Status = RunFailed
+				r.Error = "one or more steps failed"
+			}
+		}
+	case RunCompensating:
+		if r.hasStatus(StepEnqueued, StepCompensating) {
+			return nil
+		}
+		next := -1
+		for i, step := range r.Steps {
+			if step.Status == StepSucceeded && step.Compensation.Type() != "" &&
+				(next < 0 || step.Completed > r.Steps[next].Completed) {
+				next = i
+			}
+		}
+		if next < 0 {
+			r.Status = RunCompensated
+			return nil
+		}
+		r.Steps[next].Status = StepCompensating
+		out = append(out, r.compensationDispatch(next))
+	}
+	return out
+}
+
+// pendingDispatches returns the tasks already marked as enqueued or
+// compensating, for Resume.
+func (r *Run) pendingDispatches() []dispatch {
+	var out []dispatch
+	for i, step := range r.Steps {
+		switch step.Status {
+		case StepEnqueued:
+			out = append(out, r.stepDispatch(i))
+		case StepCompensating:
+			out = append(out, r.compensationDispatch(i))
+		}
+	}
+	return out
+}
+
+// revert undoes the marking of d after its enqueue failed.
+func (r *Run) revert(d dispatch) {
+	i := r.stepIndex(d.step)
+	if i < 0 {
+		return
+	}
+	switch {
+	case d.compensate && r.Steps[i].Status == StepCompensating:
+		r.Steps[i].Status = StepSucceeded
+	case !d.compensate && r.Steps[i].Status == StepEnqueued:
+		r.Steps[i].Status = StepPending
+	}
+}
+
+func (r *Run) dependenciesDone(step StepRun) bool {
+	for _, dep := range step.DependsOn {
+		i := r.stepIndex(dep)
+		if i < 0 || (r.Steps[i].Status != StepSucceeded && r.Steps[i].Status != StepFailed) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Run) hasStatus(statuses ...StepStatus) bool {
+	return slices.ContainsFunc(r.Steps, func(s StepRun) bool { return slices.Contains(statuses, s.Status) })
+}
+
+func (r *Run) stepDispatch(i int) dispatch {
+	step := r.Steps[i]
+	headers := map[string]string{RunIDHeader: r.ID, StepHeader: step.Name}
+	for _, dep := range step.DependsOn {
+		if j := r.stepIndex(dep); j >= 0 && r.Steps[j].Status == StepSucceeded {
+			headers[ResultHeaderPrefix+dep] = r.Steps[j].Result
+		}
+	}
+	return dispatch{step: step.Name, task: step.Task.WithHeaders(headers)}
+}
+
+func (r *Run) compensationDispatch(i int) dispatch {
+	step := r.Steps[i]
+	headers := map[string]string{RunIDHeader: r.ID, CompensateHeader: step.Name}
+	if step.Status == StepSucceeded || step.Status == StepCompensating {
+		headers[ResultHeaderPrefix+step.Name] = step.Result
+	}
+	return dispatch{step: step.Name, compensate: true, task: step.Compensation.WithHeaders(headers)}
+}
@@ -0,0 +1,68 @@
+package workflow
+
+import (
+	"context"
+	"sync"
+)
+
+// Store records workflow runs durably.
+type Store interface {
+	// Create stores a new run or returns ErrRunExists.
+	Create(ctx context.Context, run Run) error
+	// Get returns the run with id or ErrRunNotFound.
+	Get(ctx context.Context, id string) (Run, error)
+	// Update applies update to the run with id atomically and returns the
+	// stored result. When update returns an error nothing is stored.
+	Update(ctx context.Context, id string, update func(*Run) error) (Run, error)
+}
+
+// MemoryStore is a non-durable Store.
+type MemoryStore struct {
+	mu   sync.Mutex
+	runs map[string]Run
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore constructs an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{runs: make(map[string]Run)}
+}
+
+// Create stores run.
+func (s *MemoryStore) Create(_ context.Context, run Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.runs[run.ID]; ok {
+		return ErrRunExists
+	}
+	s.runs[run.ID] = run.clone()
+	return nil
+}
+
+// Get returns a copy of the run with id.
+func (s *MemoryStore) Get(_ context.Context, id string) (Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return Run{}, ErrRunNotFound
+	}
+	return run.clone(), nil
+}
+
+// Update applies update under the store lock.
+func (s *MemoryStore) Update(_ context.Context, id string, update func(*Run) error) (Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return Run{}, ErrRunNotFound
+	}
+	run = run.clone()
+	if err := update(&run); err != nil {
+		return Run{}, err
+	}
+	s.runs[id] = run
+	return run.clone(), nil
+}
@@ -0,0 +1,130 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/go-jimu/components/taskqueue"
+)
+
+// FailurePolicy decides how a run reacts to a step that failed for good.
+type FailurePolicy int
+
+const (
+	// Inherit uses the workflow policy. It is the zero value of
+	// Step.FailurePolicy; a workflow with Inherit aborts.
+	Inherit FailurePolicy = iota
+	// Abort marks the run failed and skips steps that have not started.
+	Abort
+	// Compensate skips steps that have not started, waits for running steps,
+	// and then enqueues the compensation tasks of completed steps in reverse
+	// completion order.
+	Compensate
+	// Continue records the failure and runs dependent steps as if the step
+	// had succeeded. The run fails once every step has finished.
+	Continue
+)
+
+// String returns a stable lowercase name.
+func (p FailurePolicy) String() string {
+	switch p {
+	case Inherit:
+		return "inherit"
+	case Abort:
+		return "abort"
+	case Compensate:
+		return "compensate"
+	case Continue:
+		return "continue"
+	default:
+		return "unknown"
+	}
+}
+
+// Step is one node of a workflow DAG.
+type Step struct {
+	// Name identifies the step within its workflow.
+	Name string
+	// Task is enqueued once every dependency has completed.
+	Task taskqueue.Task
+	// DependsOn names the steps that must complete first.
+	DependsOn []string
+	// Compensation, when its type is set, undoes the step under the
+	// Compensate policy.
+	Compensation taskqueue.Task
+	// FailurePolicy overrides the workflow policy for failures of this step.
+	FailurePolicy FailurePolicy
+}
+
+// Workflow is a DAG of steps started as one Run.
+type Workflow struct {
+	Name          string
+	Steps         []Step
+	FailurePolicy FailurePolicy
+}
+
+// Validate reports whether w is a well-formed DAG.
+func (w Workflow) Validate() error {
+	if len(w.Steps) == 0 {
+		return ErrEmptyWorkflow
+	}
+	if !w.FailurePolicy.valid() {
+		return fmt.Errorf("%w: %d", ErrInvalidPolicy, w.FailurePolicy)
+	}
+	steps := make(map[string]Step, len(w.Steps))
+	for _, step := range w.Steps {
+		if step.Name == "" {
+			return ErrEmptyStepName
+		}
+		if step.Task.Type() == "" {
+			return fmt.Errorf("%w: %s", ErrEmptyStepTask, step.Name)
+		}
+		if !step.FailurePolicy.valid() {
+			return fmt.Errorf("%w: step %s: %d", ErrInvalidPolicy, step.Name, step.FailurePolicy)
+		}
+		if _, ok := steps[step.Name]; ok {
+			return fmt.Errorf("%w: %s", ErrDuplicateStep, step.Name)
+		}
+		steps[step.Name] = step
+	}
+	for _, step := range w.Steps {
+		for _, dep := range step.DependsOn {
+			if _, ok := steps[dep]; !ok {
+				return fmt.Errorf("%w: %s depends on %s", ErrUnknownDependency, step.Name, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(steps))
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("%w: through %s", ErrCyclicDependency, name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range steps[name].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, step := range w.Steps {
+		if err := visit(step.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p FailurePolicy) valid() bool {
+	return p >= Inherit && p <= Continue
+}